@@ -14,11 +14,13 @@ func main() {
 	fmt.Println()
 
 	// Initialize market maker
-	mm := marketmaker.New(&marketmaker.Config{
-		MinSpreadPct:    0.002, // Only trade if spread > 0.2%
-		TargetSpreadPct: 0.001, // Capture 0.1% per round-trip
-		MaxMarkets:      100,   // Scan top 100 markets
-	})
+	config := &marketmaker.Config{
+		MinSpread:       0.002,                           // Only trade if spread > 0.2% of mid
+		SpreadMetric:    marketmaker.SpreadRelativeToMid, // Filter and report spreads relative to mid
+		TargetSpreadPct: 0.001,                           // Capture 0.1% per round-trip
+		MaxMarkets:      100,                             // Scan top 100 markets
+	}
+	mm := marketmaker.New(config)
 
 	// Find active markets with tradeable spreads
 	fmt.Println("Scanning for active markets with real liquidity...")
@@ -41,18 +43,15 @@ func main() {
 			break
 		}
 
-		mid := (opp.BestBid + opp.BestAsk) / 2
-		ourSpread := opp.SuggestedSellPrice - opp.SuggestedBuyPrice
-		ourSpreadPct := (ourSpread / mid) * 100
+		ourSpread := marketmaker.NewSpreadMetrics(opp.SuggestedBuyPrice, opp.SuggestedSellPrice)
 
 		fmt.Printf("%d. %s\n", i+1, opp.Question)
-		fmt.Printf("   Volume: $%.0f | Mid: %.4f\n", opp.Volume, mid)
-		fmt.Printf("   Current Market: Bid %.4f | Ask %.4f | Spread %.3f%%\n",
-			opp.BestBid, opp.BestAsk, opp.SpreadPct*100)
-		fmt.Printf("   Your Orders:    Bid %.4f | Ask %.4f | Spread %.3f%%\n",
-			opp.SuggestedBuyPrice, opp.SuggestedSellPrice, ourSpreadPct)
-		fmt.Printf("   Profit per round-trip: ~%.3f%%\n",
-			((opp.SuggestedSellPrice-opp.SuggestedBuyPrice)/opp.SuggestedBuyPrice)*100)
+		fmt.Printf("   Volume: $%.0f | Mid: %.4f\n", opp.Volume, opp.Spread.Mid)
+		fmt.Printf("   Current Market: Bid %.4f | Ask %.4f | Spread %s\n",
+			opp.BestBid, opp.BestAsk, opp.Spread.Format(config.SpreadMetric))
+		fmt.Printf("   Your Orders:    Bid %.4f | Ask %.4f | Spread %s\n",
+			opp.SuggestedBuyPrice, opp.SuggestedSellPrice, ourSpread.Format(config.SpreadMetric))
+		fmt.Printf("   Profit per round-trip: ~%.2f¢ per share\n", ourSpread.Cents)
 		fmt.Printf("   Token ID: %s\n", opp.TokenID)
 		fmt.Println()
 	}
@@ -63,19 +62,25 @@ func main() {
 	fmt.Println("===========================================")
 
 	totalVolume := 0.0
-	avgSpread := 0.0
+	avgSpread := marketmaker.SpreadMetrics{}
 	for _, opp := range opportunities {
 		totalVolume += opp.Volume
-		avgSpread += opp.SpreadPct
+		avgSpread.Cents += opp.Spread.Cents
+		avgSpread.RelativeToMid += opp.Spread.RelativeToMid
+		avgSpread.BasisPoints += opp.Spread.BasisPoints
+		avgSpread.LogOdds += opp.Spread.LogOdds
 	}
 
-	if len(opportunities) > 0 {
-		avgSpread /= float64(len(opportunities))
+	if n := float64(len(opportunities)); n > 0 {
+		avgSpread.Cents /= n
+		avgSpread.RelativeToMid /= n
+		avgSpread.BasisPoints /= n
+		avgSpread.LogOdds /= n
 	}
 
 	fmt.Printf("Markets found: %d\n", len(opportunities))
 	fmt.Printf("Total 24h volume: $%.0f\n", totalVolume)
-	fmt.Printf("Average spread: %.3f%%\n", avgSpread*100)
+	fmt.Printf("Average spread: %s\n", avgSpread.Format(config.SpreadMetric))
 	fmt.Println()
 	fmt.Println("These markets have REAL liquidity and active traders.")
 	fmt.Println("You can place orders INSIDE their spread and be the best price!")
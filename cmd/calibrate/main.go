@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"fiscal/pkg/marketmaker"
+)
+
+// Usage: calibrate -data resolved.json [-rules rules.json] [-at 2025-06-01 | -horizon 168h]
+// Backtests the pricing strategy on resolved markets and reports how well calibrated
+// its fair values were, overall, by category and by pricing rule.
+func main() {
+	dataPath := flag.String("data", "", "JSON array of resolved markets with price history (required)")
+	rulesPath := flag.String("rules", "", "Rules file to evaluate instead of the embedded defaults")
+	atFlag := flag.String("at", "", "Price every market as of this date (YYYY-MM-DD)")
+	horizon := flag.Duration("horizon", 7*24*time.Hour, "Without -at, price each market this long before it resolved")
+	bins := flag.Int("bins", 10, "Number of reliability bins")
+	predictionsPath := flag.String("predictions", "", "File to write every prediction to as JSON")
+	flag.Parse()
+
+	if *dataPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var opts marketmaker.CalibrationOptions
+	opts.Horizon = *horizon
+	opts.Bins = *bins
+	if *atFlag != "" {
+		at, err := time.Parse("2006-01-02", *atFlag)
+		if err != nil {
+			log.Fatalf("Invalid -at: %v", err)
+		}
+		opts.At = at
+	}
+
+	markets, err := marketmaker.LoadResolvedMarkets(*dataPath)
+	if err != nil {
+		log.Fatalf("Error loading resolved markets: %v", err)
+	}
+
+	ps := &marketmaker.PricingStrategy{}
+	rulesName := "embedded default rules"
+	if *rulesPath != "" {
+		rs, err := marketmaker.LoadRuleSet(*rulesPath)
+		if err != nil {
+			log.Fatalf("Invalid rules file: %v", err)
+		}
+		ps.Rules = marketmaker.NewRuleStore(rs)
+		rulesName = *rulesPath
+	}
+
+	fmt.Println("===========================================")
+	fmt.Println("Pricing Calibration Backtest")
+	fmt.Println("===========================================")
+	fmt.Println()
+
+	when := fmt.Sprintf("%v before resolution", *horizon)
+	if !opts.At.IsZero() {
+		when = "as of " + opts.At.Format("Jan 2, 2006")
+	}
+	fmt.Printf("Rules:   %s\n", rulesName)
+	fmt.Printf("Markets: %s (%d resolved)\n", *dataPath, len(markets))
+	fmt.Printf("Priced:  %s\n\n", when)
+
+	report := ps.Calibrate(markets, opts)
+	if report.Skipped > 0 {
+		fmt.Printf("Skipped %d markets not open at their pricing time\n\n", report.Skipped)
+	}
+	if len(report.Predictions) == 0 {
+		fmt.Println("No markets to score.")
+		return
+	}
+
+	fmt.Println("OVERALL")
+	printMetrics(report.Overall)
+	printReliability(report.Overall)
+
+	fmt.Println("\nBY CATEGORY")
+	var categories []marketmaker.MarketCategory
+	for category := range report.Categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+	for _, category := range categories {
+		m := report.Categories[category]
+		fmt.Printf("\n%s\n", category)
+		printMetrics(m)
+		printReliability(m)
+	}
+
+	fmt.Println("\nBY RULE")
+	var models []string
+	for model := range report.Models {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		m := report.Models[model]
+		fmt.Printf("   %-32s n=%-4d brier %.4f | log loss %.4f | predicted %5.1f%% | realized %5.1f%%\n",
+			model, m.Count, m.Brier, m.LogLoss, m.MeanPredicted*100, m.BaseRate*100)
+	}
+
+	if *predictionsPath != "" {
+		data, err := json.MarshalIndent(report.Predictions, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding predictions: %v", err)
+		}
+		if err := os.WriteFile(*predictionsPath, data, 0o644); err != nil {
+			log.Fatalf("Error writing predictions: %v", err)
+		}
+		fmt.Printf("\nWrote %d predictions to %s\n", len(report.Predictions), *predictionsPath)
+	}
+}
+
+func printMetrics(m marketmaker.CalibrationMetrics) {
+	fmt.Printf("   Markets: %d | Brier %.4f | Log loss %.4f\n", m.Count, m.Brier, m.LogLoss)
+	fmt.Printf("   Predicted %.1f%% on average, %.1f%% resolved YES\n", m.MeanPredicted*100, m.BaseRate*100)
+	if m.MarketCount > 0 {
+		fmt.Printf("   Against the market price (%d markets): ours %.4f | market %.4f\n",
+			m.MarketCount, m.ModelBrier, m.MarketBrier)
+	}
+}
+
+func printReliability(m marketmaker.CalibrationMetrics) {
+	fmt.Println("   Reliability:")
+	for _, bin := range m.Reliability {
+		fmt.Printf("      %3.0f-%3.0f%%  n=%-4d predicted %5.1f%% | realized %5.1f%%\n",
+			bin.Lo*100, bin.Hi*100, bin.Count, bin.MeanPredicted*100, bin.Observed*100)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 
@@ -8,17 +9,38 @@ import (
 )
 
 func main() {
+	oddsPath := flag.String("odds", "", "CSV or JSON file of external odds to anchor prices to")
+	devig := flag.String("devig", "shin", "Vig removal method for -odds: proportional, shin or power")
+	entitiesPath := flag.String("entities", "", "JSON reference table linking questions to entity-keyed odds")
+	mappingsPath := flag.String("mappings", "entity_mappings.json", "File of confirmed question-to-entity mappings")
+	riskName := flag.String("risk", "dust", "Risk profile for sizing: dust or active")
+	bankrollFlag := flag.Float64("bankroll", 500, "Bankroll in dollars to size positions against")
+	decisionsPath := flag.String("decisions", "", "File to write one JSON pricing decision per line, for auditing and diffing runs")
+	flag.Parse()
+
+	risk, err := marketmaker.LookupRiskProfile(*riskName)
+	if err != nil {
+		log.Fatalf("Invalid -risk: %v", err)
+	}
+	risk.Bankroll = marketmaker.FixedBankroll(*bankrollFlag)
+	if err := risk.Validate(); err != nil {
+		log.Fatalf("Invalid risk profile: %v", err)
+	}
+
 	fmt.Println("=======================================================")
 	fmt.Println("Dust Market Analyzer - Intelligent Pricing for Illiquid Markets")
 	fmt.Println("=======================================================")
 	fmt.Println()
 
 	// Initialize market maker
-	mm := marketmaker.New(&marketmaker.Config{
-		MinSpreadPct:    0.002,
+	config := &marketmaker.Config{
+		MinSpread:       0.002,
+		SpreadMetric:    marketmaker.SpreadRelativeToMid,
 		TargetSpreadPct: 0.001,
 		MaxMarkets:      100,
-	})
+		Risk:            &risk,
+	}
+	mm := marketmaker.New(config)
 
 	// Find illiquid markets
 	fmt.Println("Scanning for dust markets with placeholder orderbooks...")
@@ -33,50 +55,129 @@ func main() {
 	}
 
 	fmt.Printf("\n[SUCCESS] Found %d dust markets!\n", len(opportunities))
-	fmt.Println("\nApplying intelligent pricing strategies...\n")
+	fmt.Println("\nApplying intelligent pricing strategies...")
+	fmt.Println()
 	fmt.Println("=======================================================")
 
 	// Categorize and price each market
-	ps := &marketmaker.PricingStrategy{}
+	ps := config.Strategy()
+	bankroll, err := ps.Bankroll()
+	if err != nil {
+		log.Fatalf("Error reading bankroll: %v", err)
+	}
+
+	if *oddsPath != "" {
+		method, err := marketmaker.ParseDevigMethod(*devig)
+		if err != nil {
+			log.Fatalf("Invalid -devig: %v", err)
+		}
+		book, err := marketmaker.LoadOddsFile(*oddsPath, method)
+		if err != nil {
+			log.Fatalf("Error loading odds: %v", err)
+		}
+
+		var resolver *marketmaker.EntityResolver
+		if *entitiesPath != "" {
+			table, err := marketmaker.LoadEntityTable(*entitiesPath)
+			if err != nil {
+				log.Fatalf("Error loading entities: %v", err)
+			}
+			mappings, err := marketmaker.OpenMappingStore(*mappingsPath)
+			if err != nil {
+				log.Fatalf("Error loading entity mappings: %v", err)
+			}
+			resolver = &marketmaker.EntityResolver{Table: table, Mappings: mappings}
+		}
+
+		ps.AnchorToOdds(book, resolver)
+		fmt.Printf("Anchoring to %d external lines from %s (%s de-vig)\n\n", book.Len(), *oddsPath, method)
+	}
 
 	type ScoredOpportunity struct {
-		Opp       marketmaker.Opportunity
-		Category  marketmaker.MarketCategory
-		BidPrice  float64
-		AskPrice  float64
-		Reasoning string
-		PosSize   float64
+		Opp      marketmaker.Opportunity
+		Category marketmaker.MarketCategory
+		BidPrice float64
+		AskPrice float64
+		BuySize  float64
+		SellSize float64
+		Quote    marketmaker.PricingQuote
+		Decision marketmaker.PricingDecision
 	}
 
 	var scoredOpps []ScoredOpportunity
 
-	bankroll := 500.0 // Assume $500 bankroll for dust market testing
+	// Quotes for mutually exclusive events are normalized together so they sum to 100%
+	eventQuotes := make(map[*marketmaker.EventContext]map[string]marketmaker.PricingQuote)
 
 	for _, opp := range opportunities {
-		category := ps.CategorizeMarket(opp.Question)
-		bidPrice, askPrice, reasoning := ps.SuggestPricingForDustMarket(opp.Question, category)
+		class := ps.ClassifyMarketData(opp.Market, opp.Event)
+		category := class.Category
+		input := marketmaker.PricingInput{
+			Market:   opp.Market,
+			Event:    opp.Event,
+			Category: category,
+			Book:     opp.Book,
+		}
+		quote := ps.PriceMarket(input)
+
+		if opp.Event.MutuallyExclusive() {
+			quotes, ok := eventQuotes[opp.Event]
+			if !ok {
+				quotes = make(map[string]marketmaker.PricingQuote)
+				priced, err := ps.PriceEvent(opp.Event, marketmaker.NormalizeOptions{})
+				if err != nil {
+					fmt.Printf("Pricing %q market by market: %v\n", opp.Event.Title, err)
+				}
+				for i, q := range priced {
+					quotes[opp.Event.Markets[i].Question] = q
+				}
+				eventQuotes[opp.Event] = quotes
+			}
+			if normalized, ok := quotes[opp.Question]; ok {
+				quote = normalized
+			}
+		}
+		bidPrice, askPrice := quote.Bid, quote.Ask
 
-		// Estimate probability from our pricing (mid-point)
-		estimatedProb := (bidPrice + askPrice) / 2
+		// Size against our fair value; the band's midpoint means nothing without a bid
+		estimatedProb := quote.FairValue
 
 		// Suggest position size
 		buySize, sellSize, sizeReasoning := ps.SuggestPositionSize(bankroll, estimatedProb, bidPrice, askPrice)
 
+		decision := ps.Decide(input, class, quote)
+		decision.SetSize(buySize, sellSize, sizeReasoning)
+
 		scoredOpps = append(scoredOpps, ScoredOpportunity{
-			Opp:       opp,
-			Category:  category,
-			BidPrice:  bidPrice,
-			AskPrice:  askPrice,
-			Reasoning: reasoning + " | " + sizeReasoning,
-			PosSize:   (buySize + sellSize) / 2,
+			Opp:      opp,
+			Category: category,
+			BidPrice: bidPrice,
+			AskPrice: askPrice,
+			BuySize:  buySize,
+			SellSize: sellSize,
+			Quote:    quote,
+			Decision: decision,
 		})
 	}
 
+	if *decisionsPath != "" {
+		decisions := make([]marketmaker.PricingDecision, len(scoredOpps))
+		for i, so := range scoredOpps {
+			decisions[i] = so.Decision
+		}
+		if err := marketmaker.WriteDecisions(*decisionsPath, decisions); err != nil {
+			log.Fatalf("Error writing decisions: %v", err)
+		}
+		fmt.Printf("Wrote %d pricing decisions to %s\n\n", len(decisions), *decisionsPath)
+	}
+
 	// Show categorized opportunities
 	categories := map[marketmaker.MarketCategory]string{
 		marketmaker.CategorySports:      "SPORTS LONGSHOTS",
 		marketmaker.CategoryPolitics:    "POLITICAL EVENTS",
 		marketmaker.CategoryEconomic:    "ECONOMIC EVENTS",
+		marketmaker.CategoryLongshot:    "OBSERVED LONGSHOTS",
+		marketmaker.CategoryCompetitive: "COMPETITIVE MARKETS",
 		marketmaker.CategoryUnknown:     "UNCATEGORIZED",
 	}
 
@@ -101,23 +202,81 @@ func main() {
 				break
 			}
 
-			spread := so.AskPrice - so.BidPrice
-			spreadPct := (spread / so.BidPrice) * 100
-
-			fmt.Printf("\n%d. %s\n", i+1, so.Opp.Question)
-			fmt.Printf("   Category: %s\n", catName)
-			fmt.Printf("   Current Market: Bid %.4f | Ask %.4f (placeholder)\n",
-				so.Opp.BestBid, so.Opp.BestAsk)
-			fmt.Printf("   Suggested Prices: Bid %.4f | Ask %.4f\n", so.BidPrice, so.AskPrice)
-			fmt.Printf("   Your Spread: %.3f%%\n", spreadPct)
-			fmt.Printf("   Position Size: $%.0f per side\n", so.PosSize)
-			fmt.Printf("   Reasoning: %s\n", so.Reasoning)
+			spread := marketmaker.NewSpreadMetrics(so.BidPrice, so.AskPrice)
+
+			fmt.Printf("\n%d. %s", i+1, so.Decision)
+			fmt.Printf("   Your Spread: %s (%.2f¢)\n", spread.Format(config.SpreadMetric), spread.Cents)
 			fmt.Printf("   Token ID: %s\n", so.Opp.TokenID)
 		}
 
 		fmt.Println()
 	}
 
+	// Size everything together: siblings in one event are exclusive, so summing
+	// per-market Kelly overbets them
+	var quoted []marketmaker.QuotedMarket
+	for _, so := range scoredOpps {
+		quoted = append(quoted, marketmaker.QuotedMarket{Market: so.Opp.Market, Event: so.Opp.Event, Quote: so.Quote})
+	}
+	if len(quoted) > 0 {
+		fmt.Println("\n=======================================================")
+		fmt.Printf("PORTFOLIO KELLY (%s profile: %.0f%% Kelly, %.0f%% max exposure, $%.0f bankroll)\n",
+			risk.Name, risk.KellyFraction*100, risk.MaxExposure*100, bankroll)
+		fmt.Println("=======================================================")
+		portfolio := marketmaker.QuotePortfolio(quoted)
+		allocation, err := marketmaker.OptimizePortfolio(portfolio, marketmaker.PortfolioOptions{
+			Fraction:    risk.KellyFraction,
+			MaxExposure: risk.MaxExposure,
+		})
+		if err != nil {
+			fmt.Printf("Portfolio sizing failed: %v\n", err)
+		} else {
+			// Outcomes follow market order; a market without a bid has only its sell bet
+			marketOf := make(map[string]int, len(portfolio.Outcomes))
+			for i, o := range portfolio.Outcomes {
+				marketOf[o.ID] = i
+			}
+
+			// Both sides of each market count toward its category's cap
+			stakes := make([]float64, len(allocation.Fractions))
+			categories := make([]marketmaker.MarketCategory, len(allocation.Fractions))
+			for i, f := range allocation.Fractions {
+				stakes[i] = f * bankroll
+				categories[i] = scoredOpps[marketOf[portfolio.Bets[i].Outcome]].Category
+			}
+			stakes = risk.CapExposure(stakes, categories, bankroll)
+
+			buys := make([]float64, len(scoredOpps))
+			sells := make([]float64, len(scoredOpps))
+			for i, bet := range portfolio.Bets {
+				if bet.Side == marketmaker.SideBuy {
+					buys[marketOf[bet.Outcome]] += stakes[i]
+				} else {
+					sells[marketOf[bet.Outcome]] += stakes[i]
+				}
+			}
+
+			total := 0.0
+			for _, stake := range stakes {
+				total += stake
+			}
+			fmt.Printf("Joint allocation: $%.0f across %d markets (per-market Kelly would stake $%.0f)\n",
+				total, len(quoted), allocation.IndependentTotal*bankroll)
+			hidden := 0
+			for i, so := range scoredOpps {
+				buy, sell := buys[i], sells[i]
+				if buy < risk.MinTicket && sell < risk.MinTicket {
+					hidden++
+					continue
+				}
+				fmt.Printf("   $%5.0f buy | $%5.0f sell  %s\n", buy, sell, so.Opp.Question)
+			}
+			if hidden > 0 {
+				fmt.Printf("   (%d markets below the $%.0f minimum ticket not shown)\n", hidden, risk.MinTicket)
+			}
+		}
+	}
+
 	// Summary and strategy advice
 	fmt.Println("\n=======================================================")
 	fmt.Println("DUST MARKET STRATEGY")
@@ -126,7 +285,7 @@ func main() {
 	fmt.Println("These markets are illiquid for a reason - they're hard to price!")
 	fmt.Println()
 	fmt.Println("Risk Mitigation:")
-	fmt.Println("1. Start with TINY positions ($5-20 per market)")
+	fmt.Printf("1. Start with TINY positions ($%.0f-%.0f per market)\n", risk.MinTicket, risk.MaxTicket)
 	fmt.Println("2. Use WIDE spreads (50-200% spread) for safety")
 	fmt.Println("3. Monitor fills closely - immediate fill = bad pricing")
 	fmt.Println("4. Diversify across 10+ uncorrelated markets")
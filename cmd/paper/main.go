@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"fiscal/pkg/marketmaker"
+)
+
+func main() {
+	mode := flag.String("mode", "dust", "Markets to quote: dust or active")
+	collateral := flag.Float64("collateral", 300, "Simulated starting collateral in dollars")
+	size := flag.Float64("size", 10, "Shares per quote side")
+	maxInventory := flag.Float64("max-inventory", 50, "Stop bidding a token once this many shares are held (0 for no limit)")
+	maxMarkets := flag.Int("markets", 30, "Maximum number of markets to quote")
+	interval := flag.Duration("interval", 30*time.Second, "Time between quoting steps")
+	duration := flag.Duration("duration", 0, "How long to run live (0 runs until interrupted)")
+	recordPath := flag.String("record", "", "File to append the live market data to, for replaying later")
+	replayPath := flag.String("replay", "", "Recorded market data to replay instead of polling live books")
+	queueSkip := flag.Float64("queue-skip", 0, "Share of the displayed size at our price a new order goes ahead of (0 back of queue, 1 front)")
+	participation := flag.Float64("participation", 1, "Share of volume reaching our orders that fills them")
+	depletion := flag.Bool("depletion", false, "Count shrinking size at our price as trades")
+	ledgerPath := flag.String("ledger", "", "File to write the run's ledger to, replacing any earlier run's")
+	riskName := flag.String("risk", "", "Risk profile whose limits every order must pass: dust or active (default -mode)")
+	ordersPerMinute := flag.Int("orders-per-minute", 120, "Most orders to place in any minute (0 for no limit)")
+	fastFill := flag.Duration("fast-fill", 10*time.Second, "Pause a market filled this soon after quoting it (0 disables)")
+	largeFill := flag.Float64("large-fill", 0, "Pause a market on a fill of at least this many dollars (0 disables)")
+	cooldown := flag.Duration("cooldown", 30*time.Minute, "How long a paused market stays paused (0 for the rest of the run)")
+	maxDrawdown := flag.Float64("max-drawdown", 0.1, "Cancel everything once equity falls this share below its peak (0 disables)")
+	maxAPIErrors := flag.Int("max-api-errors", 5, "Cancel everything after this many failed exchange calls in a row (0 disables)")
+	killFile := flag.String("kill-file", "", "Cancel everything and stop quoting while this file exists")
+	haltCooldown := flag.Duration("halt-cooldown", 0, "How long a halt lasts (0 for the rest of the run)")
+	flag.Parse()
+
+	fmt.Println("===========================================")
+	fmt.Println("Paper Trader - Simulated Quoting")
+	fmt.Println("===========================================")
+	fmt.Println()
+
+	config := &marketmaker.Config{
+		MinSpread:       0.002,
+		SpreadMetric:    marketmaker.SpreadRelativeToMid,
+		TargetSpreadPct: 0.001,
+		MaxMarkets:      100,
+		Toxicity:        marketmaker.NewToxicityTracker(),
+	}
+	mm := marketmaker.New(config)
+
+	// Fills and book mids feed the strategy's toxicity widening and fair-value posteriors
+	ps := config.Strategy()
+	ps.Posteriors = marketmaker.NewPosteriorBook(marketmaker.PosteriorConfig{})
+
+	var model marketmaker.QuoteModel
+	switch *mode {
+	case "dust":
+		model = marketmaker.StrategyModel{Strategy: ps}
+	case "active":
+		model = marketmaker.QuoterModel{Quoter: marketmaker.InventoryQuoter{}, Volatility: 0.05}
+	default:
+		log.Fatalf("Invalid -mode %q (want dust or active)", *mode)
+	}
+
+	// Paper orders are signed like real ones, with a throwaway key that never holds funds
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("Error generating paper wallet: %v", err)
+	}
+	wallet, err := marketmaker.NewWallet(hex.EncodeToString(key))
+	if err != nil {
+		log.Fatalf("Error creating paper wallet: %v", err)
+	}
+
+	paper := marketmaker.NewPaperExchange(*collateral)
+	paper.Fills = marketmaker.PaperFillModel{QueueSkip: *queueSkip, Participation: *participation, CountDepletion: *depletion}
+	ledger := marketmaker.NewLedger()
+	if *ledgerPath != "" {
+		// Paper trade IDs restart with every run, so an earlier run's fills cannot be kept
+		if err := os.Remove(*ledgerPath); err != nil && !os.IsNotExist(err) {
+			log.Fatalf("Error replacing ledger: %v", err)
+		}
+		if ledger, err = marketmaker.OpenLedger(*ledgerPath); err != nil {
+			log.Fatalf("Error opening ledger: %v", err)
+		}
+	}
+
+	if *riskName == "" {
+		*riskName = *mode
+	}
+	profile, err := marketmaker.LookupRiskProfile(*riskName)
+	if err != nil {
+		log.Fatalf("Invalid -risk: %v", err)
+	}
+	profile.Bankroll = marketmaker.FixedBankroll(*collateral)
+	limits, err := marketmaker.RiskLimitsFor(profile)
+	if err != nil {
+		log.Fatalf("Error setting risk limits: %v", err)
+	}
+	limits.MaxOrdersPerMinute = *ordersPerMinute
+	risk := marketmaker.NewRiskManager(limits, ledger)
+	risk.Logf = log.Printf
+
+	breaker := marketmaker.NewCircuitBreaker(marketmaker.BreakerLimits{
+		FastFill:     *fastFill,
+		LargeFill:    *largeFill,
+		Cooldown:     *cooldown,
+		MaxDrawdown:  *maxDrawdown,
+		MaxAPIErrors: *maxAPIErrors,
+		KillFile:     *killFile,
+		HaltCooldown: *haltCooldown,
+	}, nil)
+	breaker.Equity = marketmaker.BankrollFunc(func() (float64, error) { return paper.Account().Equity(), nil })
+	breaker.Logf = log.Printf
+	breaker.Ledger = ledger
+
+	orders := marketmaker.NewOrderManager(breaker.Watch(risk.Guard(paper)), marketmaker.NewOrderBuilder(wallet, marketmaker.PolygonChainID))
+	orders.Risk = risk
+	breaker.Orders = orders
+
+	engine := marketmaker.NewQuotingEngine(orders, model, *size)
+	engine.Books = paper
+	engine.MaxInventory = *maxInventory
+	engine.Inventory = paper.Position
+	engine.Breaker = breaker
+	engine.Toxicity = config.Toxicity
+	engine.Posteriors = ps.Posteriors
+
+	orders.OnFill = func(o marketmaker.ManagedOrder, f marketmaker.OrderFill) {
+		log.Printf("FILL %s %.2f @ %.4f on %s", o.Side, f.Size, f.Price, o.TokenID)
+		ledger.RecordOrderFill(o, f)
+		breaker.OnFill(o, f)
+		engine.RecordFill(o, f, ledger.Info(o.TokenID).Category)
+	}
+
+	// Every quoted token is described to the ledger so its PnL and limits roll up by market,
+	// event and category
+	quote := func(opportunities []marketmaker.Opportunity) {
+		for _, opp := range opportunities {
+			ledger.Describe(opp.TokenID, marketmaker.TokenInfo{
+				Question: opp.Question,
+				Event:    eventID(opp.Market),
+				Category: ps.CategorizeMarketData(opp.Market, opp.Event),
+			})
+		}
+		engine.SetOpportunities(opportunities)
+	}
+
+	var started, ended time.Time
+	if *replayPath != "" {
+		started, ended = replay(*replayPath, paper, orders, risk, breaker, engine, quote, *interval, *maxMarkets)
+	} else {
+		started, ended = live(mm, *mode, *recordPath, paper, engine, quote, *interval, *duration, *maxMarkets)
+	}
+
+	if err := engine.Stop(context.Background()); err != nil {
+		log.Printf("Error pulling quotes: %v", err)
+	}
+	// The trade history supplies the fees the order manager does not see
+	if _, err := ledger.Sync(context.Background(), paper, ""); err != nil {
+		log.Printf("Error syncing ledger: %v", err)
+	}
+	if *ledgerPath != "" {
+		if err := ledger.Save(); err != nil {
+			log.Printf("Error saving ledger: %v", err)
+		}
+	}
+	report(paper.Account(), *collateral, ended.Sub(started))
+	trips(breaker.Trips())
+	fmt.Println()
+	fmt.Print(ledger.Report(paper.MarkPrice))
+}
+
+// live quotes the scanned markets against books polled from the CLOB
+func live(mm *marketmaker.MarketMaker, mode, recordPath string, paper *marketmaker.PaperExchange,
+	engine *marketmaker.QuotingEngine, quote func([]marketmaker.Opportunity), interval, duration time.Duration, maxMarkets int) (time.Time, time.Time) {
+	fmt.Printf("Scanning for %s markets...\n", mode)
+	scan := mm.FindIlliquidMarkets
+	if mode == "active" {
+		scan = mm.FindActiveMarkets
+	}
+	opportunities, err := scan()
+	if err != nil {
+		log.Fatalf("Error finding markets: %v", err)
+	}
+	if len(opportunities) > maxMarkets {
+		opportunities = opportunities[:maxMarkets]
+	}
+	if len(opportunities) == 0 {
+		log.Fatalf("No %s markets to quote", mode)
+	}
+
+	var tokens []string
+	var listings []marketmaker.MarketEvent
+	for _, opp := range opportunities {
+		market := opp.Market
+		tokens = append(tokens, opp.TokenID)
+		listings = append(listings, marketmaker.MarketEvent{Time: time.Now(), TokenID: opp.TokenID, Market: &market, Book: opp.Book})
+	}
+	for _, ev := range listings {
+		paper.Apply(ev)
+	}
+	record(recordPath, listings)
+	quote(opportunities)
+	fmt.Printf("Quoting %d markets every %s. Press Ctrl-C to stop.\n\n", len(opportunities), interval)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	started := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		events, err := paper.PollBooks(mm, tokens)
+		if err != nil {
+			log.Printf("Error polling books: %v", err)
+		}
+		record(recordPath, events)
+		if err := engine.Step(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error quoting: %v", err)
+		}
+		account := paper.Account()
+		log.Printf("equity $%.2f | cash $%.2f (%.2f locked) | %d positions | %d trades",
+			account.Equity(), account.Collateral, account.Locked, len(account.Positions), account.Trades)
+
+		select {
+		case <-ctx.Done():
+			return started, time.Now()
+		case <-ticker.C:
+		}
+	}
+}
+
+// replay quotes the markets in a recorded tape, stepping the engine on the tape's clock
+func replay(path string, paper *marketmaker.PaperExchange, orders *marketmaker.OrderManager, risk *marketmaker.RiskManager,
+	breaker *marketmaker.CircuitBreaker, engine *marketmaker.QuotingEngine, quote func([]marketmaker.Opportunity), interval time.Duration, maxMarkets int) (time.Time, time.Time) {
+	events, err := marketmaker.LoadMarketTape(path)
+	if err != nil {
+		log.Fatalf("Error loading tape: %v", err)
+	}
+	if len(events) == 0 {
+		log.Fatalf("Tape %s is empty", path)
+	}
+
+	var opportunities []marketmaker.Opportunity
+	listed := make(map[string]bool)
+	for _, ev := range events {
+		if ev.Market == nil || listed[ev.TokenID] || len(opportunities) >= maxMarkets {
+			continue
+		}
+		listed[ev.TokenID] = true
+		opportunities = append(opportunities, marketmaker.Opportunity{Question: ev.Market.Question, TokenID: ev.TokenID, Market: *ev.Market})
+	}
+	if len(opportunities) == 0 {
+		log.Fatalf("Tape %s records no market listings to quote", path)
+	}
+	quote(opportunities)
+
+	clock := events[0].Time
+	now := func() time.Time { return clock }
+	paper.Now, orders.Now, risk.Now, breaker.Now, engine.Now = now, now, now, now, now
+	fmt.Printf("Replaying %d events on %d markets from %s to %s\n\n", len(events), len(opportunities),
+		events[0].Time.Format(time.RFC3339), events[len(events)-1].Time.Format(time.RFC3339))
+
+	ctx := context.Background()
+	var lastStep time.Time
+	for _, ev := range events {
+		clock = ev.Time
+		paper.Apply(ev)
+		if clock.Sub(lastStep) < interval {
+			continue
+		}
+		lastStep = clock
+		if err := engine.Step(ctx); err != nil {
+			log.Printf("%s: error quoting: %v", clock.Format(time.RFC3339), err)
+		}
+	}
+	return events[0].Time, clock
+}
+
+// record appends events to the tape, if one was asked for
+func record(path string, events []marketmaker.MarketEvent) {
+	if path == "" || len(events) == 0 {
+		return
+	}
+	if err := marketmaker.AppendMarketTape(path, events); err != nil {
+		log.Printf("Error recording market data: %v", err)
+	}
+}
+
+// report prints the run's result against the strategy doc's estimates
+func report(account marketmaker.PaperAccount, collateral float64, elapsed time.Duration) {
+	fmt.Println()
+	fmt.Println("===========================================")
+	fmt.Println("Paper Trading Results")
+	fmt.Println("===========================================")
+
+	equity := account.Equity()
+	ret := (equity - collateral) / collateral
+	fmt.Printf("Ran for:        %s\n", elapsed.Round(time.Second))
+	fmt.Printf("Trades:         %d\n", account.Trades)
+	fmt.Printf("Fees paid:      $%.2f\n", account.Fees)
+	fmt.Printf("Cash:           $%.2f\n", account.Collateral)
+	fmt.Printf("Positions:      $%.2f marked to mid\n", account.Value)
+	fmt.Printf("Equity:         $%.2f (started with $%.2f)\n", equity, collateral)
+	fmt.Printf("Return:         %+.2f%%\n", ret*100)
+	if elapsed >= 24*time.Hour {
+		monthly := ret * float64(30*24*time.Hour) / float64(elapsed)
+		fmt.Printf("Monthly pace:   %+.2f%% (doc's realistic dust scenario: 0-2%%, pessimistic: -5%% to -10%%)\n", monthly*100)
+	} else {
+		fmt.Println("Monthly pace:   run at least a day to compare with the doc's 0-2% realistic scenario")
+	}
+
+	tokens := make([]string, 0, len(account.Positions))
+	for tokenID := range account.Positions {
+		tokens = append(tokens, tokenID)
+	}
+	sort.Strings(tokens)
+	for _, tokenID := range tokens {
+		fmt.Printf("  %10.2f shares of %s\n", account.Positions[tokenID], tokenID)
+	}
+}
+
+// trips lists every time the circuit breaker paused a market or halted quoting
+func trips(trips []marketmaker.BreakerTrip) {
+	if len(trips) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("Circuit breaker tripped %d times:\n", len(trips))
+	for _, trip := range trips {
+		scope := "all markets"
+		if trip.Market != "" {
+			scope = trip.Market
+		} else if trip.TokenID != "" {
+			scope = trip.TokenID
+		}
+		fmt.Printf("  %s  %s: %s\n", trip.Time.Format(time.RFC3339), scope, trip.Reason)
+	}
+}
+
+// eventID is the first event a market belongs to, or empty
+func eventID(m marketmaker.Market) string {
+	if len(m.Events) == 0 {
+		return ""
+	}
+	return m.Events[0].ID
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"fiscal/pkg/marketmaker"
+)
+
+// Usage: rules [rules.json] [corpus.json]
+// Validates a rules file and checks it against a corpus of labeled questions.
+// Without arguments the embedded default rules and corpus are checked.
+func main() {
+	fmt.Println("===========================================")
+	fmt.Println("Pricing Rules Checker")
+	fmt.Println("===========================================")
+	fmt.Println()
+
+	rs := marketmaker.DefaultRuleSet()
+	rulesName := "embedded default rules"
+	if len(os.Args) > 1 {
+		loaded, err := marketmaker.LoadRuleSet(os.Args[1])
+		if err != nil {
+			log.Fatalf("Invalid rules file: %v", err)
+		}
+		rs = loaded
+		rulesName = os.Args[1]
+	}
+
+	corpus := marketmaker.DefaultRuleCorpus()
+	corpusName := "embedded default corpus"
+	if len(os.Args) > 2 {
+		loaded, err := marketmaker.LoadRuleCorpus(os.Args[2])
+		if err != nil {
+			log.Fatalf("Error loading corpus: %v", err)
+		}
+		corpus = loaded
+		corpusName = os.Args[2]
+	}
+
+	fmt.Printf("Rules:  %s (version %d, %d category rules, %d price rules)\n",
+		rulesName, rs.Version, len(rs.Categories), len(rs.Rules))
+	fmt.Printf("Corpus: %s (%d questions)\n\n", corpusName, len(corpus))
+
+	failures := 0
+	for _, result := range rs.CheckCorpus(corpus) {
+		if result.Passed {
+			continue
+		}
+		failures++
+		fmt.Printf("[FAIL] %s\n", result.Case.Question)
+		fmt.Printf("   Expected: %s / %s\n", result.Case.Category, result.Case.Rule)
+		fmt.Printf("   Got:      %s / %s\n", result.Category, result.Rule)
+		for _, label := range rs.Classify(result.Case.Question, result.Case.Tags).Labels {
+			fmt.Printf("   Label:    %s %.2f (%v)\n", label.Category, label.Confidence, label.Features)
+		}
+	}
+
+	report := rs.EvaluateClassifier(corpus)
+	fmt.Println("\nClassifier precision by category:")
+	for _, category := range []marketmaker.MarketCategory{
+		marketmaker.CategorySports,
+		marketmaker.CategoryPolitics,
+		marketmaker.CategoryEconomic,
+		marketmaker.CategoryUnknown,
+	} {
+		m, ok := report.Categories[category]
+		if !ok {
+			continue
+		}
+		fmt.Printf("   %-10s precision %5.1f%% | recall %5.1f%% (tp %d, fp %d, fn %d)\n",
+			category, m.Precision*100, m.Recall*100, m.TruePositives, m.FalsePositives, m.FalseNegatives)
+	}
+	fmt.Printf("   Accuracy %.1f%% | %d ambiguous\n", report.Accuracy*100, report.Ambiguous)
+
+	fmt.Printf("\n%d/%d questions passed\n", len(corpus)-failures, len(corpus))
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
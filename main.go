@@ -14,11 +14,13 @@ func main() {
 	fmt.Println()
 
 	// Initialize market maker
-	mm := marketmaker.New(&marketmaker.Config{
-		MinSpreadPct:    0.002, // Only trade if spread > 0.2%
-		TargetSpreadPct: 0.001, // Capture 0.1% per round-trip
-		MaxMarkets:      100,   // Scan top 100 markets
-	})
+	config := &marketmaker.Config{
+		MinSpread:       0.002,                           // Only trade if spread > 0.2% of mid
+		SpreadMetric:    marketmaker.SpreadRelativeToMid, // Report spreads relative to mid
+		TargetSpreadPct: 0.001,                           // Capture 0.1% per round-trip
+		MaxMarkets:      100,                             // Scan top 100 markets
+	}
+	mm := marketmaker.New(config)
 
 	// Find illiquid markets (placeholder orderbooks)
 	fmt.Println("Scanning for illiquid markets with placeholder orderbooks...")
@@ -42,8 +44,8 @@ func main() {
 			break
 		}
 		fmt.Printf("%d. %s\n", i+1, opp.Question)
-		fmt.Printf("   Current: Bid %.4f | Ask %.4f | Spread %.2f%%\n",
-			opp.BestBid, opp.BestAsk, opp.SpreadPct*100)
+		fmt.Printf("   Current: Bid %.4f | Ask %.4f | Spread %s\n",
+			opp.BestBid, opp.BestAsk, opp.Spread.Format(config.SpreadMetric))
 		fmt.Printf("   Suggested: Buy %.4f | Sell %.4f\n",
 			opp.SuggestedBuyPrice, opp.SuggestedSellPrice)
 		fmt.Printf("   Token ID: %s\n", opp.TokenID)
@@ -0,0 +1,386 @@
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// BreakerLimits are the conditions that trip a circuit breaker. Zero disables a trigger.
+type BreakerLimits struct {
+	FastFill     time.Duration // A fill this soon after its order was placed pauses the market
+	LargeFill    float64       // Dollars in one fill that pause the market
+	Cooldown     time.Duration // How long a paused market stays paused; 0 until Resume or Reset
+	MaxDrawdown  float64       // Share of peak equity lost that halts all quoting, e.g. 0.1
+	MaxAPIErrors int           // Consecutive failed exchange calls that halt all quoting
+	KillFile     string        // Halt all quoting while this file exists
+	HaltCooldown time.Duration // How long a halt lasts; 0 until Reset
+}
+
+// BreakerTrip is one time the breaker paused a market or halted quoting
+type BreakerTrip struct {
+	TokenID string // Token whose fill paused the market; empty for a halt of all quoting
+	Market  string // Market paused, as the ledger names it; empty for a halt
+	Reason  string
+	Time    time.Time
+	Until   time.Time // Zero when only a resume or reset ends it
+}
+
+// CircuitBreaker automates the dust-market stop losses: "cancel if filled immediately" and
+// "cancel other side immediately". A fill too soon after placement or too large pauses its
+// market and cancels the quotes on every outcome of it. Drawdown, repeated exchange
+// failures or a kill file halt everything and cancel every order. A QuotingEngine whose
+// Breaker is set skips paused markets and quotes nothing while halted; the exchange
+// returned by Watch refuses their orders from anyone else.
+type CircuitBreaker struct {
+	Limits BreakerLimits
+	Orders *OrderManager                            // Orders to cancel when the breaker trips
+	Ledger *Ledger                                  // Groups outcomes into markets for pauses; nil pauses only the filled token
+	Equity BankrollSource                           // Current equity, for drawdown; nil disables MaxDrawdown
+	Logf   func(format string, args ...interface{}) // Reports every trip, resume and reset
+	Now    func() time.Time                         // nil uses time.Now
+
+	mu       sync.Mutex
+	paused   map[string]BreakerTrip // By market
+	halt     *BreakerTrip
+	peak     float64 // Highest equity seen since the last reset
+	failures int     // Consecutive failed exchange calls
+	trips    []BreakerTrip
+}
+
+// NewCircuitBreaker creates a breaker that cancels through orders when it trips. orders may
+// be set later, for an OrderManager built on the breaker's watched exchange.
+func NewCircuitBreaker(limits BreakerLimits, orders *OrderManager) *CircuitBreaker {
+	return &CircuitBreaker{Limits: limits, Orders: orders, paused: make(map[string]BreakerTrip)}
+}
+
+// OnFill checks a fill against the fast- and large-fill limits, pausing the market if either
+// is hit. Call it from OrderManager.OnFill.
+func (b *CircuitBreaker) OnFill(o ManagedOrder, f OrderFill) {
+	var reasons []string
+	// Match times are whole seconds, so a fill may be dated up to a second before its order.
+	// One dated earlier still is missing a real time and says nothing about speed.
+	elapsed := f.Time.Sub(o.Created)
+	if limit := b.Limits.FastFill; limit > 0 && !o.Created.IsZero() && elapsed >= -time.Second && elapsed <= limit {
+		reasons = append(reasons, fmt.Sprintf("%s filled %s after placement, within %s",
+			o.Side, max(elapsed, 0).Round(time.Second), limit))
+	}
+	if limit := b.Limits.LargeFill; limit > 0 && f.Size*f.Price >= limit {
+		reasons = append(reasons, fmt.Sprintf("%s fill of $%.2f is at least $%.2f", o.Side, f.Size*f.Price, limit))
+	}
+	if len(reasons) == 0 {
+		return
+	}
+
+	reason := fmt.Sprintf("%.2f @ %.4f: %s", f.Size, f.Price, reasons[0])
+	for _, r := range reasons[1:] {
+		reason += "; " + r
+	}
+	if err := b.Pause(context.Background(), o.TokenID, reason); err != nil {
+		b.logf("Error cancelling quotes on paused %s: %v", o.TokenID, err)
+	}
+}
+
+// Pause stops quoting a token's market for the cooldown and cancels the open orders on
+// every outcome of it
+func (b *CircuitBreaker) Pause(ctx context.Context, tokenID, reason string) error {
+	market := b.market(tokenID)
+	b.mu.Lock()
+	trip := b.record(tokenID, reason, b.Limits.Cooldown)
+	trip.Market = market
+	b.trips[len(b.trips)-1] = trip
+	b.paused[market] = trip
+	b.mu.Unlock()
+
+	b.logf("PAUSE %q after a fill on %s: %s%s", market, tokenID, reason, until(trip))
+	if b.Orders == nil {
+		return nil
+	}
+	var ids []string
+	for _, o := range b.Orders.Open("") {
+		if b.market(o.TokenID) == market {
+			ids = append(ids, o.ClientID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return b.Orders.cancel(ctx, ids)
+}
+
+// Resume quotes a paused token's market again
+func (b *CircuitBreaker) Resume(tokenID string) {
+	market := b.market(tokenID)
+	b.mu.Lock()
+	_, ok := b.paused[market]
+	delete(b.paused, market)
+	b.mu.Unlock()
+
+	if ok {
+		b.logf("RESUME %q", market)
+	}
+}
+
+// Paused reports whether a token's market is paused, and why. A pause whose cooldown has
+// passed ends.
+func (b *CircuitBreaker) Paused(tokenID string) (BreakerTrip, bool) {
+	market := b.market(tokenID)
+	b.mu.Lock()
+	trip, ok := b.paused[market]
+	expired := ok && !trip.Until.IsZero() && !b.now().Before(trip.Until)
+	if expired {
+		delete(b.paused, market)
+	}
+	b.mu.Unlock()
+
+	if expired {
+		b.logf("RESUME %q: cooldown over", market)
+		return BreakerTrip{}, false
+	}
+	return trip, ok
+}
+
+// market is the market a token belongs to, by the ledger's description of it
+func (b *CircuitBreaker) market(tokenID string) string {
+	if b.Ledger == nil {
+		return tokenID
+	}
+	return b.Ledger.Info(tokenID).marketKey(tokenID)
+}
+
+// Halt stops all quoting and cancels every open order
+func (b *CircuitBreaker) Halt(ctx context.Context, reason string) error {
+	b.trip(reason)
+	if b.Orders == nil {
+		return nil
+	}
+	return b.Orders.CancelToken(ctx, "")
+}
+
+// trip halts quoting without cancelling, for callers that cannot reach the exchange
+func (b *CircuitBreaker) trip(reason string) {
+	b.mu.Lock()
+	if b.halt != nil {
+		b.mu.Unlock()
+		return
+	}
+	trip := b.record("", reason, b.Limits.HaltCooldown)
+	b.halt = &trip
+	b.mu.Unlock()
+
+	b.logf("HALT: %s%s", reason, until(trip))
+}
+
+// Halted reports whether all quoting is halted, and why. A halt whose cooldown has passed
+// ends, and drawdown is measured afresh from the equity at that point.
+func (b *CircuitBreaker) Halted() (BreakerTrip, bool) {
+	b.mu.Lock()
+	if b.halt == nil {
+		b.mu.Unlock()
+		return BreakerTrip{}, false
+	}
+	trip := *b.halt
+	expired := !trip.Until.IsZero() && !b.now().Before(trip.Until)
+	if expired {
+		b.halt, b.peak, b.failures = nil, 0, 0
+	}
+	b.mu.Unlock()
+
+	if expired {
+		b.logf("RESUME all quoting: cooldown over")
+		return BreakerTrip{}, false
+	}
+	return trip, true
+}
+
+// Reset ends the halt and every pause, measures drawdown afresh and forgets past failures.
+// A kill file that still exists halts again on the next check.
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	b.paused = make(map[string]BreakerTrip)
+	b.halt, b.peak, b.failures = nil, 0, 0
+	b.mu.Unlock()
+
+	b.logf("RESET: all markets may quote again")
+}
+
+// Trips lists every pause and halt so far, oldest first
+func (b *CircuitBreaker) Trips() []BreakerTrip {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]BreakerTrip(nil), b.trips...)
+}
+
+// Check halts quoting if the kill file exists or equity has fallen too far from its peak,
+// and cancels every open order while halted
+func (b *CircuitBreaker) Check(ctx context.Context) error {
+	if path := b.Limits.KillFile; path != "" {
+		if _, err := os.Stat(path); err == nil {
+			b.trip(fmt.Sprintf("kill file %s exists", path))
+		} else if !os.IsNotExist(err) {
+			b.trip(fmt.Sprintf("failed to check kill file: %v", err))
+		}
+	}
+
+	var errs []error
+	if b.Equity != nil && b.Limits.MaxDrawdown > 0 {
+		equity, err := b.Equity.Bankroll()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get equity: %w", err))
+		} else {
+			b.mu.Lock()
+			if equity > b.peak {
+				b.peak = equity
+			}
+			peak := b.peak
+			b.mu.Unlock()
+
+			if peak > 0 && (peak-equity)/peak >= b.Limits.MaxDrawdown {
+				b.trip(fmt.Sprintf("equity $%.2f is %.1f%% below its peak of $%.2f, limit %.1f%%",
+					equity, (peak-equity)/peak*100, peak, b.Limits.MaxDrawdown*100))
+			}
+		}
+	}
+
+	if _, halted := b.Halted(); halted && b.Orders != nil {
+		if len(b.Orders.Open("")) > 0 {
+			if err := b.Orders.CancelToken(ctx, ""); err != nil {
+				errs = append(errs, fmt.Errorf("failed to cancel orders while halted: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run checks every interval until ctx is cancelled, reporting failures to onError. A
+// QuotingEngine whose Breaker is set checks on every step without it.
+func (b *CircuitBreaker) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Check(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch wraps an exchange so that failed calls count toward MaxAPIErrors and orders on
+// paused markets, or on any market while halted, are refused
+func (b *CircuitBreaker) Watch(ex Exchange) Exchange {
+	return &breakerExchange{ex: ex, breaker: b}
+}
+
+// record notes a trip, ending after cooldown unless it is zero. The caller holds b.mu.
+func (b *CircuitBreaker) record(tokenID, reason string, cooldown time.Duration) BreakerTrip {
+	trip := BreakerTrip{TokenID: tokenID, Reason: reason, Time: b.now()}
+	if cooldown > 0 {
+		trip.Until = trip.Time.Add(cooldown)
+	}
+	b.trips = append(b.trips, trip)
+	return trip
+}
+
+// observe counts consecutive failed exchange calls. Refusals, such as a 4xx or an order the
+// exchange rejected, show the exchange is up and reset the count.
+func (b *CircuitBreaker) observe(err error, rejected bool) {
+	var clobErr *CLOBError
+	failed := err != nil && !rejected && !(errors.As(err, &clobErr) && clobErr.Status < 500)
+
+	b.mu.Lock()
+	if !failed {
+		b.failures = 0
+		b.mu.Unlock()
+		return
+	}
+	b.failures++
+	n := b.failures
+	b.mu.Unlock()
+
+	if limit := b.Limits.MaxAPIErrors; limit > 0 && n >= limit {
+		b.trip(fmt.Sprintf("%d exchange calls failed in a row, last: %v", n, err))
+	}
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) logf(format string, args ...interface{}) {
+	if b.Logf != nil {
+		b.Logf(format, args...)
+	}
+}
+
+// until describes when a trip ends
+func until(trip BreakerTrip) string {
+	if trip.Until.IsZero() {
+		return " (until reset)"
+	}
+	return fmt.Sprintf(" (until %s)", trip.Until.Format(time.RFC3339))
+}
+
+// breakerExchange is an exchange watched by a circuit breaker
+type breakerExchange struct {
+	ex      Exchange
+	breaker *CircuitBreaker
+}
+
+// PostOrder implements Exchange
+func (w *breakerExchange) PostOrder(ctx context.Context, order SignedOrder, orderType OrderType) (PostOrderResponse, error) {
+	refuse := func(trip BreakerTrip) (PostOrderResponse, error) {
+		msg := "circuit breaker tripped: " + trip.Reason
+		return PostOrderResponse{ErrorMsg: msg}, errors.New("order rejected: " + msg)
+	}
+	if trip, halted := w.breaker.Halted(); halted {
+		return refuse(trip)
+	}
+	if trip, paused := w.breaker.Paused(order.TokenID); paused {
+		return refuse(trip)
+	}
+
+	resp, err := w.ex.PostOrder(ctx, order, orderType)
+	w.breaker.observe(err, resp.ErrorMsg != "")
+	return resp, err
+}
+
+// CancelOrders implements Exchange
+func (w *breakerExchange) CancelOrders(ctx context.Context, orderIDs []string) (CancelResponse, error) {
+	resp, err := w.ex.CancelOrders(ctx, orderIDs)
+	w.breaker.observe(err, false)
+	return resp, err
+}
+
+// Order implements Exchange
+func (w *breakerExchange) Order(ctx context.Context, orderID string) (OpenOrder, error) {
+	o, err := w.ex.Order(ctx, orderID)
+	w.breaker.observe(err, false)
+	return o, err
+}
+
+// OpenOrders implements Exchange
+func (w *breakerExchange) OpenOrders(ctx context.Context, filter OrderFilter) ([]OpenOrder, error) {
+	orders, err := w.ex.OpenOrders(ctx, filter)
+	w.breaker.observe(err, false)
+	return orders, err
+}
+
+// Trades implements Exchange
+func (w *breakerExchange) Trades(ctx context.Context, filter TradeFilter) ([]Trade, error) {
+	trades, err := w.ex.Trades(ctx, filter)
+	w.breaker.observe(err, false)
+	return trades, err
+}
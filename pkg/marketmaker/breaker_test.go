@@ -0,0 +1,65 @@
+package marketmaker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerPausesWholeMarket(t *testing.T) {
+	const yes, no, other = "1234", "5678", "9012"
+	ledger := NewLedger()
+	ledger.Describe(yes, TokenInfo{Question: "Will it rain?", Market: "0xrain"})
+	ledger.Describe(no, TokenInfo{Question: "Will it rain?", Market: "0xrain"})
+	ledger.Describe(other, TokenInfo{Question: "Will it snow?", Market: "0xsnow"})
+
+	m := newTestOrderManager(t, NewPaperExchange(100))
+	breaker := NewCircuitBreaker(BreakerLimits{FastFill: 10 * time.Second}, m)
+	breaker.Ledger = ledger
+
+	ctx := context.Background()
+	var filled ManagedOrder
+	for _, token := range []string{yes, no, other} {
+		o, err := m.Place(ctx, OrderRequest{TokenID: token, Side: SideBuy, Price: 0.1, Size: 10,
+			Options: OrderOptions{TickSize: 0.01}})
+		if err != nil {
+			t.Fatalf("Place %s: %v", token, err)
+		}
+		if token == yes {
+			filled = o
+		}
+	}
+
+	breaker.OnFill(filled, OrderFill{Price: 0.1, Size: 1, Time: filled.Created.Add(time.Second)})
+	for _, token := range []string{yes, no} {
+		if _, paused := breaker.Paused(token); !paused {
+			t.Errorf("%s not paused with its market", token)
+		}
+	}
+	if _, paused := breaker.Paused(other); paused {
+		t.Error("another market was paused")
+	}
+	open := m.Open("")
+	if len(open) != 1 || open[0].TokenID != other {
+		t.Errorf("open orders %+v, want only the one on %s", open, other)
+	}
+	if trips := breaker.Trips(); len(trips) != 1 || trips[0].Market != "0xrain" || trips[0].TokenID != yes {
+		t.Errorf("trips %+v, want one on 0xrain from %s", trips, yes)
+	}
+}
+
+func TestCircuitBreakerIgnoresUndatedFills(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerLimits{FastFill: 10 * time.Second}, nil)
+	o := ManagedOrder{TokenID: testTokenID, Side: SideBuy, Created: time.Unix(1_700_000_000, 0)}
+
+	// A fill dated at the epoch carries no time, not one before its order was placed
+	breaker.OnFill(o, OrderFill{Price: 0.1, Size: 1, Time: time.Unix(0, 0)})
+	if _, paused := breaker.Paused(testTokenID); paused {
+		t.Error("an undated fill counted as fast")
+	}
+	// Match times are whole seconds, so a fill in the same second may predate its order
+	breaker.OnFill(o, OrderFill{Price: 0.1, Size: 1, Time: o.Created.Add(-500 * time.Millisecond)})
+	if _, paused := breaker.Paused(testTokenID); !paused {
+		t.Error("a fill within the match time's rounding was not fast")
+	}
+}
@@ -0,0 +1,256 @@
+package marketmaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PricePoint is a historical price of a market's YES token
+type PricePoint struct {
+	Time  time.Time `json:"time"`
+	Price float64   `json:"price"`
+}
+
+// ResolvedMarket is a market whose outcome is known, with its price history
+type ResolvedMarket struct {
+	Question   string       `json:"question"`
+	Tags       []Tag        `json:"tags,omitempty"`
+	Category   string       `json:"category,omitempty"` // Labeled category, kept for reference only
+	Outcome    float64      `json:"outcome"`            // 1 if YES won, 0 if NO won, 0.5 for a 50-50 resolution
+	StartDate  time.Time    `json:"start_date,omitempty"`
+	EndDate    time.Time    `json:"end_date,omitempty"` // Scheduled end as listed before resolution; never inferred from ResolvedAt
+	ResolvedAt time.Time    `json:"resolved_at"`
+	Prices     []PricePoint `json:"prices,omitempty"`
+}
+
+// LoadResolvedMarkets reads a JSON array of resolved markets
+func LoadResolvedMarkets(path string) ([]ResolvedMarket, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resolved markets: %w", err)
+	}
+
+	var markets []ResolvedMarket
+	if err := json.Unmarshal(data, &markets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resolved markets: %w", err)
+	}
+
+	var errs []error
+	for i := range markets {
+		m := &markets[i]
+		if strings.TrimSpace(m.Question) == "" {
+			errs = append(errs, fmt.Errorf("market %d: empty question", i))
+		}
+		if m.Outcome < 0 || m.Outcome > 1 {
+			errs = append(errs, fmt.Errorf("market %d: outcome %.3f outside [0,1]", i, m.Outcome))
+		}
+		if m.ResolvedAt.IsZero() {
+			errs = append(errs, fmt.Errorf("market %d: missing resolved_at", i))
+		}
+		sort.Slice(m.Prices, func(a, b int) bool { return m.Prices[a].Time.Before(m.Prices[b].Time) })
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return markets, nil
+}
+
+// PriceAt returns the last price at or before t
+func (m ResolvedMarket) PriceAt(t time.Time) (float64, bool) {
+	i := sort.Search(len(m.Prices), func(i int) bool { return m.Prices[i].Time.After(t) })
+	if i == 0 {
+		return 0, false
+	}
+	return m.Prices[i-1].Price, true
+}
+
+// market rebuilds the Gamma market as it looked at t. Without a scheduled end date the
+// market has none: the resolution time was not known at t, and pricing against it would
+// leak the outcome's timing into the deadline models.
+func (m ResolvedMarket) market(t time.Time) Market {
+	market := Market{Question: m.Question, Tags: m.Tags}
+	if !m.StartDate.IsZero() {
+		market.StartDate = m.StartDate.Format(time.RFC3339)
+	}
+	if !m.EndDate.IsZero() {
+		market.EndDate = m.EndDate.Format(time.RFC3339)
+	}
+	if price, ok := m.PriceAt(t); ok {
+		market.LastTradePrice = price
+	}
+	return market
+}
+
+// CalibrationOptions controls when markets are priced and how results are binned
+type CalibrationOptions struct {
+	At      time.Time     // Price every market at this time; zero prices each market Horizon before it resolved
+	Horizon time.Duration // Lead time before resolution when At is zero (default 7 days)
+	Bins    int           // Reliability bins over [0,1] (default 10)
+}
+
+// CalibrationPrediction is what the strategy would have quoted for one resolved market
+type CalibrationPrediction struct {
+	Question    string         `json:"question"`
+	Labeled     string         `json:"labeled,omitempty"`
+	Category    MarketCategory `json:"category"`
+	Model       string         `json:"model"`
+	At          time.Time      `json:"at"`
+	Probability float64        `json:"probability"`
+	Bid         float64        `json:"bid"`
+	Ask         float64        `json:"ask"`
+	MarketPrice float64        `json:"market_price,omitempty"` // Last traded price at the time, 0 if none
+	Outcome     float64        `json:"outcome"`
+}
+
+// ReliabilityBin compares predicted and realized frequencies for predictions in [Lo, Hi)
+type ReliabilityBin struct {
+	Lo            float64
+	Hi            float64
+	Count         int
+	MeanPredicted float64
+	Observed      float64 // Fraction of markets that resolved YES
+}
+
+// CalibrationMetrics scores a set of predictions
+type CalibrationMetrics struct {
+	Count         int
+	Brier         float64 // Mean squared error; 0 is perfect, 0.25 is a coin flip
+	LogLoss       float64 // Mean negative log-likelihood of the outcome
+	MeanPredicted float64
+	BaseRate      float64          // Fraction of markets that resolved YES
+	MarketCount   int              // Predictions with a market price to compare against
+	MarketBrier   float64          // Brier score of the market price on those predictions
+	ModelBrier    float64          // Our Brier score on the same predictions
+	Reliability   []ReliabilityBin // Only bins with predictions
+}
+
+// CalibrationReport is the result of backtesting a strategy on resolved markets
+type CalibrationReport struct {
+	Overall     CalibrationMetrics
+	Categories  map[MarketCategory]CalibrationMetrics
+	Models      map[string]CalibrationMetrics // By quoting model or rule, e.g. "rules/sports-longshot"
+	Predictions []CalibrationPrediction
+	Skipped     int // Markets not open at their pricing time
+}
+
+// Calibrate prices each resolved market as the strategy would have at the chosen time,
+// then scores the fair values against the outcomes. It runs the pricing models only: the
+// strategy's Toxicity and Posteriors were learned from live trading, after the pricing time.
+func (ps *PricingStrategy) Calibrate(markets []ResolvedMarket, opts CalibrationOptions) CalibrationReport {
+	horizon := opts.Horizon
+	if horizon <= 0 {
+		horizon = 7 * 24 * time.Hour
+	}
+
+	report := CalibrationReport{
+		Categories: make(map[MarketCategory]CalibrationMetrics),
+		Models:     make(map[string]CalibrationMetrics),
+	}
+	for _, m := range markets {
+		at := opts.At
+		if at.IsZero() {
+			at = m.ResolvedAt.Add(-horizon)
+		}
+		if !at.Before(m.ResolvedAt) || (!m.StartDate.IsZero() && at.Before(m.StartDate)) {
+			report.Skipped++
+			continue
+		}
+
+		market := m.market(at)
+		category := ps.CategorizeMarketData(market, nil)
+		quote := ps.priceModels(PricingInput{Market: market, Category: category, Now: at})
+
+		report.Predictions = append(report.Predictions, CalibrationPrediction{
+			Question:    m.Question,
+			Labeled:     m.Category,
+			Category:    category,
+			Model:       quote.Model,
+			At:          at,
+			Probability: quote.FairValue,
+			Bid:         quote.Bid,
+			Ask:         quote.Ask,
+			MarketPrice: market.LastTradePrice,
+			Outcome:     m.Outcome,
+		})
+	}
+
+	byCategory := make(map[MarketCategory][]CalibrationPrediction)
+	byModel := make(map[string][]CalibrationPrediction)
+	for _, p := range report.Predictions {
+		byCategory[p.Category] = append(byCategory[p.Category], p)
+		byModel[p.Model] = append(byModel[p.Model], p)
+	}
+
+	report.Overall = ScoreCalibration(report.Predictions, opts.Bins)
+	for category, preds := range byCategory {
+		report.Categories[category] = ScoreCalibration(preds, opts.Bins)
+	}
+	for model, preds := range byModel {
+		report.Models[model] = ScoreCalibration(preds, opts.Bins)
+	}
+	return report
+}
+
+// ScoreCalibration computes Brier score, log loss and a reliability curve
+func ScoreCalibration(preds []CalibrationPrediction, bins int) CalibrationMetrics {
+	if bins <= 0 {
+		bins = 10
+	}
+
+	var m CalibrationMetrics
+	curve := make([]ReliabilityBin, bins)
+	for i := range curve {
+		curve[i].Lo, curve[i].Hi = float64(i)/float64(bins), float64(i+1)/float64(bins)
+	}
+
+	for _, p := range preds {
+		prob := math.Max(probabilityEpsilon, math.Min(1-probabilityEpsilon, p.Probability))
+		brier := (p.Probability - p.Outcome) * (p.Probability - p.Outcome)
+
+		m.Count++
+		m.Brier += brier
+		m.LogLoss -= p.Outcome*math.Log(prob) + (1-p.Outcome)*math.Log(1-prob)
+		m.MeanPredicted += p.Probability
+		m.BaseRate += p.Outcome
+
+		if p.MarketPrice > 0 {
+			m.MarketCount++
+			m.MarketBrier += (p.MarketPrice - p.Outcome) * (p.MarketPrice - p.Outcome)
+			m.ModelBrier += brier
+		}
+
+		bin := &curve[int(math.Min(float64(bins-1), p.Probability*float64(bins)))]
+		bin.Count++
+		bin.MeanPredicted += p.Probability
+		bin.Observed += p.Outcome
+	}
+
+	if m.Count == 0 {
+		return m
+	}
+	n := float64(m.Count)
+	m.Brier /= n
+	m.LogLoss /= n
+	m.MeanPredicted /= n
+	m.BaseRate /= n
+	if m.MarketCount > 0 {
+		m.MarketBrier /= float64(m.MarketCount)
+		m.ModelBrier /= float64(m.MarketCount)
+	}
+
+	for _, bin := range curve {
+		if bin.Count == 0 {
+			continue
+		}
+		bin.MeanPredicted /= float64(bin.Count)
+		bin.Observed /= float64(bin.Count)
+		m.Reliability = append(m.Reliability, bin)
+	}
+	return m
+}
@@ -0,0 +1,37 @@
+package marketmaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolvedMarketDoesNotLeakResolutionTime(t *testing.T) {
+	resolved := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	at := resolved.Add(-7 * 24 * time.Hour)
+
+	m := ResolvedMarket{Question: "Will it happen?", ResolvedAt: resolved}
+	if got := m.market(at).EndDate; got != "" {
+		t.Errorf("end date %q inferred from the resolution time, want none", got)
+	}
+	m.EndDate = resolved.Add(-time.Hour)
+	if got, want := m.market(at).EndDate, m.EndDate.Format(time.RFC3339); got != want {
+		t.Errorf("end date %q, want the scheduled %q", got, want)
+	}
+}
+
+func TestCalibrateIgnoresLearnedState(t *testing.T) {
+	resolved := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	markets := []ResolvedMarket{{Question: "Will it happen?", Outcome: 1, ResolvedAt: resolved}}
+
+	want := (&PricingStrategy{}).Calibrate(markets, CalibrationOptions{}).Predictions[0]
+
+	// Widening learned from today's fills did not exist when the market was priced
+	tox := NewToxicityTracker()
+	tox.categories = map[MarketCategory]*toxicityState{want.Category: {multiplier: 3}}
+	ps := &PricingStrategy{Toxicity: tox, Posteriors: NewPosteriorBook(PosteriorConfig{})}
+	got := ps.Calibrate(markets, CalibrationOptions{}).Predictions[0]
+	if got.Bid != want.Bid || got.Ask != want.Ask || got.Probability != want.Probability {
+		t.Errorf("quote %.4f/%.4f at %.4f with learned state, want %.4f/%.4f at %.4f",
+			got.Bid, got.Ask, got.Probability, want.Bid, want.Ask, want.Probability)
+	}
+}
@@ -0,0 +1,268 @@
+package marketmaker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ClassifierConfig tunes how category scores turn into a classification.
+// Zero values use the defaults noted on each field.
+type ClassifierConfig struct {
+	MinScore       float64 `json:"min_score"`       // Top score needed to assign a category (default 1)
+	LabelThreshold float64 `json:"label_threshold"` // Confidence needed to appear as a label (default 0.2)
+	AmbiguityRatio float64 `json:"ambiguity_ratio"` // Runner-up/top score ratio at which the result is ambiguous (default 0.75; 0 uses the default, 1 flags only ties)
+	PriorWeight    float64 `json:"prior_weight"`    // Score mass reserved for "none of the above" (default 1)
+}
+
+func (c *ClassifierConfig) validate() error {
+	var errs []error
+	if c.MinScore < 0 {
+		errs = append(errs, errors.New("min_score must not be negative"))
+	}
+	if c.LabelThreshold < 0 || c.LabelThreshold > 1 {
+		errs = append(errs, errors.New("label_threshold must be within [0,1]"))
+	}
+	if c.AmbiguityRatio < 0 || c.AmbiguityRatio > 1 {
+		errs = append(errs, errors.New("ambiguity_ratio must be within [0,1]"))
+	}
+	if c.PriorWeight < 0 {
+		errs = append(errs, errors.New("prior_weight must not be negative"))
+	}
+
+	if c.MinScore == 0 {
+		c.MinScore = 1
+	}
+	if c.LabelThreshold == 0 {
+		c.LabelThreshold = 0.2
+	}
+	if c.AmbiguityRatio == 0 {
+		c.AmbiguityRatio = 0.75
+	}
+	if c.PriorWeight == 0 {
+		c.PriorWeight = 1
+	}
+
+	return errors.Join(errs...)
+}
+
+// CategoryScore is the classifier's evidence for one category
+type CategoryScore struct {
+	Category   MarketCategory `json:"category"`
+	Score      float64        `json:"score"`              // Sum of matched feature weights
+	Confidence float64        `json:"confidence"`         // Share of total score, including the "none of the above" prior
+	Features   []string       `json:"features,omitempty"` // Names of the matched features
+}
+
+// Classification is the result of classifying a question
+type Classification struct {
+	Category   MarketCategory  // Top category; CategoryUnknown when evidence is weak or ambiguous
+	Confidence float64         // Confidence in Category
+	Ambiguous  bool            // Two or more categories scored too closely to pick one
+	Labels     []CategoryScore // Every category above the label threshold, best first
+}
+
+// Classify scores the question against every category feature and returns a
+// multi-label classification
+func (rs *RuleSet) Classify(question string, tags []Tag) Classification {
+	text := newQuestionText(question)
+	cfg := rs.Classifier
+
+	scores := make(map[MarketCategory]*CategoryScore)
+	total := cfg.PriorWeight
+
+	for i, cr := range rs.Categories {
+		if !cr.Match.matches(text, tags) {
+			continue
+		}
+		cs, ok := scores[cr.category]
+		if !ok {
+			cs = &CategoryScore{Category: cr.category}
+			scores[cr.category] = cs
+		}
+		cs.Score += cr.weight()
+		cs.Features = append(cs.Features, cr.featureName(i))
+		total += cr.weight()
+	}
+
+	ranked := make([]CategoryScore, 0, len(scores))
+	for _, cs := range scores {
+		cs.Confidence = cs.Score / total
+		ranked = append(ranked, *cs)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Category < ranked[j].Category
+	})
+
+	var result Classification
+	for _, cs := range ranked {
+		if cs.Confidence >= cfg.LabelThreshold {
+			result.Labels = append(result.Labels, cs)
+		}
+	}
+
+	if len(ranked) == 0 || ranked[0].Score < cfg.MinScore {
+		return result
+	}
+
+	if len(ranked) > 1 && ranked[1].Score >= ranked[0].Score*cfg.AmbiguityRatio {
+		result.Ambiguous = true
+		return result
+	}
+
+	result.Category = ranked[0].Category
+	result.Confidence = ranked[0].Confidence
+	return result
+}
+
+// featureName identifies a category rule in classification output
+func (cr *CategoryRule) featureName(index int) string {
+	if cr.Name != "" {
+		return cr.Name
+	}
+	return fmt.Sprintf("%s#%d", cr.Category, index)
+}
+
+// ClassifyMarket classifies a market from its question and Gamma tags
+func (ps *PricingStrategy) ClassifyMarket(market Market) Classification {
+	return ps.rules().Classify(market.Question, market.Tags)
+}
+
+// questionText is a question prepared for keyword matching
+type questionText struct {
+	lower  string
+	tokens []string
+}
+
+func newQuestionText(question string) questionText {
+	lower := strings.ToLower(question)
+	return questionText{lower: lower, tokens: tokenize(lower)}
+}
+
+// tokenize splits text into lowercase words on anything that is not a letter or digit
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// keywordPhrase is a keyword split into words; prefix makes the last word match any
+// word it starts
+type keywordPhrase struct {
+	words  []string
+	prefix bool
+}
+
+func compileKeywords(keywords []string) ([]keywordPhrase, error) {
+	phrases := make([]keywordPhrase, 0, len(keywords))
+	for _, keyword := range keywords {
+		prefix := strings.HasSuffix(keyword, "*")
+		words := tokenize(strings.TrimSuffix(keyword, "*"))
+		if len(words) == 0 {
+			return nil, fmt.Errorf("keyword %q has no words", keyword)
+		}
+		phrases = append(phrases, keywordPhrase{words: words, prefix: prefix})
+	}
+	return phrases, nil
+}
+
+// contains reports whether the phrase appears as consecutive whole words
+func (t questionText) contains(phrase keywordPhrase) bool {
+	n := len(phrase.words)
+	for start := 0; start+n <= len(t.tokens); start++ {
+		matched := true
+		for i, word := range phrase.words {
+			token := t.tokens[start+i]
+			last := i == n-1
+			if token != word && !(last && phrase.prefix && strings.HasPrefix(token, word)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (t questionText) containsAny(phrases []keywordPhrase) bool {
+	for _, phrase := range phrases {
+		if t.contains(phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// CategoryMetrics are per-category classifier statistics over a labeled corpus
+type CategoryMetrics struct {
+	TruePositives  int
+	FalsePositives int
+	FalseNegatives int
+	Precision      float64
+	Recall         float64
+}
+
+// ClassifierReport summarizes classifier performance over a labeled corpus
+type ClassifierReport struct {
+	Total      int
+	Correct    int
+	Ambiguous  int
+	Accuracy   float64
+	Categories map[MarketCategory]*CategoryMetrics
+}
+
+// EvaluateClassifier classifies every case and measures precision and recall per
+// category. Ambiguous results count as CategoryUnknown.
+func (rs *RuleSet) EvaluateClassifier(cases []RuleCase) ClassifierReport {
+	report := ClassifierReport{Categories: make(map[MarketCategory]*CategoryMetrics)}
+	metrics := func(c MarketCategory) *CategoryMetrics {
+		m, ok := report.Categories[c]
+		if !ok {
+			m = &CategoryMetrics{}
+			report.Categories[c] = m
+		}
+		return m
+	}
+
+	for _, c := range cases {
+		want, err := ParseMarketCategory(c.Category)
+		if err != nil {
+			continue
+		}
+
+		got := rs.Classify(c.Question, c.Tags)
+		report.Total++
+		if got.Ambiguous {
+			report.Ambiguous++
+		}
+
+		if got.Category == want {
+			report.Correct++
+			metrics(want).TruePositives++
+			continue
+		}
+		metrics(got.Category).FalsePositives++
+		metrics(want).FalseNegatives++
+	}
+
+	if report.Total > 0 {
+		report.Accuracy = float64(report.Correct) / float64(report.Total)
+	}
+	for _, m := range report.Categories {
+		if predicted := m.TruePositives + m.FalsePositives; predicted > 0 {
+			m.Precision = float64(m.TruePositives) / float64(predicted)
+		}
+		if actual := m.TruePositives + m.FalseNegatives; actual > 0 {
+			m.Recall = float64(m.TruePositives) / float64(actual)
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,48 @@
+package marketmaker
+
+import "testing"
+
+func TestClassifyWordBoundaries(t *testing.T) {
+	rules := DefaultRuleSet()
+	tests := []struct {
+		question string
+		want     MarketCategory
+	}{
+		// "fed" is the central bank, not a prefix of "federal"
+		{"Fed rate increase at the next meeting?", CategoryEconomic},
+		{"Will the Federal Reserve cut rates in March?", CategoryEconomic},
+		{"Will there be a federal government shutdown?", CategoryUnknown},
+		{"Will the Confederate statue be removed?", CategoryUnknown},
+		// "win" alone is not sports; a mayoral race is politics
+		{"Will Zohran Mamdani win the NYC mayoral race?", CategoryPolitics},
+		{"Will Jensen win?", CategoryUnknown},
+		{"Will the Dodgers win the World Series?", CategorySports},
+	}
+	for _, tt := range tests {
+		if got := rules.Classify(tt.question, nil); got.Category != tt.want {
+			t.Errorf("%q classified %s, want %s", tt.question, got.Category, tt.want)
+		}
+	}
+}
+
+func TestEvaluateClassifierMetrics(t *testing.T) {
+	rules := DefaultRuleSet()
+	cases := []RuleCase{
+		{Question: "Will the Dodgers win the World Series?", Category: "sports"},
+		{Question: "Will the Federal Reserve cut rates in March?", Category: "economic"},
+		{Question: "Will Zohran Mamdani win the NYC mayoral race?", Category: "sports"}, // mislabeled on purpose
+		{Question: "Will it rain?", Category: "not-a-category"},                         // skipped
+	}
+	report := rules.EvaluateClassifier(cases)
+
+	if report.Total != 3 || report.Correct != 2 {
+		t.Fatalf("%d of %d correct, want 2 of 3", report.Correct, report.Total)
+	}
+	sports, politics := report.Categories[CategorySports], report.Categories[CategoryPolitics]
+	if sports == nil || sports.Precision != 1 || sports.Recall != 0.5 {
+		t.Errorf("sports metrics %+v, want precision 1 and recall 0.5", sports)
+	}
+	if politics == nil || politics.FalsePositives != 1 || politics.Precision != 0 {
+		t.Errorf("politics metrics %+v, want one false positive", politics)
+	}
+}
@@ -58,6 +58,42 @@ func (mm *MarketMaker) FetchMarkets() ([]Market, error) {
 	return markets, nil
 }
 
+// FetchEvent retrieves an event with every open market in it from the Gamma API
+func (mm *MarketMaker) FetchEvent(id string) (*EventContext, error) {
+	url := fmt.Sprintf("%s/events/%s", GammaAPIURL, id)
+
+	resp, err := mm.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gamma API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event response: %w", err)
+	}
+
+	var event struct {
+		Title   string   `json:"title"`
+		Markets []Market `json:"markets"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	ctx := &EventContext{Title: event.Title}
+	for _, market := range event.Markets {
+		if !market.Closed {
+			ctx.Markets = append(ctx.Markets, market)
+		}
+	}
+	return ctx, nil
+}
+
 // GetOrderBook fetches the orderbook for a specific token
 func (mm *MarketMaker) GetOrderBook(tokenID string) (*OrderBookResponse, error) {
 	url := fmt.Sprintf("%s/book?token_id=%s", CLOBURL, tokenID)
@@ -85,6 +121,46 @@ func (mm *MarketMaker) GetOrderBook(tokenID string) (*OrderBookResponse, error)
 	return &orderbook, nil
 }
 
+// priceHistoryFidelity is the spacing of the points GetPriceHistory returns
+const priceHistoryFidelity = time.Hour
+
+// GetPriceHistory fetches a token's prices over the past week, one per hour, oldest first
+func (mm *MarketMaker) GetPriceHistory(tokenID string) ([]float64, error) {
+	url := fmt.Sprintf("%s/prices-history?market=%s&interval=1w&fidelity=%d",
+		CLOBURL, tokenID, int(priceHistoryFidelity/time.Minute))
+
+	resp, err := mm.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price history: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CLOB API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price history response: %w", err)
+	}
+
+	var history struct {
+		History []struct {
+			T int64   `json:"t"`
+			P float64 `json:"p"`
+		} `json:"history"`
+	}
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal price history: %w", err)
+	}
+
+	prices := make([]float64, 0, len(history.History))
+	for _, point := range history.History {
+		prices = append(prices, point.P)
+	}
+	return prices, nil
+}
+
 // parseFloat safely parses a string to float64
 func parseFloat(s string) (float64, error) {
 	if s == "" {
@@ -109,3 +185,45 @@ func parseVolume(v interface{}) float64 {
 		return 0
 	}
 }
+
+// TokenIDs decodes the market's JSON-encoded CLOB token IDs, YES first
+func (m Market) TokenIDs() []string {
+	var ids []string
+	if m.ClobTokenIDs == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(m.ClobTokenIDs), &ids); err != nil {
+		return nil
+	}
+	return ids
+}
+
+// yesTokenID returns the market's YES token, or "" if it has none
+func (m Market) yesTokenID() string {
+	if ids := m.TokenIDs(); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// parseOutcomePrices decodes Gamma's JSON-encoded outcome price array
+func parseOutcomePrices(s string) []float64 {
+	if s == "" {
+		return nil
+	}
+
+	var raw []string
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil
+	}
+
+	prices := make([]float64, 0, len(raw))
+	for _, r := range raw {
+		p, err := parseFloat(r)
+		if err != nil {
+			return nil
+		}
+		prices = append(prices, p)
+	}
+	return prices
+}
@@ -0,0 +1,470 @@
+package marketmaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// OrderType is how long an order rests on the book
+type OrderType string
+
+const (
+	OrderGTC OrderType = "GTC" // Good till cancelled
+	OrderGTD OrderType = "GTD" // Good till the order's expiration
+	OrderFOK OrderType = "FOK" // Fill entirely at once or cancel
+	OrderFAK OrderType = "FAK" // Fill what is available at once, cancel the rest
+)
+
+// SignedOrder is an order as posted to the CLOB: amounts in 6-decimal base units, signed by Signer
+type SignedOrder struct {
+	Salt          int64  `json:"salt"`
+	Maker         string `json:"maker"`
+	Signer        string `json:"signer"`
+	Taker         string `json:"taker"`
+	TokenID       string `json:"tokenId"`
+	MakerAmount   string `json:"makerAmount"`
+	TakerAmount   string `json:"takerAmount"`
+	Expiration    string `json:"expiration"`
+	Nonce         string `json:"nonce"`
+	FeeRateBps    string `json:"feeRateBps"`
+	Side          string `json:"side"` // "BUY" or "SELL"
+	SignatureType int    `json:"signatureType"`
+	Signature     string `json:"signature"`
+}
+
+// PostOrderRequest is the body of POST /order
+type PostOrderRequest struct {
+	Order     SignedOrder `json:"order"`
+	Owner     string      `json:"owner"` // API key of the poster
+	OrderType OrderType   `json:"orderType"`
+}
+
+// PostOrderResponse is the CLOB's answer to a new order
+type PostOrderResponse struct {
+	Success           bool     `json:"success"`
+	ErrorMsg          string   `json:"errorMsg"`
+	OrderID           string   `json:"orderID"`
+	Status            string   `json:"status"` // "live", "matched", "delayed" or "unmatched"
+	TransactionHashes []string `json:"transactionsHashes,omitempty"`
+	MakingAmount      string   `json:"makingAmount,omitempty"`
+	TakingAmount      string   `json:"takingAmount,omitempty"`
+}
+
+// CancelResponse lists which orders a cancel request removed
+type CancelResponse struct {
+	Canceled    []string          `json:"canceled"`
+	NotCanceled map[string]string `json:"not_canceled"` // Order ID to reason
+}
+
+// OpenOrder is an order as the CLOB reports it
+type OpenOrder struct {
+	ID              string   `json:"id"`
+	Status          string   `json:"status"` // "LIVE", "MATCHED", "CANCELED"...
+	Owner           string   `json:"owner"`
+	MakerAddress    string   `json:"maker_address"`
+	Market          string   `json:"market"` // Condition ID
+	AssetID         string   `json:"asset_id"`
+	Side            string   `json:"side"`
+	OriginalSize    string   `json:"original_size"`
+	SizeMatched     string   `json:"size_matched"`
+	Price           string   `json:"price"`
+	Outcome         string   `json:"outcome"`
+	Expiration      string   `json:"expiration"`
+	OrderType       string   `json:"order_type"`
+	AssociateTrades []string `json:"associate_trades"`
+	CreatedAt       int64    `json:"created_at"`
+}
+
+// MakerOrder is one resting order matched by a trade
+type MakerOrder struct {
+	OrderID       string `json:"order_id"`
+	Owner         string `json:"owner"`
+	MakerAddress  string `json:"maker_address"`
+	MatchedAmount string `json:"matched_amount"`
+	Price         string `json:"price"`
+	AssetID       string `json:"asset_id"`
+	Side          string `json:"side"`
+	Outcome       string `json:"outcome"`
+}
+
+// Trade is a match involving one of our orders
+type Trade struct {
+	ID              string       `json:"id"`
+	TakerOrderID    string       `json:"taker_order_id"`
+	Market          string       `json:"market"`
+	AssetID         string       `json:"asset_id"`
+	Side            string       `json:"side"` // Taker's side
+	Size            string       `json:"size"`
+	FeeRateBps      string       `json:"fee_rate_bps"`
+	Price           string       `json:"price"`
+	Status          string       `json:"status"` // "MATCHED", "MINED", "CONFIRMED", "RETRYING" or "FAILED"
+	MatchTime       string       `json:"match_time"`
+	Outcome         string       `json:"outcome"`
+	Owner           string       `json:"owner"`
+	MakerAddress    string       `json:"maker_address"`
+	TraderSide      string       `json:"trader_side"` // Whether we were "TAKER" or "MAKER"
+	TransactionHash string       `json:"transaction_hash"`
+	MakerOrders     []MakerOrder `json:"maker_orders"`
+}
+
+// MatchedAt is when the trade matched. A trade without a usable match time is dated seen,
+// the time it was first observed.
+func (t Trade) MatchedAt(seen time.Time) time.Time {
+	if secs, err := strconv.ParseFloat(t.MatchTime, 64); err == nil && secs > 0 {
+		return time.Unix(int64(secs), 0)
+	}
+	return seen
+}
+
+// OrderFilter narrows an open-orders listing; empty fields match everything
+type OrderFilter struct {
+	ID      string
+	Market  string
+	AssetID string
+}
+
+// TradeFilter narrows a trades listing; empty fields match everything
+type TradeFilter struct {
+	ID      string
+	Market  string
+	AssetID string
+	Maker   string
+	Before  time.Time
+	After   time.Time
+}
+
+// AssetType selects collateral (USDC) or an outcome token balance
+type AssetType string
+
+const (
+	AssetCollateral  AssetType = "COLLATERAL"
+	AssetConditional AssetType = "CONDITIONAL"
+)
+
+// BalanceAllowance is a wallet's balance and exchange allowance for one asset, in whole units
+type BalanceAllowance struct {
+	Balance   float64
+	Allowance float64
+}
+
+// tokenDecimals is the base-unit scale of USDC and outcome tokens
+const tokenDecimals = 1e6
+
+// Pagination cursors used by the CLOB's listings
+const (
+	firstCursor = "MA=="
+	endCursor   = "LTE="
+)
+
+// CLOBError is a non-2xx response from the CLOB
+type CLOBError struct {
+	Status  int
+	Message string
+}
+
+func (e *CLOBError) Error() string {
+	return fmt.Sprintf("CLOB API returned status %d: %s", e.Status, e.Message)
+}
+
+// CLOBClient places and manages orders on the CLOB. Creating or deriving API keys needs only
+// the wallet (L1); everything else also needs credentials (L2).
+type CLOBClient struct {
+	BaseURL       string
+	ChainID       int64
+	Wallet        *Wallet
+	Creds         *APICredentials
+	SignatureType int // Wallet type orders are signed with, 0 for a plain EOA
+	HTTPClient    *http.Client
+	Now           func() time.Time // Clock for request timestamps (nil uses time.Now)
+}
+
+// NewCLOBClient creates a client for the production CLOB on Polygon
+func NewCLOBClient(wallet *Wallet, creds *APICredentials) *CLOBClient {
+	return &CLOBClient{
+		BaseURL:    CLOBURL,
+		ChainID:    PolygonChainID,
+		Wallet:     wallet,
+		Creds:      creds,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// CreateAPIKey creates new L2 credentials for the wallet
+func (c *CLOBClient) CreateAPIKey(ctx context.Context, nonce uint64) (APICredentials, error) {
+	var creds APICredentials
+	if err := c.doL1(ctx, http.MethodPost, "/auth/api-key", nonce, &creds); err != nil {
+		return APICredentials{}, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return creds, nil
+}
+
+// DeriveAPIKey recovers the credentials previously created for the wallet and nonce
+func (c *CLOBClient) DeriveAPIKey(ctx context.Context, nonce uint64) (APICredentials, error) {
+	var creds APICredentials
+	if err := c.doL1(ctx, http.MethodGet, "/auth/derive-api-key", nonce, &creds); err != nil {
+		return APICredentials{}, fmt.Errorf("failed to derive API key: %w", err)
+	}
+	return creds, nil
+}
+
+// CreateOrDeriveAPIKey creates credentials, or derives them if the wallet already has some,
+// and makes them the client's credentials
+func (c *CLOBClient) CreateOrDeriveAPIKey(ctx context.Context, nonce uint64) (APICredentials, error) {
+	creds, err := c.CreateAPIKey(ctx, nonce)
+	if err != nil {
+		var derr error
+		if creds, derr = c.DeriveAPIKey(ctx, nonce); derr != nil {
+			return APICredentials{}, errors.Join(err, derr)
+		}
+	}
+	c.Creds = &creds
+	return creds, nil
+}
+
+// PostOrder submits a signed order
+func (c *CLOBClient) PostOrder(ctx context.Context, order SignedOrder, orderType OrderType) (PostOrderResponse, error) {
+	if c.Creds == nil {
+		return PostOrderResponse{}, errors.New("failed to post order: no API credentials")
+	}
+
+	var resp PostOrderResponse
+	req := PostOrderRequest{Order: order, Owner: c.Creds.APIKey, OrderType: orderType}
+	if err := c.doL2(ctx, http.MethodPost, "/order", nil, req, &resp); err != nil {
+		return PostOrderResponse{}, fmt.Errorf("failed to post order: %w", err)
+	}
+	if !resp.Success {
+		return resp, fmt.Errorf("order rejected: %s", resp.ErrorMsg)
+	}
+	return resp, nil
+}
+
+// CancelOrder cancels one order
+func (c *CLOBClient) CancelOrder(ctx context.Context, orderID string) (CancelResponse, error) {
+	var resp CancelResponse
+	if err := c.doL2(ctx, http.MethodDelete, "/order", nil, map[string]string{"orderID": orderID}, &resp); err != nil {
+		return CancelResponse{}, fmt.Errorf("failed to cancel order %s: %w", orderID, err)
+	}
+	return resp, nil
+}
+
+// CancelOrders cancels several orders in one request
+func (c *CLOBClient) CancelOrders(ctx context.Context, orderIDs []string) (CancelResponse, error) {
+	var resp CancelResponse
+	if err := c.doL2(ctx, http.MethodDelete, "/orders", nil, orderIDs, &resp); err != nil {
+		return CancelResponse{}, fmt.Errorf("failed to cancel %d orders: %w", len(orderIDs), err)
+	}
+	return resp, nil
+}
+
+// CancelAll cancels every open order for the API key
+func (c *CLOBClient) CancelAll(ctx context.Context) (CancelResponse, error) {
+	var resp CancelResponse
+	if err := c.doL2(ctx, http.MethodDelete, "/cancel-all", nil, nil, &resp); err != nil {
+		return CancelResponse{}, fmt.Errorf("failed to cancel all orders: %w", err)
+	}
+	return resp, nil
+}
+
+// Order fetches one order by exchange ID
+func (c *CLOBClient) Order(ctx context.Context, orderID string) (OpenOrder, error) {
+	var order OpenOrder
+	if err := c.doL2(ctx, http.MethodGet, "/data/order/"+orderID, nil, nil, &order); err != nil {
+		return OpenOrder{}, fmt.Errorf("failed to get order %s: %w", orderID, err)
+	}
+	return order, nil
+}
+
+// OpenOrders lists open orders, following pagination to the end
+func (c *CLOBClient) OpenOrders(ctx context.Context, filter OrderFilter) ([]OpenOrder, error) {
+	query := url.Values{}
+	setIf(query, "id", filter.ID)
+	setIf(query, "market", filter.Market)
+	setIf(query, "asset_id", filter.AssetID)
+
+	var orders []OpenOrder
+	if err := paginate(ctx, c, "/data/orders", query, &orders); err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	return orders, nil
+}
+
+// Trades lists our trades, following pagination to the end
+func (c *CLOBClient) Trades(ctx context.Context, filter TradeFilter) ([]Trade, error) {
+	query := url.Values{}
+	setIf(query, "id", filter.ID)
+	setIf(query, "market", filter.Market)
+	setIf(query, "asset_id", filter.AssetID)
+	setIf(query, "maker_address", filter.Maker)
+	if !filter.Before.IsZero() {
+		query.Set("before", strconv.FormatInt(filter.Before.Unix(), 10))
+	}
+	if !filter.After.IsZero() {
+		query.Set("after", strconv.FormatInt(filter.After.Unix(), 10))
+	}
+
+	var trades []Trade
+	if err := paginate(ctx, c, "/data/trades", query, &trades); err != nil {
+		return nil, fmt.Errorf("failed to list trades: %w", err)
+	}
+	return trades, nil
+}
+
+// BalanceAllowance fetches the wallet's balance of collateral, or of one outcome token
+func (c *CLOBClient) BalanceAllowance(ctx context.Context, asset AssetType, tokenID string) (BalanceAllowance, error) {
+	query := url.Values{}
+	query.Set("asset_type", string(asset))
+	setIf(query, "token_id", tokenID)
+	query.Set("signature_type", strconv.Itoa(c.SignatureType))
+
+	var raw struct {
+		Balance   string `json:"balance"`
+		Allowance string `json:"allowance"`
+	}
+	if err := c.doL2(ctx, http.MethodGet, "/balance-allowance", query, nil, &raw); err != nil {
+		return BalanceAllowance{}, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	balance, err := parseFloat(raw.Balance)
+	if err != nil {
+		return BalanceAllowance{}, fmt.Errorf("failed to parse balance %q: %w", raw.Balance, err)
+	}
+	allowance, _ := parseFloat(raw.Allowance)
+	return BalanceAllowance{Balance: balance / tokenDecimals, Allowance: allowance / tokenDecimals}, nil
+}
+
+// CollateralBankroll sizes against the wallet's USDC balance
+func (c *CLOBClient) CollateralBankroll(ctx context.Context) BankrollSource {
+	return BankrollFunc(func() (float64, error) {
+		b, err := c.BalanceAllowance(ctx, AssetCollateral, "")
+		return b.Balance, err
+	})
+}
+
+func (c *CLOBClient) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// doL1 sends a request authenticated by a wallet signature
+func (c *CLOBClient) doL1(ctx context.Context, method, path string, nonce uint64, out interface{}) error {
+	if c.Wallet == nil {
+		return errors.New("no wallet")
+	}
+	headers, err := L1Headers(c.Wallet, c.now().Unix(), nonce, c.ChainID)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, nil, nil, headers, out)
+}
+
+// doL2 sends a request authenticated by an HMAC of its method, path and body
+func (c *CLOBClient) doL2(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	if c.Wallet == nil || c.Creds == nil {
+		return errors.New("no wallet or API credentials")
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+	headers, err := L2Headers(c.Wallet.Address(), *c.Creds, c.now().Unix(), method, path, body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, query, body, headers, out)
+}
+
+func (c *CLOBClient) do(ctx context.Context, method, path string, query url.Values, body []byte, headers http.Header, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = headers
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := string(data)
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		return &CLOBError{Status: resp.StatusCode, Message: msg}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// paginate follows next_cursor through a listing, appending each page's data to out
+func paginate[T any](ctx context.Context, c *CLOBClient, path string, query url.Values, out *[]T) error {
+	cursor := firstCursor
+	for cursor != endCursor {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("next_cursor", cursor)
+
+		var page struct {
+			Data       []T    `json:"data"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.doL2(ctx, http.MethodGet, path, q, nil, &page); err != nil {
+			return err
+		}
+		*out = append(*out, page.Data...)
+
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	return nil
+}
+
+func setIf(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
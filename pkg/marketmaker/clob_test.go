@@ -0,0 +1,212 @@
+package marketmaker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"fiscal/pkg/marketmaker"
+	"fiscal/pkg/marketmaker/clobtest"
+)
+
+const clobTestToken = "1234"
+
+func newTestWallet(t *testing.T, b byte) *marketmaker.Wallet {
+	t.Helper()
+	wallet, err := marketmaker.NewWallet(strings.Repeat(string("0123456789abcdef"[b%16]), 64))
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	return wallet
+}
+
+// newTestClient starts a server and returns a client holding fresh credentials
+func newTestClient(t *testing.T) (*clobtest.Server, *marketmaker.CLOBClient) {
+	t.Helper()
+	srv := clobtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client(newTestWallet(t, 1), nil)
+	if _, err := client.CreateOrDeriveAPIKey(context.Background(), 0); err != nil {
+		t.Fatalf("CreateOrDeriveAPIKey: %v", err)
+	}
+	return srv, client
+}
+
+func buildOrder(t *testing.T, client *marketmaker.CLOBClient, price float64, negRisk bool) marketmaker.SignedOrder {
+	t.Helper()
+	order, err := marketmaker.NewOrderBuilder(client.Wallet, client.ChainID).Build(
+		marketmaker.OrderArgs{TokenID: clobTestToken, Side: marketmaker.SideBuy, Price: price, Size: 10},
+		marketmaker.OrderOptions{TickSize: 0.01, NegRisk: negRisk})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return order
+}
+
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var clobErr *marketmaker.CLOBError
+	if !errors.As(err, &clobErr) || clobErr.Status != status {
+		t.Fatalf("got error %v, want status %d", err, status)
+	}
+}
+
+func TestCLOBClientAPIKeys(t *testing.T) {
+	srv := clobtest.NewServer()
+	defer srv.Close()
+	ctx := context.Background()
+	wallet := newTestWallet(t, 1)
+
+	created, err := srv.Client(wallet, nil).CreateOrDeriveAPIKey(ctx, 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// A second create for the same nonce fails, so the key is derived instead
+	derived, err := srv.Client(wallet, nil).CreateOrDeriveAPIKey(ctx, 0)
+	if err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+	if derived != created {
+		t.Errorf("derived %+v, want %+v", derived, created)
+	}
+	if _, err := srv.Client(wallet, nil).DeriveAPIKey(ctx, 7); err == nil {
+		t.Error("derived a key for a nonce never created")
+	}
+}
+
+func TestCLOBClientOrders(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.PageSize = 2
+	ctx := context.Background()
+
+	var ids []string
+	for _, price := range []float64{0.1, 0.2, 0.3, 0.4, 0.5} {
+		resp, err := client.PostOrder(ctx, buildOrder(t, client, price, false), marketmaker.OrderGTC)
+		if err != nil {
+			t.Fatalf("PostOrder: %v", err)
+		}
+		ids = append(ids, resp.OrderID)
+	}
+
+	// Five orders at two per page take three pages
+	open, err := client.OpenOrders(ctx, marketmaker.OrderFilter{})
+	if err != nil {
+		t.Fatalf("OpenOrders: %v", err)
+	}
+	if len(open) != 5 {
+		t.Fatalf("listed %d open orders, want 5", len(open))
+	}
+	for i, o := range open {
+		if o.ID != ids[i] {
+			t.Errorf("order %d is %s, want %s", i, o.ID, ids[i])
+		}
+	}
+
+	if resp, err := client.CancelOrder(ctx, ids[0]); err != nil || len(resp.Canceled) != 1 {
+		t.Fatalf("CancelOrder: %+v, %v", resp, err)
+	}
+	if resp, err := client.CancelOrders(ctx, ids[1:3]); err != nil || len(resp.Canceled) != 2 {
+		t.Fatalf("CancelOrders: %+v, %v", resp, err)
+	}
+	if resp, err := client.CancelOrder(ctx, ids[0]); err != nil || resp.NotCanceled[ids[0]] == "" {
+		t.Errorf("cancelling twice: %+v, %v", resp, err)
+	}
+
+	if _, err := srv.Fill(ids[3], 4); err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+	o, err := client.Order(ctx, ids[3])
+	if err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+	if o.SizeMatched != "4" || o.Status != "LIVE" {
+		t.Errorf("order after fill: matched %s status %s, want 4 LIVE", o.SizeMatched, o.Status)
+	}
+	trades, err := client.Trades(ctx, marketmaker.TradeFilter{})
+	if err != nil {
+		t.Fatalf("Trades: %v", err)
+	}
+	if len(trades) != 1 || trades[0].MakerOrders[0].OrderID != ids[3] {
+		t.Errorf("trades %+v, want one against %s", trades, ids[3])
+	}
+
+	if resp, err := client.CancelAll(ctx); err != nil || len(resp.Canceled) != 2 {
+		t.Fatalf("CancelAll: %+v, %v", resp, err)
+	}
+	if open, err := client.OpenOrders(ctx, marketmaker.OrderFilter{}); err != nil || len(open) != 0 {
+		t.Errorf("after CancelAll: %d open, %v", len(open), err)
+	}
+	if _, err := client.Order(ctx, "0xmissing"); err == nil {
+		t.Error("fetched an order that does not exist")
+	} else {
+		wantStatus(t, err, http.StatusNotFound)
+	}
+}
+
+func TestCLOBClientRejectsTamperedHMAC(t *testing.T) {
+	srv, client := newTestClient(t)
+	creds := *client.Creds
+	// Another valid key, so the HMAC is computed but does not match
+	creds.Secret = strings.Repeat("A", len(creds.Secret)-1) + "="
+	tampered := srv.Client(client.Wallet, &creds)
+
+	_, err := tampered.OpenOrders(context.Background(), marketmaker.OrderFilter{})
+	wantStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestCLOBClientRejectsWrongL1Signer(t *testing.T) {
+	srv := clobtest.NewServer()
+	defer srv.Close()
+
+	// Signed by one wallet while claiming to be another
+	headers, err := marketmaker.L1Headers(newTestWallet(t, 1), time.Now().Unix(), 0, srv.ChainID)
+	if err != nil {
+		t.Fatalf("L1Headers: %v", err)
+	}
+	headers.Set(marketmaker.HeaderAddress, newTestWallet(t, 2).Address())
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/auth/api-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = headers
+	resp, err := srv.Client(nil, nil).HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestCLOBClientRejectsWrongExchange(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.SetNegRisk(clobTestToken, true)
+	ctx := context.Background()
+
+	if _, err := client.PostOrder(ctx, buildOrder(t, client, 0.5, false), marketmaker.OrderGTC); err == nil {
+		t.Error("accepted a neg-risk order signed for the CTF exchange")
+	}
+	if _, err := client.PostOrder(ctx, buildOrder(t, client, 0.5, true), marketmaker.OrderGTC); err != nil {
+		t.Errorf("neg-risk order signed for the neg-risk exchange: %v", err)
+	}
+}
+
+func TestCLOBClientRejectsStaleTimestamps(t *testing.T) {
+	srv, client := newTestClient(t)
+	stale := func() time.Time { return time.Now().Add(-2 * marketmaker.AuthMaxAge) }
+
+	client.Now = stale
+	_, err := client.OpenOrders(context.Background(), marketmaker.OrderFilter{})
+	wantStatus(t, err, http.StatusUnauthorized)
+
+	fresh := srv.Client(newTestWallet(t, 3), nil)
+	fresh.Now = stale
+	_, err = fresh.CreateAPIKey(context.Background(), 0)
+	wantStatus(t, err, http.StatusUnauthorized)
+}
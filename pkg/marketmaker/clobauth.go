@@ -0,0 +1,164 @@
+package marketmaker
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CLOB authentication headers
+const (
+	HeaderAddress    = "POLY_ADDRESS"
+	HeaderSignature  = "POLY_SIGNATURE"
+	HeaderTimestamp  = "POLY_TIMESTAMP"
+	HeaderNonce      = "POLY_NONCE"
+	HeaderAPIKey     = "POLY_API_KEY"
+	HeaderPassphrase = "POLY_PASSPHRASE"
+)
+
+// AuthMaxAge is how far a request's timestamp may be from the verifier's clock. Older
+// requests are refused, so a captured request cannot be replayed later.
+const AuthMaxAge = 2 * time.Minute
+
+// clobAuthMessage is the fixed statement signed to prove control of a wallet
+const clobAuthMessage = "This message attests that I control the given wallet"
+
+// APICredentials are the L2 credentials the CLOB issues for a wallet
+type APICredentials struct {
+	APIKey     string `json:"apiKey"`
+	Secret     string `json:"secret"` // URL-safe base64 HMAC key
+	Passphrase string `json:"passphrase"`
+}
+
+// ClobAuthHash is the EIP-712 digest a wallet signs for L1 authentication
+func ClobAuthHash(address string, timestamp int64, nonce uint64, chainID int64) ([32]byte, error) {
+	addr, err := abiAddress(address)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	typeHash := keccak256([]byte("ClobAuth(address address,string timestamp,uint256 nonce,string message)"))
+	ts := keccak256([]byte(strconv.FormatInt(timestamp, 10)))
+	n := abiUint(new(big.Int).SetUint64(nonce))
+	msg := keccak256([]byte(clobAuthMessage))
+	structHash := keccak256(typeHash[:], addr[:], ts[:], n[:], msg[:])
+
+	return TypedDataHash(EIP712Domain{Name: "ClobAuthDomain", Version: "1", ChainID: chainID}, structHash)
+}
+
+// L1Headers signs a ClobAuth message, proving control of the wallet to create or derive API keys
+func L1Headers(w *Wallet, timestamp int64, nonce uint64, chainID int64) (http.Header, error) {
+	hash, err := ClobAuthHash(w.Address(), timestamp, nonce, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash auth message: %w", err)
+	}
+
+	h := make(http.Header)
+	h.Set(HeaderAddress, w.Address())
+	h.Set(HeaderSignature, "0x"+hex.EncodeToString(w.SignHash(hash)))
+	h.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	h.Set(HeaderNonce, strconv.FormatUint(nonce, 10))
+	return h, nil
+}
+
+// VerifyL1Headers checks that L1 headers are fresh at now and were signed by the address
+// they claim, and returns it
+func VerifyL1Headers(h http.Header, chainID int64, now time.Time) (string, error) {
+	address := h.Get(HeaderAddress)
+	timestamp, err := verifyTimestamp(h, now)
+	if err != nil {
+		return "", err
+	}
+	nonce := uint64(0)
+	if s := h.Get(HeaderNonce); s != "" {
+		if nonce, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return "", fmt.Errorf("invalid %s: %w", HeaderNonce, err)
+		}
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(h.Get(HeaderSignature), "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %w", HeaderSignature, err)
+	}
+
+	hash, err := ClobAuthHash(address, timestamp, nonce, chainID)
+	if err != nil {
+		return "", err
+	}
+	signer, err := RecoverAddress(hash, sig)
+	if err != nil {
+		return "", err
+	}
+	if !SameAddress(signer, address) {
+		return "", fmt.Errorf("signature is from %s, not %s", signer, address)
+	}
+	return signer, nil
+}
+
+// L2Signature is the HMAC of a request: timestamp, method, path and body, keyed by the API secret
+func L2Signature(secret string, timestamp int64, method, path string, body []byte) (string, error) {
+	key, err := base64.URLEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode API secret: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + method + path))
+	mac.Write(body)
+	return base64.URLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// L2Headers authenticates one request with API credentials
+func L2Headers(address string, creds APICredentials, timestamp int64, method, path string, body []byte) (http.Header, error) {
+	sig, err := L2Signature(creds.Secret, timestamp, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	h := make(http.Header)
+	h.Set(HeaderAddress, address)
+	h.Set(HeaderSignature, sig)
+	h.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	h.Set(HeaderAPIKey, creds.APIKey)
+	h.Set(HeaderPassphrase, creds.Passphrase)
+	return h, nil
+}
+
+// VerifyL2Headers checks that a request is fresh at now and its HMAC matches the credentials
+// issued for its API key
+func VerifyL2Headers(h http.Header, creds APICredentials, method, path string, body []byte, now time.Time) error {
+	if h.Get(HeaderAPIKey) != creds.APIKey || h.Get(HeaderPassphrase) != creds.Passphrase {
+		return errors.New("API key or passphrase mismatch")
+	}
+	timestamp, err := verifyTimestamp(h, now)
+	if err != nil {
+		return err
+	}
+
+	want, err := L2Signature(creds.Secret, timestamp, method, path, body)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(want), []byte(h.Get(HeaderSignature))) {
+		return errors.New("request signature mismatch")
+	}
+	return nil
+}
+
+// verifyTimestamp parses a request's timestamp and checks it is within AuthMaxAge of now
+func verifyTimestamp(h http.Header, now time.Time) (int64, error) {
+	timestamp, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", HeaderTimestamp, err)
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > AuthMaxAge || age < -AuthMaxAge {
+		return 0, fmt.Errorf("stale %s: %s from the server clock, limit %s", HeaderTimestamp, age.Round(time.Second), AuthMaxAge)
+	}
+	return timestamp, nil
+}
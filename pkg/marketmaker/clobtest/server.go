@@ -0,0 +1,543 @@
+// Package clobtest runs a local stand-in for the Polymarket CLOB that verifies L1 and L2
+// signatures, so the trading client can be exercised without touching the real exchange.
+package clobtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fiscal/pkg/marketmaker"
+)
+
+// Server is an in-memory CLOB. Orders rest until cancelled or filled with Fill.
+type Server struct {
+	*httptest.Server
+	ChainID  int64
+	PageSize int              // Listing page size (default 100)
+	Now      func() time.Time // Clock for order and trade times and for checking request timestamps (nil uses time.Now)
+
+	mu       sync.Mutex
+	keys     map[string]marketmaker.APICredentials // "address/nonce" to credentials
+	owners   map[string]string                     // API key to address
+	secrets  map[string]marketmaker.APICredentials // API key to credentials
+	orders   map[string]*marketmaker.OpenOrder
+	sequence []string // Order IDs in creation order
+	trades   []marketmaker.Trade
+	balances map[string]marketmaker.BalanceAllowance
+	negRisk  map[string]bool // Token IDs settled by the negative-risk exchange
+	failures []failure
+	nextID   int
+}
+
+type failure struct {
+	status  int
+	message string
+}
+
+// NewServer starts a server on a local port for the Polygon chain ID. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		ChainID:  marketmaker.PolygonChainID,
+		keys:     make(map[string]marketmaker.APICredentials),
+		owners:   make(map[string]string),
+		secrets:  make(map[string]marketmaker.APICredentials),
+		orders:   make(map[string]*marketmaker.OpenOrder),
+		balances: make(map[string]marketmaker.BalanceAllowance),
+		negRisk:  make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/api-key", s.createAPIKey)
+	mux.HandleFunc("GET /auth/derive-api-key", s.deriveAPIKey)
+	mux.HandleFunc("POST /order", s.l2(s.postOrder))
+	mux.HandleFunc("DELETE /order", s.l2(s.cancelOrder))
+	mux.HandleFunc("DELETE /orders", s.l2(s.cancelOrders))
+	mux.HandleFunc("DELETE /cancel-all", s.l2(s.cancelAll))
+	mux.HandleFunc("GET /data/order/{id}", s.l2(s.getOrder))
+	mux.HandleFunc("GET /data/orders", s.l2(s.listOrders))
+	mux.HandleFunc("GET /data/trades", s.l2(s.listTrades))
+	mux.HandleFunc("GET /balance-allowance", s.l2(s.balanceAllowance))
+
+	s.Server = httptest.NewServer(s.failOrServe(mux))
+	return s
+}
+
+// Client returns a CLOB client pointed at the server
+func (s *Server) Client(wallet *marketmaker.Wallet, creds *marketmaker.APICredentials) *marketmaker.CLOBClient {
+	c := marketmaker.NewCLOBClient(wallet, creds)
+	c.BaseURL = s.URL
+	c.ChainID = s.ChainID
+	c.HTTPClient = s.Server.Client()
+	return c
+}
+
+// FailNext makes the next request fail with the given status, before authentication
+func (s *Server) FailNext(status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, message: message})
+}
+
+// SetBalance sets an address's balance and allowance of collateral (empty tokenID) or a token
+func (s *Server) SetBalance(address, tokenID string, balance, allowance float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balances[balanceKey(address, tokenID)] = marketmaker.BalanceAllowance{Balance: balance, Allowance: allowance}
+}
+
+// SetNegRisk marks a token as settled by the negative-risk exchange, so its orders must be
+// signed for that contract
+func (s *Server) SetNegRisk(tokenID string, negRisk bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.negRisk[tokenID] = negRisk
+}
+
+// Orders returns every order the server has seen, in creation order
+func (s *Server) Orders() []marketmaker.OpenOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]marketmaker.OpenOrder, len(s.sequence))
+	for i, id := range s.sequence {
+		out[i] = *s.orders[id]
+	}
+	return out
+}
+
+// Fill matches size of a resting order against a taker, recording a trade
+func (s *Server) Fill(orderID string, size float64) (marketmaker.Trade, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[orderID]
+	if !ok || o.Status != "LIVE" {
+		return marketmaker.Trade{}, fmt.Errorf("order %s is not live", orderID)
+	}
+	original, _ := strconv.ParseFloat(o.OriginalSize, 64)
+	matched, _ := strconv.ParseFloat(o.SizeMatched, 64)
+	if size <= 0 || matched+size > original+1e-9 {
+		return marketmaker.Trade{}, fmt.Errorf("cannot fill %.2f of order %s with %.2f remaining", size, orderID, original-matched)
+	}
+
+	matched += size
+	o.SizeMatched = formatAmount(matched)
+	if original-matched < 1e-9 {
+		o.Status = "MATCHED"
+	}
+
+	takerSide := "SELL"
+	if o.Side == "SELL" {
+		takerSide = "BUY"
+	}
+	s.nextID++
+	trade := marketmaker.Trade{
+		ID:           fmt.Sprintf("trade-%d", s.nextID),
+		TakerOrderID: fmt.Sprintf("taker-%d", s.nextID),
+		Market:       o.Market,
+		AssetID:      o.AssetID,
+		Side:         takerSide,
+		Size:         formatAmount(size),
+		FeeRateBps:   "0",
+		Price:        o.Price,
+		Status:       "MATCHED",
+		MatchTime:    strconv.FormatInt(s.now().Unix(), 10),
+		Outcome:      o.Outcome,
+		Owner:        o.Owner,
+		MakerAddress: o.MakerAddress,
+		TraderSide:   "MAKER",
+		MakerOrders: []marketmaker.MakerOrder{{
+			OrderID:       o.ID,
+			Owner:         o.Owner,
+			MakerAddress:  o.MakerAddress,
+			MatchedAmount: formatAmount(size),
+			Price:         o.Price,
+			AssetID:       o.AssetID,
+			Side:          o.Side,
+			Outcome:       o.Outcome,
+		}},
+	}
+	o.AssociateTrades = append(o.AssociateTrades, trade.ID)
+	s.trades = append(s.trades, trade)
+	return trade, nil
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) failOrServe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		var f *failure
+		if len(s.failures) > 0 {
+			f = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if f != nil {
+			writeError(w, f.status, f.message)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// nonce is the request's L1 nonce, which defaults to zero
+func nonce(r *http.Request) string {
+	if n := r.Header.Get(marketmaker.HeaderNonce); n != "" {
+		return n
+	}
+	return "0"
+}
+
+// credentials derives a wallet's credentials for a nonce deterministically
+func credentials(address string, nonce string) marketmaker.APICredentials {
+	seed := sha256.Sum256([]byte(strings.ToLower(address) + "/" + nonce))
+	secret := sha256.Sum256(append(seed[:], 's'))
+	pass := sha256.Sum256(append(seed[:], 'p'))
+	id := hex.EncodeToString(seed[:16])
+	return marketmaker.APICredentials{
+		APIKey:     id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32],
+		Secret:     base64.URLEncoding.EncodeToString(secret[:]),
+		Passphrase: hex.EncodeToString(pass[:16]),
+	}
+}
+
+func (s *Server) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	address, err := marketmaker.VerifyL1Headers(r.Header, s.ChainID, s.now())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	key := strings.ToLower(address) + "/" + nonce(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[key]; ok {
+		writeError(w, http.StatusBadRequest, "Could not create api key")
+		return
+	}
+	creds := credentials(address, nonce(r))
+	s.keys[key] = creds
+	s.owners[creds.APIKey] = address
+	s.secrets[creds.APIKey] = creds
+	writeJSON(w, creds)
+}
+
+func (s *Server) deriveAPIKey(w http.ResponseWriter, r *http.Request) {
+	address, err := marketmaker.VerifyL1Headers(r.Header, s.ChainID, s.now())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	creds, ok := s.keys[strings.ToLower(address)+"/"+nonce(r)]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Could not derive api key!")
+		return
+	}
+	writeJSON(w, creds)
+}
+
+// l2 authenticates a request by API key and HMAC before handing it on with its owner
+func (s *Server) l2(next func(w http.ResponseWriter, r *http.Request, owner string, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "failed to read body")
+			return
+		}
+
+		apiKey := r.Header.Get(marketmaker.HeaderAPIKey)
+		s.mu.Lock()
+		address, ok := s.owners[apiKey]
+		creds := s.secrets[apiKey]
+		s.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Unauthorized/Invalid api key")
+			return
+		}
+		if !marketmaker.SameAddress(r.Header.Get(marketmaker.HeaderAddress), address) {
+			writeError(w, http.StatusUnauthorized, "address does not own the api key")
+			return
+		}
+		if err := marketmaker.VerifyL2Headers(r.Header, creds, r.Method, r.URL.Path, body, s.now()); err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		next(w, r, apiKey, body)
+	}
+}
+
+func (s *Server) postOrder(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	var req marketmaker.PostOrderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid order payload")
+		return
+	}
+	if req.Owner != owner {
+		writeError(w, http.StatusBadRequest, "owner does not match api key")
+		return
+	}
+
+	s.mu.Lock()
+	address := s.owners[owner]
+	negRisk := s.negRisk[req.Order.TokenID]
+	s.mu.Unlock()
+
+	order := req.Order
+	price, size, err := orderPriceSize(order)
+	if err == nil && !marketmaker.SameAddress(order.Signer, address) {
+		err = fmt.Errorf("order signer %s is not the api key owner", order.Signer)
+	}
+	if err == nil && order.TokenID == "" {
+		err = fmt.Errorf("missing token id")
+	}
+	if err == nil {
+		err = marketmaker.VerifyOrderSignature(order, s.ChainID, negRisk)
+	}
+	if err != nil {
+		writeJSON(w, marketmaker.PostOrderResponse{ErrorMsg: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("0x%064x", s.nextID)
+	s.orders[id] = &marketmaker.OpenOrder{
+		ID:           id,
+		Status:       "LIVE",
+		Owner:        owner,
+		MakerAddress: order.Maker,
+		Market:       "condition-" + order.TokenID,
+		AssetID:      order.TokenID,
+		Side:         order.Side,
+		OriginalSize: formatAmount(size),
+		SizeMatched:  "0",
+		Price:        formatAmount(price),
+		Outcome:      "Yes",
+		Expiration:   order.Expiration,
+		OrderType:    string(req.OrderType),
+		CreatedAt:    s.now().Unix(),
+	}
+	s.sequence = append(s.sequence, id)
+	writeJSON(w, marketmaker.PostOrderResponse{Success: true, OrderID: id, Status: "live"})
+}
+
+// orderPriceSize recovers price and size from an order's base-unit amounts
+func orderPriceSize(o marketmaker.SignedOrder) (float64, float64, error) {
+	maker, err1 := strconv.ParseFloat(o.MakerAmount, 64)
+	taker, err2 := strconv.ParseFloat(o.TakerAmount, 64)
+	if err1 != nil || err2 != nil || maker <= 0 || taker <= 0 {
+		return 0, 0, fmt.Errorf("invalid amounts %q/%q", o.MakerAmount, o.TakerAmount)
+	}
+	switch o.Side {
+	case "BUY":
+		return maker / taker, taker / 1e6, nil
+	case "SELL":
+		return taker / maker, maker / 1e6, nil
+	}
+	return 0, 0, fmt.Errorf("invalid side %q", o.Side)
+}
+
+func (s *Server) cancelOrder(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	var req struct {
+		OrderID string `json:"orderID"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cancel payload")
+		return
+	}
+	writeJSON(w, s.cancel(owner, []string{req.OrderID}))
+}
+
+func (s *Server) cancelOrders(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	var ids []string
+	if err := json.Unmarshal(body, &ids); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cancel payload")
+		return
+	}
+	writeJSON(w, s.cancel(owner, ids))
+}
+
+func (s *Server) cancelAll(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	s.mu.Lock()
+	var ids []string
+	for _, id := range s.sequence {
+		if o := s.orders[id]; o.Owner == owner && o.Status == "LIVE" {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, s.cancel(owner, ids))
+}
+
+func (s *Server) cancel(owner string, ids []string) marketmaker.CancelResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := marketmaker.CancelResponse{Canceled: []string{}, NotCanceled: map[string]string{}}
+	for _, id := range ids {
+		o, ok := s.orders[id]
+		switch {
+		case !ok || o.Owner != owner:
+			resp.NotCanceled[id] = "order not found"
+		case o.Status != "LIVE":
+			resp.NotCanceled[id] = "order is " + strings.ToLower(o.Status)
+		default:
+			o.Status = "CANCELED"
+			resp.Canceled = append(resp.Canceled, id)
+		}
+	}
+	return resp
+}
+
+func (s *Server) getOrder(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[r.PathValue("id")]
+	if !ok || o.Owner != owner {
+		writeError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	writeJSON(w, o)
+}
+
+func (s *Server) listOrders(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	var orders []marketmaker.OpenOrder
+	for _, id := range s.sequence {
+		o := s.orders[id]
+		if o.Owner != owner || o.Status != "LIVE" ||
+			!matches(q.Get("id"), o.ID) || !matches(q.Get("market"), o.Market) || !matches(q.Get("asset_id"), o.AssetID) {
+			continue
+		}
+		orders = append(orders, *o)
+	}
+	s.mu.Unlock()
+
+	writePage(s, w, q.Get("next_cursor"), orders)
+}
+
+func (s *Server) listTrades(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	q := r.URL.Query()
+	before, _ := strconv.ParseInt(q.Get("before"), 10, 64)
+	after, _ := strconv.ParseInt(q.Get("after"), 10, 64)
+
+	s.mu.Lock()
+	var trades []marketmaker.Trade
+	for _, t := range s.trades {
+		at, _ := strconv.ParseInt(t.MatchTime, 10, 64)
+		if t.Owner != owner ||
+			!matches(q.Get("id"), t.ID) || !matches(q.Get("market"), t.Market) || !matches(q.Get("asset_id"), t.AssetID) ||
+			(q.Get("maker_address") != "" && !marketmaker.SameAddress(q.Get("maker_address"), t.MakerAddress)) ||
+			(before > 0 && at >= before) || (after > 0 && at <= after) {
+			continue
+		}
+		trades = append(trades, t)
+	}
+	s.mu.Unlock()
+
+	writePage(s, w, q.Get("next_cursor"), trades)
+}
+
+func (s *Server) balanceAllowance(w http.ResponseWriter, r *http.Request, owner string, body []byte) {
+	q := r.URL.Query()
+	tokenID := ""
+	switch marketmaker.AssetType(q.Get("asset_type")) {
+	case marketmaker.AssetCollateral:
+	case marketmaker.AssetConditional:
+		if tokenID = q.Get("token_id"); tokenID == "" {
+			writeError(w, http.StatusBadRequest, "token_id is required for conditional balances")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "invalid asset_type")
+		return
+	}
+
+	s.mu.Lock()
+	b := s.balances[balanceKey(s.owners[owner], tokenID)]
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]string{
+		"balance":   strconv.FormatInt(int64(b.Balance*1e6), 10),
+		"allowance": strconv.FormatInt(int64(b.Allowance*1e6), 10),
+	})
+}
+
+// writePage writes one page of a listing, with cursors encoding the offset as the CLOB does
+func writePage[T any](s *Server, w http.ResponseWriter, cursor string, items []T) {
+	offset := 0
+	if cursor != "" {
+		raw, err := base64.StdEncoding.DecodeString(cursor)
+		if err == nil {
+			offset, err = strconv.Atoi(string(raw))
+		}
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "invalid next_cursor")
+			return
+		}
+	}
+
+	size := s.PageSize
+	if size <= 0 {
+		size = 100
+	}
+	end := min(offset+size, len(items))
+	page := []T{}
+	if offset < len(items) {
+		page = items[offset:end]
+	}
+
+	next := "LTE="
+	if end < len(items) {
+		next = base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+	writeJSON(w, map[string]interface{}{"data": page, "next_cursor": next, "limit": size, "count": len(page)})
+}
+
+func matches(filter, value string) bool {
+	return filter == "" || filter == value
+}
+
+func balanceKey(address, tokenID string) string {
+	return strings.ToLower(address) + "/" + tokenID
+}
+
+// formatAmount prints a size or price without trailing zeros
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,213 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+)
+
+// OutcomeEstimate is a raw fair-value estimate for one outcome of an event
+type OutcomeEstimate struct {
+	Question   string
+	FairValue  float64
+	Confidence float64 // 0-1; low-confidence estimates absorb more of any adjustment
+}
+
+// NormalizeOptions controls how an event's estimates are made coherent
+type NormalizeOptions struct {
+	Exhaustive bool    // The listed outcomes cover every possibility, so no "other" bucket
+	OtherShare float64 // Minimum probability reserved for unlisted outcomes (default 0.02)
+	HalfWidth  float64 // Log-odds distance from fair to bid and ask (default 0.4)
+}
+
+// NormalizedOutcome is one outcome's coherent fair value and quote
+type NormalizedOutcome struct {
+	Question string
+	Raw      float64 // Estimate before normalization
+	Fair     float64
+	Bid      float64
+	Ask      float64
+}
+
+// NormalizedEvent is a set of outcome fair values that sum to one together with Other
+type NormalizedEvent struct {
+	Outcomes []NormalizedOutcome
+	Other    float64 // Probability of an outcome not listed
+	RawSum   float64 // Sum of the raw estimates
+	BidSum   float64 // Sum of bids; at most one so buying every outcome from us never locks in a profit, unless one tick per outcome already exceeds it
+}
+
+// defaultOtherShare is the probability reserved for unlisted outcomes of a non-exhaustive event
+const defaultOtherShare = 0.02
+
+// NormalizeEvent turns independent per-outcome estimates for a mutually exclusive event
+// into fair values summing to one (with the residual in Other), then derives bid/ask
+// bands around them. Estimates are rescaled by a common shift in log-odds, so relative
+// odds are preserved and low-confidence estimates move the most.
+func NormalizeEvent(estimates []OutcomeEstimate, opts NormalizeOptions) NormalizedEvent {
+	otherShare := opts.OtherShare
+	if otherShare <= 0 && !opts.Exhaustive {
+		otherShare = defaultOtherShare
+	}
+	if opts.Exhaustive {
+		otherShare = 0
+	}
+	halfWidth := opts.HalfWidth
+	if halfWidth <= 0 {
+		halfWidth = defaultAnchorHalfWidth
+	}
+
+	event := NormalizedEvent{Outcomes: make([]NormalizedOutcome, len(estimates))}
+	for _, e := range estimates {
+		event.RawSum += e.FairValue
+	}
+
+	target := 1 - otherShare
+	shift := 0.0
+	if event.RawSum > target || (opts.Exhaustive && event.RawSum < target) {
+		shift = solveLogitShift(estimates, target)
+	}
+
+	listed := 0.0
+	for i, e := range estimates {
+		fair := shiftedEstimate(e, shift)
+		tick := tickSizeFor(fair)
+		bid := math.Min(1-2*tick, math.Max(tick, floorToTick(logistic(logit(fair)-halfWidth), tick)))
+		event.Outcomes[i] = NormalizedOutcome{
+			Question: e.Question,
+			Raw:      e.FairValue,
+			Fair:     fair,
+			Bid:      bid,
+			Ask:      math.Max(bid+tick, math.Min(1-tick, ceilToTick(logistic(logit(fair)+halfWidth), tick))),
+		}
+		listed += fair
+	}
+	event.Other = math.Max(0, 1-listed)
+
+	// Bids on every outcome must not sum past one, or a seller could lock in a profit
+	// against us by selling the whole event
+	for _, o := range event.Outcomes {
+		event.BidSum += o.Bid
+	}
+	if event.BidSum > 1 {
+		scale := 1 / event.BidSum
+		event.BidSum = 0
+		for i := range event.Outcomes {
+			o := &event.Outcomes[i]
+			// Every outcome keeps a bid of at least one tick; the rounding that costs is taken
+			// back from the largest bids below
+			tick := tickSizeFor(o.Bid)
+			o.Bid = math.Max(tick, floorToTick(o.Bid*scale, tick))
+			event.BidSum += o.Bid
+		}
+		for event.BidSum > 1+probabilityEpsilon && trimLargestBid(event.Outcomes) {
+			event.BidSum = 0
+			for _, o := range event.Outcomes {
+				event.BidSum += o.Bid
+			}
+		}
+	}
+
+	return event
+}
+
+// trimLargestBid lowers the largest bid above one tick by a tick, reporting false when
+// every bid is already at its minimum
+func trimLargestBid(outcomes []NormalizedOutcome) bool {
+	largest := -1
+	for i, o := range outcomes {
+		if o.Bid > tickSizeFor(o.Bid)+probabilityEpsilon && (largest < 0 || o.Bid > outcomes[largest].Bid) {
+			largest = i
+		}
+	}
+	if largest < 0 {
+		return false
+	}
+	bid := outcomes[largest].Bid - tickSizeFor(outcomes[largest].Bid)
+	outcomes[largest].Bid = floorToTick(bid, tickSizeFor(bid))
+	return true
+}
+
+// shiftedEstimate moves an estimate by shift in log-odds, scaled by how little we trust it
+func shiftedEstimate(e OutcomeEstimate, shift float64) float64 {
+	weight := 1 - 0.5*math.Max(0, math.Min(1, e.Confidence))
+	return logistic(logit(e.FairValue) + shift*weight)
+}
+
+// solveLogitShift finds the common log-odds shift that makes the estimates sum to target
+func solveLogitShift(estimates []OutcomeEstimate, target float64) float64 {
+	sum := func(shift float64) float64 {
+		s := 0.0
+		for _, e := range estimates {
+			s += shiftedEstimate(e, shift)
+		}
+		return s
+	}
+	return bisect(-30, 30, func(shift float64) bool { return sum(shift) < target })
+}
+
+// MutuallyExclusive reports whether at most one market in the event can resolve YES.
+// Polymarket marks such events negative-risk.
+func (e *EventContext) MutuallyExclusive() bool {
+	if e == nil || len(e.Markets) < 2 {
+		return false
+	}
+	for _, m := range e.Markets {
+		if !m.NegRisk {
+			return false
+		}
+	}
+	return true
+}
+
+// PriceEvent prices every market of a mutually exclusive event and normalizes the
+// quotes so the fair values are coherent. Quotes are returned in event market order.
+// Posteriors move each estimate before normalizing; toxicity then only widens, which
+// never raises a bid. A partial event cannot be normalized, since its missing markets
+// hold probability too.
+func (ps *PricingStrategy) PriceEvent(event *EventContext, opts NormalizeOptions) ([]PricingQuote, error) {
+	if !event.MutuallyExclusive() {
+		return nil, fmt.Errorf("event %q is not mutually exclusive", event.Title)
+	}
+	if event.Partial {
+		return nil, fmt.Errorf("event %q is partial: only %d of its markets were seen", event.Title, len(event.Markets))
+	}
+
+	inputs := make([]PricingInput, len(event.Markets))
+	quotes := make([]PricingQuote, len(event.Markets))
+	estimates := make([]OutcomeEstimate, len(event.Markets))
+	for i, market := range event.Markets {
+		inputs[i] = PricingInput{
+			Market:   market,
+			Event:    event,
+			Category: ps.CategorizeMarketData(market, event),
+		}
+		quotes[i] = ps.applyPosterior(ps.priceModels(inputs[i]), inputs[i])
+		estimates[i] = OutcomeEstimate{
+			Question:   market.Question,
+			FairValue:  quotes[i].FairValue,
+			Confidence: quotes[i].Confidence,
+		}
+	}
+
+	normalized := NormalizeEvent(estimates, opts)
+	for i, o := range normalized.Outcomes {
+		q := &quotes[i]
+		note := fmt.Sprintf("event-normalized %.1f%% -> %.1f%% (raw sum %.0f%%, other %.1f%%)",
+			q.FairValue*100, o.Fair*100, normalized.RawSum*100, normalized.Other*100)
+		q.Explanation += " | " + note
+		noBid := q.Bid <= 0
+		q.FairValue, q.Bid, q.Ask = o.Fair, o.Bid, o.Ask
+		if noBid {
+			q.Bid = 0
+		}
+		*q = q.withStep("event", event.Title, note, map[string]float64{
+			"raw_fair": o.Raw,
+			"raw_sum":  normalized.RawSum,
+			"bid_sum":  normalized.BidSum,
+			"other":    normalized.Other,
+		})
+		*q = ps.applyToxicity(*q, inputs[i])
+	}
+
+	return quotes, nil
+}
@@ -0,0 +1,88 @@
+package marketmaker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNormalizeEventKeepsEveryBid(t *testing.T) {
+	// A favorite plus a long tail whose scaled bids would floor to zero
+	estimates := []OutcomeEstimate{{Question: "favorite", FairValue: 0.99, Confidence: 1}}
+	for i := range 20 {
+		estimates = append(estimates, OutcomeEstimate{Question: fmt.Sprintf("longshot %d", i), FairValue: 0.0005})
+	}
+	event := NormalizeEvent(estimates, NormalizeOptions{Exhaustive: true, HalfWidth: 0.1})
+
+	sum := 0.0
+	for _, o := range event.Outcomes {
+		if tick := tickSizeFor(o.Bid); o.Bid < tick-probabilityEpsilon {
+			t.Errorf("%s: bid %.4f below one tick", o.Question, o.Bid)
+		}
+		if o.Bid >= o.Ask {
+			t.Errorf("%s: bid %.4f not below ask %.4f", o.Question, o.Bid, o.Ask)
+		}
+		sum += o.Bid
+	}
+	if sum > 1+probabilityEpsilon || event.BidSum > 1+probabilityEpsilon {
+		t.Errorf("bids sum to %.4f (reported %.4f), want at most 1", sum, event.BidSum)
+	}
+}
+
+func TestNormalizeEventKeepsNearCertainAskBelowOne(t *testing.T) {
+	estimates := []OutcomeEstimate{{Question: "favorite", FairValue: 0.9995, Confidence: 1}}
+	event := NormalizeEvent(estimates, NormalizeOptions{Exhaustive: true, HalfWidth: 0.1})
+	o := event.Outcomes[0]
+	if tick := tickSizeFor(o.Fair); o.Bid >= o.Ask || o.Ask > 1-tick+probabilityEpsilon {
+		t.Errorf("quote %.4f/%.4f, want bid below ask below 1", o.Bid, o.Ask)
+	}
+}
+
+// negRiskEvent builds a mutually exclusive event whose markets have YES tokens 1..n
+func negRiskEvent(n int) *EventContext {
+	event := &EventContext{Title: "Who will win?"}
+	for i := 1; i <= n; i++ {
+		event.Markets = append(event.Markets, Market{
+			Question:     fmt.Sprintf("Will candidate %d win?", i),
+			ClobTokenIDs: fmt.Sprintf(`["%d"]`, i),
+			NegRisk:      true,
+		})
+	}
+	return event
+}
+
+func TestPriceEventNormalizesPosteriors(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	event := negRiskEvent(4)
+	ps := &PricingStrategy{Posteriors: NewPosteriorBook(PosteriorConfig{})}
+	if _, err := ps.PriceEvent(event, NormalizeOptions{}); err != nil {
+		t.Fatalf("PriceEvent: %v", err)
+	}
+
+	// Every candidate trades at 60%, which the event as a whole cannot support
+	for i := 1; i <= 4; i++ {
+		for range 20 {
+			ps.Posteriors.Observe(fmt.Sprint(i), Signal{Kind: SignalTrade, Price: 0.6, Size: 100, Time: now})
+		}
+	}
+	quotes, err := ps.PriceEvent(event, NormalizeOptions{})
+	if err != nil {
+		t.Fatalf("PriceEvent: %v", err)
+	}
+	fairSum, bidSum := 0.0, 0.0
+	for _, q := range quotes {
+		fairSum += q.FairValue
+		bidSum += q.Bid
+	}
+	if fairSum > 1+probabilityEpsilon || bidSum > 1+probabilityEpsilon {
+		t.Errorf("fair values sum to %.3f and bids to %.3f, want at most 1", fairSum, bidSum)
+	}
+}
+
+func TestPriceEventRejectsPartialEvent(t *testing.T) {
+	event := negRiskEvent(3)
+	event.Partial = true
+	if _, err := (&PricingStrategy{}).PriceEvent(event, NormalizeOptions{}); err == nil {
+		t.Error("normalized an event missing some of its markets")
+	}
+}
@@ -0,0 +1,211 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deadline is when a market's window closes
+type Deadline struct {
+	Time   time.Time
+	Phrase string // As written in the question, empty when taken from the end date
+	Source string // "question" or "end date"
+	Cutoff bool   // The event can happen any time before Time ("by", "before", "in", or the end date)
+}
+
+// Passed reports whether the deadline is over at now
+func (d Deadline) Passed(now time.Time) bool {
+	return !d.Time.IsZero() && now.After(d.Time)
+}
+
+// months maps month names and abbreviations to months
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+// ordinalSuffix strips "st", "nd", "rd" and "th" from day numbers
+var ordinalSuffix = regexp.MustCompile(`(\d)(?:st|nd|rd|th)\b`)
+
+// ParseDeadline turns a deadline phrase ("December 31, 2025", "June 2026", "2025",
+// "end of March") into the last instant it covers. Phrases without a year refer to
+// the next such date after now.
+func ParseDeadline(phrase string, now time.Time) (time.Time, bool) {
+	s := strings.ToLower(strings.TrimSpace(phrase))
+	s = strings.TrimPrefix(s, "the ")
+	s = strings.TrimPrefix(s, "end of ")
+	s = ordinalSuffix.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer(",", " ", ".", " ").Replace(s)
+
+	var month time.Month
+	var day, year int
+	for _, field := range strings.Fields(s) {
+		if m, ok := months[field]; ok && month == 0 {
+			month = m
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return time.Time{}, false
+		}
+		switch {
+		case n >= 1000 && year == 0:
+			year = n
+		case n >= 1 && n <= 31 && day == 0:
+			day = n
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	if month == 0 && (day != 0 || year == 0) {
+		return time.Time{}, false
+	}
+
+	// The last day of the month is day zero of the next
+	end := func(y int) time.Time {
+		switch {
+		case month == 0:
+			return time.Date(y, time.December, 31, 23, 59, 59, 0, time.UTC)
+		case day == 0:
+			return time.Date(y, month+1, 0, 23, 59, 59, 0, time.UTC)
+		default:
+			return time.Date(y, month, day, 23, 59, 59, 0, time.UTC)
+		}
+	}
+
+	if year != 0 {
+		return end(year), true
+	}
+	t := end(now.Year())
+	if t.Before(now) {
+		t = end(now.Year() + 1)
+	}
+	return t, true
+}
+
+// MarketDeadline finds when a market's window closes, preferring a date stated in the
+// question over the market's end date. A market that ends without its event resolves NO,
+// so the end date is a cutoff.
+func MarketDeadline(market Market, now time.Time) (Deadline, bool) {
+	parsed := ParseQuestion(market.Question)
+	if parsed.Deadline != "" {
+		if t, ok := ParseDeadline(parsed.Deadline, now); ok {
+			return Deadline{Time: t, Phrase: parsed.Deadline, Source: "question", Cutoff: parsed.Cutoff}, true
+		}
+	}
+
+	if t, ok := market.EndTime(); ok {
+		return Deadline{Time: t, Source: "end date", Cutoff: true}, true
+	}
+	return Deadline{}, false
+}
+
+// StartTime parses the market's start date
+func (m Market) StartTime() (time.Time, bool) {
+	return parseMarketDate(m.StartDate)
+}
+
+// defaultDeadlineWindow is the window in days a rule band is assumed to cover when the
+// market's start date is unknown
+const defaultDeadlineWindow = 365
+
+// DeadlineModel prices "will X happen by <date>" markets with a constant hazard rate. The
+// matching rule's band is read as the probability over the market's whole window; as the
+// deadline approaches without the event, only the remaining days' share of that hazard is
+// left, so fair value decays toward zero. Remaining time is counted in whole days, so the
+// quote ratchets down once a day. Past the deadline the last day's band holds until the
+// market resolves, and the strategy withdraws the bid.
+type DeadlineModel struct {
+	Store         *RuleStore // nil uses the rules carried by the input, then DefaultRules
+	DefaultWindow float64    // Days covered by a rule band when the start date is unknown (default 365)
+}
+
+// Name implements PricingModel
+func (m DeadlineModel) Name() string {
+	return "deadline"
+}
+
+// Price implements PricingModel
+func (m DeadlineModel) Price(input PricingInput) (PricingQuote, bool) {
+	if input.Category == CategorySports {
+		return PricingQuote{}, false
+	}
+
+	now := input.now()
+	deadline, ok := MarketDeadline(input.Market, now)
+	if !ok || !deadline.Cutoff {
+		return PricingQuote{}, false
+	}
+
+	rs := input.Rules
+	if m.Store != nil {
+		rs = m.Store.Load()
+	}
+	if rs == nil {
+		rs = DefaultRules.Load()
+	}
+	rule, ok := rs.MatchPriceRule(input.Category, input.Market.Question, input.Market.Tags)
+	if !ok || rule.scheduled() {
+		return PricingQuote{}, false
+	}
+
+	window := m.DefaultWindow
+	if window <= 0 {
+		window = defaultDeadlineWindow
+	}
+	if start, ok := input.Market.StartTime(); ok && start.Before(deadline.Time) {
+		window = deadline.Time.Sub(start).Hours() / 24
+	}
+
+	remaining := math.Max(1, math.Ceil(deadline.Time.Sub(now).Hours()/24))
+	share := math.Min(1, remaining/window)
+
+	// With hazard rate h over window W, P(event) = 1 - exp(-hW); the remaining share s of
+	// the window leaves 1 - exp(-hWs) = 1 - (1-p)^s
+	decay := func(p float64) float64 {
+		return 1 - math.Pow(1-p, share)
+	}
+
+	fair := decay((rule.Bid + rule.Ask) / 2)
+	bidTick, askTick := tickSizeFor(decay(rule.Bid)), tickSizeFor(decay(rule.Ask))
+	bid := math.Max(bidTick, floorToTick(decay(rule.Bid), bidTick))
+	ask := math.Max(bid+askTick, ceilToTick(decay(rule.Ask), askTick))
+
+	base := (rule.Bid + rule.Ask) / 2
+	hazard := -math.Log(1-base) / window
+
+	note := fmt.Sprintf("%.0f of %.0f days left before %s (%s): hazard %.2f%%/day, band decayed to %.1f-%.1f%%",
+		remaining, window, deadline.Time.Format("Jan 2, 2006"), deadline.Source, hazard*100, bid*100, ask*100)
+	quote := PricingQuote{
+		Model:       "deadline/" + rule.Name,
+		FairValue:   base,
+		Bid:         rule.Bid,
+		Ask:         rule.Ask,
+		Confidence:  rule.confidence(),
+		Explanation: rule.Reasoning + " | " + note,
+	}
+	quote = quote.withStep("rule", rule.Name, rule.Reasoning, map[string]float64{"base_rate": base})
+
+	quote.FairValue, quote.Bid, quote.Ask = fair, bid, ask
+	return quote.withStep("deadline", deadline.Source, note, map[string]float64{
+		"days_left":      remaining,
+		"window_days":    window,
+		"hazard_per_day": hazard,
+		"window_share":   share,
+	}), true
+}
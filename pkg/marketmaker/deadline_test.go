@@ -0,0 +1,53 @@
+package marketmaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadlineModelDecaysToEndDateAndStopsBidding(t *testing.T) {
+	now := time.Date(2025, time.October, 19, 12, 0, 0, 0, time.UTC)
+	market := Market{
+		Question:  "Will the Prime Minister resign?",
+		StartDate: "2025-01-01T00:00:00Z",
+		EndDate:   "2025-12-31T00:00:00Z",
+	}
+	ps := &PricingStrategy{}
+	input := PricingInput{Market: market, Category: CategoryPolitics, Now: now}
+
+	quote := ps.PriceMarket(input)
+	if quote.Model != "deadline/rare-political-event" || quote.Ask >= 0.05 || quote.Bid <= 0 {
+		t.Errorf("quote %s %.4f/%.4f, want the 1-5%% band decayed toward the end date", quote.Model, quote.Bid, quote.Ask)
+	}
+
+	input.Now = time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)
+	quote = ps.PriceMarket(input)
+	if quote.Bid != 0 || quote.Ask <= 0 {
+		t.Errorf("quote %.4f/%.4f after the end date, want no bid and an ask", quote.Bid, quote.Ask)
+	}
+}
+
+func TestDeadlineModelLeavesScheduledRulesAlone(t *testing.T) {
+	now := time.Date(2025, time.October, 19, 12, 0, 0, 0, time.UTC)
+	ps := &PricingStrategy{}
+
+	// A meeting in March happens on its date, not at a constant hazard until then
+	fed := ps.PriceMarket(PricingInput{
+		Market:   Market{Question: "Will the Fed raise rates in March?", StartDate: "2025-01-01T00:00:00Z"},
+		Category: CategoryEconomic,
+		Now:      now,
+	})
+	if fed.Model != "rules/fed-increase" || fed.Bid != 0.3 || fed.Ask != 0.5 {
+		t.Errorf("Fed quote %s %.4f/%.4f, want the static 30-50%% band", fed.Model, fed.Bid, fed.Ask)
+	}
+
+	// The recession rule opts back into decay
+	recession := ps.PriceMarket(PricingInput{
+		Market:   Market{Question: "Will the US enter a recession by December 31, 2025?", StartDate: "2025-01-01T00:00:00Z"},
+		Category: CategoryEconomic,
+		Now:      now,
+	})
+	if recession.Model != "deadline/recession" || recession.Ask >= 0.35 {
+		t.Errorf("recession quote %s %.4f/%.4f, want the band decayed", recession.Model, recession.Bid, recession.Ask)
+	}
+}
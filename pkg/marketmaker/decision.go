@@ -0,0 +1,274 @@
+package marketmaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PricingStep is one stage of how a quote was reached: the model's band, then each
+// adjustment applied to it
+type PricingStep struct {
+	Stage     string             `json:"stage"`            // "model", "rule", "deadline", "event", "posterior", "toxicity"...
+	Source    string             `json:"source,omitempty"` // Model or rule name
+	FairValue float64            `json:"fair_value"`
+	Bid       float64            `json:"bid"`
+	Ask       float64            `json:"ask"`
+	Note      string             `json:"note,omitempty"`
+	Values    map[string]float64 `json:"values,omitempty"` // Intermediate values, e.g. base rate or hazard
+}
+
+// withStep records the quote's current values as a new step, without sharing the
+// step history with other copies of the quote
+func (q PricingQuote) withStep(stage, source, note string, values map[string]float64) PricingQuote {
+	steps := make([]PricingStep, len(q.Steps), len(q.Steps)+1)
+	copy(steps, q.Steps)
+	q.Steps = append(steps, PricingStep{
+		Stage:     stage,
+		Source:    source,
+		FairValue: q.FairValue,
+		Bid:       q.Bid,
+		Ask:       q.Ask,
+		Note:      note,
+		Values:    values,
+	})
+	return q
+}
+
+// DecisionInputs is the market data a decision was made from
+type DecisionInputs struct {
+	BestBid             float64         `json:"best_bid,omitempty"`
+	BestAsk             float64         `json:"best_ask,omitempty"`
+	LastTrade           float64         `json:"last_trade,omitempty"`
+	ObservedProbability float64         `json:"observed_probability,omitempty"`
+	ObservedSource      string          `json:"observed_source,omitempty"`
+	Event               string          `json:"event,omitempty"`
+	EventMarkets        int             `json:"event_markets,omitempty"`
+	Deadline            *time.Time      `json:"deadline,omitempty"`
+	TextCategory        MarketCategory  `json:"text_category"`    // Category from the question text alone
+	Labels              []CategoryScore `json:"labels,omitempty"` // Text classifier labels, best first
+	Ambiguous           bool            `json:"ambiguous,omitempty"`
+	Override            string          `json:"override,omitempty"` // Observed data that overrode the text category
+}
+
+// PricingDecision is an auditable record of why a market was quoted as it was
+type PricingDecision struct {
+	Time       time.Time      `json:"time"`
+	Question   string         `json:"question"`
+	TokenID    string         `json:"token_id,omitempty"`
+	Category   MarketCategory `json:"category"`
+	Inputs     DecisionInputs `json:"inputs"`
+	Model      string         `json:"model"`
+	Steps      []PricingStep  `json:"steps"`
+	FairValue  float64        `json:"fair_value"`
+	Bid        float64        `json:"bid"`
+	Ask        float64        `json:"ask"`
+	Confidence float64        `json:"confidence"`
+	BuySize    float64        `json:"buy_size"`  // Dollars
+	SellSize   float64        `json:"sell_size"` // Dollars of collateral
+	SizeNote   string         `json:"size_note,omitempty"`
+	Warnings   []string       `json:"warnings,omitempty"`
+}
+
+// lowConfidence is the confidence below which a decision is flagged
+const lowConfidence = 0.3
+
+// Decide records the inputs, steps and result behind a quote, with the classification
+// that chose input.Category
+func (ps *PricingStrategy) Decide(input PricingInput, class MarketClassification, quote PricingQuote) PricingDecision {
+	d := PricingDecision{
+		Time:       input.now(),
+		Question:   input.Market.Question,
+		Category:   input.Category,
+		Model:      quote.Model,
+		Steps:      quote.Steps,
+		FairValue:  quote.FairValue,
+		Bid:        quote.Bid,
+		Ask:        quote.Ask,
+		Confidence: quote.Confidence,
+	}
+	if ids := input.Market.TokenIDs(); len(ids) > 0 {
+		d.TokenID = ids[0]
+	}
+
+	if input.Book != nil {
+		if len(input.Book.Bids) > 0 {
+			d.Inputs.BestBid, _ = parseFloat(input.Book.Bids[0].Price)
+		}
+		if len(input.Book.Asks) > 0 {
+			d.Inputs.BestAsk, _ = parseFloat(input.Book.Asks[0].Price)
+		}
+	}
+	d.Inputs.LastTrade = input.Market.LastTradePrice
+	if obs, ok := ObserveProbability(input.Market, input.Event); ok {
+		d.Inputs.ObservedProbability = obs.Probability
+		d.Inputs.ObservedSource = obs.Source
+	}
+	if input.Event != nil {
+		d.Inputs.Event = input.Event.Title
+		d.Inputs.EventMarkets = len(input.Event.Markets)
+	}
+	if deadline, ok := MarketDeadline(input.Market, d.Time); ok {
+		t := deadline.Time
+		d.Inputs.Deadline = &t
+	}
+
+	d.Inputs.TextCategory = class.Text.Category
+	d.Inputs.Labels = class.Text.Labels
+	d.Inputs.Ambiguous = class.Text.Ambiguous
+	if class.Observed != nil {
+		d.Inputs.Override = fmt.Sprintf("%s %.1f%%", class.Observed.Source, class.Observed.Probability*100)
+	}
+
+	d.check()
+	return d
+}
+
+// SetSize records the position size chosen for the quote
+func (d *PricingDecision) SetSize(buy, sell float64, note string) {
+	d.BuySize, d.SellSize, d.SizeNote = buy, sell, note
+	d.check()
+}
+
+// check recomputes the warnings from the decision's current state
+func (d *PricingDecision) check() {
+	d.Warnings = nil
+	warn := func(format string, args ...interface{}) {
+		d.Warnings = append(d.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if d.Model == "fallback" {
+		warn("no model matched; quoting the fallback band")
+	}
+	if d.Confidence < lowConfidence {
+		warn("low confidence (%.0f%%)", d.Confidence*100)
+	}
+	if d.Inputs.Ambiguous {
+		warn("category is ambiguous (%s)", formatLabels(d.Inputs.Labels))
+	}
+	// A placeholder book (0.001/0.999) cannot be crossed by a sane quote
+	if d.Inputs.BestAsk > 0 && d.Bid >= d.Inputs.BestAsk {
+		warn("bid %.4f crosses best ask %.4f and would fill immediately", d.Bid, d.Inputs.BestAsk)
+	}
+	if d.Inputs.BestBid > 0 && d.Ask <= d.Inputs.BestBid {
+		warn("ask %.4f crosses best bid %.4f and would fill immediately", d.Ask, d.Inputs.BestBid)
+	}
+	if d.Inputs.ObservedProbability > 0 && d.Inputs.ObservedSource == "last trade" &&
+		(d.Inputs.ObservedProbability < d.Bid/2 || d.Inputs.ObservedProbability > 1-(1-d.Ask)/2) {
+		warn("last trade %.1f%% is far outside the quote", d.Inputs.ObservedProbability*100)
+	}
+	if d.Inputs.Deadline != nil && !d.Inputs.Deadline.After(d.Time) {
+		warn("deadline %s has passed; market may be awaiting resolution", d.Inputs.Deadline.Format("Jan 2, 2006"))
+	}
+	if d.SizeNote != "" && d.BuySize <= 0 && d.SellSize <= 0 {
+		warn("no size on either side")
+	}
+}
+
+// JSON serializes the decision
+func (d PricingDecision) JSON() ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pricing decision: %w", err)
+	}
+	return data, nil
+}
+
+// WriteDecisions writes decisions as JSON lines sorted by question, so two runs can be
+// compared with diff
+func WriteDecisions(path string, decisions []PricingDecision) error {
+	sorted := append([]PricingDecision(nil), decisions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Question < sorted[j].Question })
+
+	var buf bytes.Buffer
+	for _, d := range sorted {
+		data, err := d.JSON()
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// String renders the decision as indented text
+func (d PricingDecision) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n", d.Question)
+	fmt.Fprintf(&b, "   Category: %s", d.Category)
+	if d.Inputs.Override != "" {
+		fmt.Fprintf(&b, " from %s, over text category %s", d.Inputs.Override, d.Inputs.TextCategory)
+	}
+	if len(d.Inputs.Labels) > 0 {
+		fmt.Fprintf(&b, " (%s)", formatLabels(d.Inputs.Labels))
+	}
+	b.WriteString("\n")
+
+	var inputs []string
+	if d.Inputs.BestBid > 0 || d.Inputs.BestAsk > 0 {
+		inputs = append(inputs, fmt.Sprintf("book %.4f/%.4f", d.Inputs.BestBid, d.Inputs.BestAsk))
+	}
+	if d.Inputs.ObservedSource != "" {
+		inputs = append(inputs, fmt.Sprintf("observed %.1f%% (%s)", d.Inputs.ObservedProbability*100, d.Inputs.ObservedSource))
+	}
+	if d.Inputs.Event != "" {
+		inputs = append(inputs, fmt.Sprintf("event %q (%d markets)", d.Inputs.Event, d.Inputs.EventMarkets))
+	}
+	if d.Inputs.Deadline != nil {
+		inputs = append(inputs, "deadline "+d.Inputs.Deadline.Format("Jan 2, 2006"))
+	}
+	if len(inputs) > 0 {
+		fmt.Fprintf(&b, "   Inputs: %s\n", strings.Join(inputs, "; "))
+	}
+
+	for i, step := range d.Steps {
+		fmt.Fprintf(&b, "   %d. %-9s %-28s fair %5.1f%% | bid %.4f | ask %.4f",
+			i+1, step.Stage, step.Source, step.FairValue*100, step.Bid, step.Ask)
+		if len(step.Values) > 0 {
+			b.WriteString(" [" + formatValues(step.Values) + "]")
+		}
+		b.WriteString("\n")
+		if step.Note != "" {
+			fmt.Fprintf(&b, "      %s\n", step.Note)
+		}
+	}
+
+	fmt.Fprintf(&b, "   Final: fair %.1f%% | bid %.4f | ask %.4f | confidence %.0f%% (%s)\n",
+		d.FairValue*100, d.Bid, d.Ask, d.Confidence*100, d.Model)
+	if d.SizeNote != "" {
+		fmt.Fprintf(&b, "   Size: $%.0f buy | $%.0f sell - %s\n", d.BuySize, d.SellSize, d.SizeNote)
+	}
+	for _, w := range d.Warnings {
+		fmt.Fprintf(&b, "   [WARN] %s\n", w)
+	}
+	return b.String()
+}
+
+// formatLabels renders classifier labels as "sports 0.85, politics 0.30"
+func formatLabels(labels []CategoryScore) string {
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = fmt.Sprintf("%s %.2f", label.Category, label.Confidence)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatValues renders intermediate values in a stable order
+func formatValues(values map[string]float64) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%.4g", k, values[k])
+	}
+	return strings.Join(parts, " ")
+}
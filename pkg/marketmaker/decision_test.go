@@ -0,0 +1,123 @@
+package marketmaker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecisionWarnings(t *testing.T) {
+	now := time.Date(2025, time.October, 19, 12, 0, 0, 0, time.UTC)
+	passed := now.Add(-24 * time.Hour)
+	base := PricingDecision{Time: now, Model: "rules/test", Bid: 0.40, Ask: 0.50, Confidence: 0.8}
+
+	tests := []struct {
+		name   string
+		modify func(d *PricingDecision)
+		want   string
+	}{
+		{"none", func(d *PricingDecision) {}, ""},
+		{"fallback", func(d *PricingDecision) { d.Model = "fallback" }, "no model matched"},
+		{"low confidence", func(d *PricingDecision) { d.Confidence = 0.2 }, "low confidence (20%)"},
+		{"ambiguous", func(d *PricingDecision) {
+			d.Inputs.Ambiguous = true
+			d.Inputs.Labels = []CategoryScore{{Category: CategorySports, Confidence: 0.45}, {Category: CategoryPolitics, Confidence: 0.40}}
+		}, "category is ambiguous (sports 0.45, politics 0.40)"},
+		{"bid crosses", func(d *PricingDecision) { d.Inputs.BestAsk = 0.38 }, "bid 0.4000 crosses best ask 0.3800"},
+		{"ask crosses", func(d *PricingDecision) { d.Inputs.BestBid = 0.52 }, "ask 0.5000 crosses best bid 0.5200"},
+		{"far last trade", func(d *PricingDecision) {
+			d.Inputs.ObservedProbability, d.Inputs.ObservedSource = 0.10, "last trade"
+		}, "last trade 10.0% is far outside the quote"},
+		{"far outcome prices", func(d *PricingDecision) {
+			d.Inputs.ObservedProbability, d.Inputs.ObservedSource = 0.10, "outcome prices"
+		}, ""},
+		{"deadline passed", func(d *PricingDecision) { d.Inputs.Deadline = &passed }, "deadline Oct 18, 2025 has passed"},
+		{"no size", func(d *PricingDecision) { d.SizeNote = "over limit" }, "no size on either side"},
+	}
+	for _, tt := range tests {
+		d := base
+		tt.modify(&d)
+		d.check()
+		if tt.want == "" {
+			if len(d.Warnings) != 0 {
+				t.Errorf("%s: warnings %q, want none", tt.name, d.Warnings)
+			}
+			continue
+		}
+		if len(d.Warnings) != 1 || !strings.Contains(d.Warnings[0], tt.want) {
+			t.Errorf("%s: warnings %q, want one containing %q", tt.name, d.Warnings, tt.want)
+		}
+	}
+
+	// Sizing recomputes the warnings rather than appending to them
+	d := base
+	d.SetSize(0, 0, "over limit")
+	d.SetSize(100, 0, "kelly")
+	if len(d.Warnings) != 0 {
+		t.Errorf("warnings %q after sizing, want none", d.Warnings)
+	}
+}
+
+func TestDecideRecordsClassification(t *testing.T) {
+	now := time.Date(2025, time.October, 19, 12, 0, 0, 0, time.UTC)
+	market := Market{Question: "Will the Kansas City Chiefs win Super Bowl 2026?", LastTradePrice: 0.02}
+	ps := &PricingStrategy{}
+	class := ps.ClassifyMarketData(market, nil)
+	if class.Category != CategoryLongshot || class.Observed == nil {
+		t.Fatalf("classified %s (observed %v), want a longshot override", class.Category, class.Observed)
+	}
+
+	input := PricingInput{
+		Market:   market,
+		Category: class.Category,
+		Now:      now,
+		Book:     &OrderBookResponse{Bids: []Order{{Price: "0.015"}}, Asks: []Order{{Price: "0.03"}}},
+	}
+	quote := PricingQuote{Model: "longshot", FairValue: 0.02, Bid: 0.01, Ask: 0.03, Confidence: 0.6}
+	quote = quote.withStep("model", "longshot", "priced off the last trade", map[string]float64{"fair": 0.02})
+	d := ps.Decide(input, class, quote)
+
+	if d.Category != CategoryLongshot || d.Inputs.TextCategory != CategorySports {
+		t.Errorf("category %s over text %s, want longshot over sports", d.Category, d.Inputs.TextCategory)
+	}
+	if d.Inputs.Override != "last trade 2.0%" {
+		t.Errorf("override %q, want the last trade", d.Inputs.Override)
+	}
+	if len(d.Inputs.Labels) == 0 || d.Inputs.Labels[0].Category != CategorySports {
+		t.Fatalf("labels %v, want sports first", d.Inputs.Labels)
+	}
+
+	out := d.String()
+	for _, want := range []string{
+		"Category: longshot from last trade 2.0%, over text category sports (sports ",
+		"Inputs: book 0.0150/0.0300; observed 2.0% (last trade)",
+		"1. model     longshot",
+		"[fair=0.02]",
+		"Final: fair 2.0% | bid 0.0100 | ask 0.0300 | confidence 60% (longshot)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("String() missing %q:\n%s", want, out)
+		}
+	}
+
+	// Labels serialize as objects, not preformatted strings
+	data, err := d.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Inputs struct {
+			Labels []struct {
+				Category   string  `json:"category"`
+				Confidence float64 `json:"confidence"`
+			} `json:"labels"`
+		} `json:"inputs"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("labels do not decode as objects: %v\n%s", err, data)
+	}
+	if len(decoded.Inputs.Labels) == 0 || decoded.Inputs.Labels[0].Category != "sports" || decoded.Inputs.Labels[0].Confidence <= 0 {
+		t.Errorf("decoded labels %+v, want sports with its confidence", decoded.Inputs.Labels)
+	}
+}
@@ -0,0 +1,433 @@
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// BookSource fetches current orderbooks; MarketMaker reads them from the CLOB
+type BookSource interface {
+	GetOrderBook(tokenID string) (*OrderBookResponse, error)
+}
+
+// QuoteContext is what a quote model sees for one token
+type QuoteContext struct {
+	Opportunity Opportunity        // With Book replaced by the latest book
+	Book        *OrderBookResponse // Latest book, nil if none could be fetched
+	Inventory   float64            // Shares held
+	Now         time.Time
+}
+
+// QuoteModel decides where a token should be quoted. A bid of 0 means no bid.
+type QuoteModel interface {
+	TargetQuote(qc QuoteContext) (bid, ask float64, err error)
+}
+
+// QuoteModelFunc adapts a function to QuoteModel
+type QuoteModelFunc func(qc QuoteContext) (float64, float64, error)
+
+// TargetQuote implements QuoteModel
+func (f QuoteModelFunc) TargetQuote(qc QuoteContext) (float64, float64, error) {
+	return f(qc)
+}
+
+// QuoterModel quotes around the book mid with a Quoter, skewing for inventory. It suits
+// active markets, where the mid is a real price.
+type QuoterModel struct {
+	Quoter     Quoter
+	Volatility float64 // Per square-root day; InventoryQuoter only skews when it is set
+}
+
+// TargetQuote implements QuoteModel
+func (m QuoterModel) TargetQuote(qc QuoteContext) (float64, float64, error) {
+	bid, ask, ok := bestPrices(qc.Book)
+	if !ok {
+		return 0, 0, errors.New("no two-sided book to take a mid from")
+	}
+
+	input := QuoteInput{
+		Mid:              (bid + ask) / 2,
+		Inventory:        qc.Inventory,
+		Volatility:       m.Volatility,
+		TickSize:         qc.Opportunity.OrderOptions().TickSize,
+		TimeToResolution: qc.Opportunity.Market.TimeToResolution(qc.Now),
+	}
+	quote := m.Quoter.Quote(input)
+	return quote.Bid, quote.Ask, nil
+}
+
+// StrategyModel quotes the pricing strategy's band, ignoring the book. It suits dust
+// markets, where the book is a placeholder.
+type StrategyModel struct {
+	Strategy *PricingStrategy
+}
+
+// TargetQuote implements QuoteModel
+func (m StrategyModel) TargetQuote(qc QuoteContext) (float64, float64, error) {
+	opp := qc.Opportunity
+	quote := m.Strategy.PriceMarket(PricingInput{
+		Market:   opp.Market,
+		Event:    opp.Event,
+		Category: m.Strategy.CategorizeMarketData(opp.Market, opp.Event),
+		Book:     qc.Book,
+		Now:      qc.Now,
+	})
+	return quote.Bid, quote.Ask, nil
+}
+
+// QuotingEngine keeps a bid and an ask working on each opportunity's token. Each step it
+// prices every token, places missing orders, replaces orders whose price has drifted from
+// the target by RequoteThreshold, and pulls a side whose inventory limit is reached. All
+// order state goes through Orders, so the engine runs the same against the live CLOB or a
+// simulated exchange.
+type QuotingEngine struct {
+	Orders             *OrderManager
+	Model              QuoteModel
+	Books              BookSource                   // nil quotes against each opportunity's scanned book
+	Size               float64                      // Shares per side
+	MaxInventory       float64                      // Stop bidding once this many shares are held (0 for no limit)
+	MinInventory       float64                      // Stop asking at this many shares; 0 on the CLOB, which only sells shares you hold
+	RequoteThreshold   float64                      // Price drift that triggers a requote (default one tick)
+	MinRequoteInterval time.Duration                // Minimum time between requotes of one side (default 5s)
+	Inventory          func(tokenID string) float64 // Shares held; nil counts fills seen by Orders
+	Breaker            *CircuitBreaker              // Checked every step; paused tokens and halts pull quotes (nil never pauses)
+	Toxicity           *ToxicityTracker             // Fed every fill and real book mid; share it with the strategy to widen (nil feeds nothing)
+	Posteriors         *PosteriorBook               // Fed every fill and real book mid as signals; share it with the strategy (nil feeds nothing)
+	Logf               func(format string, args ...interface{})
+	Now                func() time.Time // nil uses time.Now
+
+	mu        sync.Mutex
+	opps      map[string]Opportunity
+	lastQuote map[string]time.Time // "token/side" to last placement
+}
+
+// NewQuotingEngine creates an engine that quotes size shares per side through orders
+func NewQuotingEngine(orders *OrderManager, model QuoteModel, size float64) *QuotingEngine {
+	return &QuotingEngine{
+		Orders:    orders,
+		Model:     model,
+		Size:      size,
+		opps:      make(map[string]Opportunity),
+		lastQuote: make(map[string]time.Time),
+	}
+}
+
+// SetOpportunities replaces the set of tokens to quote. Orders on tokens no longer in the
+// set are cancelled on the next step.
+func (e *QuotingEngine) SetOpportunities(opps []Opportunity) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.opps = make(map[string]Opportunity, len(opps))
+	for _, opp := range opps {
+		e.opps[opp.TokenID] = opp
+	}
+}
+
+// Step reconciles orders and brings every token's quotes up to date. With a breaker set, a
+// halt pulls every quote and a paused token's quotes are pulled until it resumes.
+func (e *QuotingEngine) Step(ctx context.Context) error {
+	var errs []error
+	if err := e.Orders.Reconcile(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if e.Breaker != nil {
+		// Check cancels every order while halted
+		if err := e.Breaker.Check(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if _, halted := e.Breaker.Halted(); halted {
+			return errors.Join(errs...)
+		}
+	}
+
+	e.mu.Lock()
+	opps := make([]Opportunity, 0, len(e.opps))
+	for _, opp := range e.opps {
+		opps = append(opps, opp)
+	}
+	quoted := func(tokenID string) bool { _, ok := e.opps[tokenID]; return ok }
+	var stale []ManagedOrder
+	for _, o := range e.Orders.Open("") {
+		if !quoted(o.TokenID) && o.ExchangeID != "" {
+			stale = append(stale, o)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, o := range stale {
+		e.logf("cancel %s %s %.2f @ %.4f on dropped token %s", o.ClientID, o.Side, o.Remaining(), o.Price, o.TokenID)
+		if err := e.Orders.Cancel(ctx, o.ClientID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, opp := range opps {
+		if e.Breaker != nil {
+			if _, paused := e.Breaker.Paused(opp.TokenID); paused {
+				if err := e.Orders.CancelToken(ctx, opp.TokenID); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", opp.Question, err))
+				}
+				continue
+			}
+		}
+		if err := e.quoteToken(ctx, opp); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", opp.Question, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run steps every interval until ctx is cancelled, reporting failures to onError. It leaves
+// orders working when it returns; call Stop to pull them.
+func (e *QuotingEngine) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Step(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop cancels every open order on the engine's tokens
+func (e *QuotingEngine) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	var tokens []string
+	for tokenID := range e.opps {
+		tokens = append(tokens, tokenID)
+	}
+	e.mu.Unlock()
+
+	var errs []error
+	for _, tokenID := range tokens {
+		if err := e.Orders.CancelToken(ctx, tokenID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// quoteToken prices one token and syncs both sides
+func (e *QuotingEngine) quoteToken(ctx context.Context, opp Opportunity) error {
+	if e.Books != nil {
+		book, err := e.Books.GetOrderBook(opp.TokenID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch book: %w", err)
+		}
+		opp.Book = book
+	}
+	opts := opp.OrderOptions()
+	inventory := e.inventory(opp.TokenID)
+	now := e.now()
+	if mid, ok := realMid(opp.Book); ok {
+		e.observe(opp.TokenID, mid, now)
+	}
+
+	bid, ask, err := e.Model.TargetQuote(QuoteContext{Opportunity: opp, Book: opp.Book, Inventory: inventory, Now: now})
+	if err != nil {
+		return fmt.Errorf("failed to price: %w", err)
+	}
+	noBid := bid <= 0
+	bid, ask = e.snap(bid, ask, opts.TickSize, opp.Book)
+	if bid >= ask {
+		return fmt.Errorf("target bid %.4f is not below ask %.4f", bid, ask)
+	}
+
+	// One-sided at the limits: no bid once long enough, no ask with nothing left to sell
+	bidSize, askSize := e.Size, math.Min(e.Size, inventory-e.MinInventory)
+	if noBid {
+		bidSize = 0
+	}
+	if e.MaxInventory > 0 {
+		bidSize = math.Min(bidSize, e.MaxInventory-inventory)
+	}
+	minSize := math.Max(opts.MinSize, 0.01)
+
+	return errors.Join(
+		e.syncSide(ctx, opp, SideBuy, bid, bidSize, minSize, opts),
+		e.syncSide(ctx, opp, SideSell, ask, askSize, minSize, opts),
+	)
+}
+
+// syncSide makes the resting orders on one side match the target price and size
+func (e *QuotingEngine) syncSide(ctx context.Context, opp Opportunity, side Side, price, size, minSize float64, opts OrderOptions) error {
+	var existing []ManagedOrder
+	for _, o := range e.Orders.Open(opp.TokenID) {
+		if o.Side == side {
+			existing = append(existing, o)
+		}
+	}
+	want := size >= minSize
+	key := opp.TokenID + "/" + side.String()
+
+	var errs []error
+	for i, o := range existing {
+		// Keep the first order if the side is wanted; an order not yet acknowledged cannot be
+		// cancelled and is left to reconciliation
+		if (want && i == 0) || o.ExchangeID == "" {
+			continue
+		}
+		e.logf("cancel %s %s %.2f @ %.4f on %s", o.ClientID, side, o.Remaining(), o.Price, opp.Question)
+		if err := e.Orders.Cancel(ctx, o.ClientID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !want {
+		return errors.Join(errs...)
+	}
+
+	if len(existing) == 0 {
+		// A side just filled or cancelled waits out the interval like any requote
+		if !e.canRequote(key) {
+			return errors.Join(errs...)
+		}
+		e.logf("place %s %.2f @ %.4f on %s", side, size, price, opp.Question)
+		if _, err := e.Orders.Place(ctx, OrderRequest{TokenID: opp.TokenID, Side: side, Price: price, Size: size, Options: opts}); err != nil {
+			errs = append(errs, err)
+		}
+		e.markQuoted(key)
+		return errors.Join(errs...)
+	}
+
+	o := existing[0]
+	drifted := math.Abs(o.Price-price) >= e.requoteThreshold(opts.TickSize)-1e-9
+	// An order larger than the inventory limit now allows must shrink at once
+	oversized := o.Remaining() > size+1e-9
+	if o.ExchangeID == "" || (!drifted && !oversized) || (!oversized && !e.canRequote(key)) {
+		return errors.Join(errs...)
+	}
+
+	e.logf("requote %s %s %.4f -> %.4f (%.2f shares) on %s", o.ClientID, side, o.Price, price, size, opp.Question)
+	if _, err := e.Orders.Replace(ctx, o.ClientID, price, size, opts); err != nil {
+		errs = append(errs, err)
+	}
+	e.markQuoted(key)
+	return errors.Join(errs...)
+}
+
+// RecordFill feeds one of our fills to Toxicity and Posteriors. Call it from
+// OrderManager.OnFill with the token's category.
+func (e *QuotingEngine) RecordFill(o ManagedOrder, f OrderFill, category MarketCategory) {
+	fill := Fill{TokenID: o.TokenID, Category: category, Side: o.Side, Price: f.Price, Size: f.Size, Time: f.Time}
+	if e.Toxicity != nil {
+		e.Toxicity.RecordFill(fill)
+	}
+	if e.Posteriors != nil {
+		e.Posteriors.ObserveFill(fill)
+	}
+}
+
+// observe feeds a token's book mid to Toxicity, resolving markouts, and to Posteriors
+func (e *QuotingEngine) observe(tokenID string, mid float64, now time.Time) {
+	if e.Toxicity != nil {
+		e.Toxicity.Observe(tokenID, mid, now)
+	}
+	if e.Posteriors != nil {
+		e.Posteriors.Observe(tokenID, Signal{Kind: SignalBook, Price: mid, Time: now})
+	}
+}
+
+// snap rounds the target outward to the tick grid and keeps each side from crossing the book
+func (e *QuotingEngine) snap(bid, ask, tick float64, book *OrderBookResponse) (float64, float64) {
+	bid = math.Max(tick, floorToTick(bid, tick))
+	ask = math.Min(1-tick, ceilToTick(ask, tick))
+
+	if bestBid, bestAsk, ok := bestPrices(book); ok {
+		if bid >= bestAsk {
+			bid = cleanPrice(bestAsk - tick)
+		}
+		if ask <= bestBid {
+			ask = cleanPrice(bestBid + tick)
+		}
+	}
+	return bid, ask
+}
+
+// inventory returns shares held in a token
+func (e *QuotingEngine) inventory(tokenID string) float64 {
+	if e.Inventory != nil {
+		return e.Inventory(tokenID)
+	}
+
+	held := 0.0
+	for _, o := range e.Orders.Orders(func(o ManagedOrder) bool { return o.TokenID == tokenID }) {
+		held += o.Side.sign() * o.Filled
+	}
+	return held
+}
+
+func (e *QuotingEngine) canRequote(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.now().Sub(e.lastQuote[key]) >= e.minRequoteInterval()
+}
+
+func (e *QuotingEngine) markQuoted(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastQuote[key] = e.now()
+}
+
+func (e *QuotingEngine) requoteThreshold(tick float64) float64 {
+	if e.RequoteThreshold > 0 {
+		return e.RequoteThreshold
+	}
+	return tick
+}
+
+func (e *QuotingEngine) minRequoteInterval() time.Duration {
+	if e.MinRequoteInterval > 0 {
+		return e.MinRequoteInterval
+	}
+	return 5 * time.Second
+}
+
+func (e *QuotingEngine) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+func (e *QuotingEngine) logf(format string, args ...interface{}) {
+	if e.Logf != nil {
+		e.Logf(format, args...)
+	}
+}
+
+// bestPrices returns the top of a book
+func bestPrices(book *OrderBookResponse) (bid, ask float64, ok bool) {
+	if book == nil || len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return 0, 0, false
+	}
+	bid, err1 := parseFloat(book.Bids[0].Price)
+	ask, err2 := parseFloat(book.Asks[0].Price)
+	return bid, ask, err1 == nil && err2 == nil
+}
+
+// isPlaceholder reports whether a top of book is a placeholder, such as the CLOB's
+// 0.001/0.999 default, rather than a real market
+func isPlaceholder(bid, ask float64) bool {
+	return bid <= 0.01 && ask >= 0.99
+}
+
+// realMid returns the mid of a book, unless the book is missing, one-sided or a placeholder
+// whose mid of 0.5 says nothing about the price
+func realMid(book *OrderBookResponse) (float64, bool) {
+	bid, ask, ok := bestPrices(book)
+	if !ok || isPlaceholder(bid, ask) {
+		return 0, false
+	}
+	return (bid + ask) / 2, true
+}
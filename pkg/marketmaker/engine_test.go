@@ -0,0 +1,138 @@
+package marketmaker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQuotingEngineWaitsBeforeReplacingCancelledSide(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	now := func() time.Time { return clock }
+
+	m := newTestOrderManager(t, NewPaperExchange(100))
+	m.Now = now
+	model := QuoteModelFunc(func(QuoteContext) (float64, float64, error) { return 0.2, 0.3, nil })
+	e := NewQuotingEngine(m, model, 10)
+	e.Now = now
+	e.MinRequoteInterval = time.Minute
+	e.SetOpportunities([]Opportunity{{Question: "Will it happen?", TokenID: testTokenID}})
+
+	ctx := context.Background()
+	bids := func() int {
+		n := 0
+		for _, o := range m.Open(testTokenID) {
+			if o.Side == SideBuy {
+				n++
+			}
+		}
+		return n
+	}
+	if err := e.Step(ctx); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if bids() != 1 {
+		t.Fatalf("%d bids after the first step, want 1", bids())
+	}
+
+	if err := m.CancelToken(ctx, testTokenID); err != nil {
+		t.Fatalf("CancelToken: %v", err)
+	}
+	clock = clock.Add(10 * time.Second)
+	if err := e.Step(ctx); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if bids() != 0 {
+		t.Errorf("bid replaced %s after the last placement, within the requote interval", 10*time.Second)
+	}
+
+	clock = clock.Add(time.Minute)
+	if err := e.Step(ctx); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if bids() != 1 {
+		t.Errorf("%d bids once the interval passed, want 1", bids())
+	}
+}
+
+func TestQuotingEnginePullsBidWhenModelWillNotBid(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	now := func() time.Time { return clock }
+
+	m := newTestOrderManager(t, NewPaperExchange(100))
+	m.Now = now
+	bid := 0.2
+	model := QuoteModelFunc(func(QuoteContext) (float64, float64, error) { return bid, 0.3, nil })
+	e := NewQuotingEngine(m, model, 10)
+	e.Now = now
+	e.SetOpportunities([]Opportunity{{Question: "Will it happen?", TokenID: testTokenID}})
+
+	ctx := context.Background()
+	if err := e.Step(ctx); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if open := m.Open(testTokenID); len(open) != 1 {
+		t.Fatalf("%d orders after the first step, want the bid", len(open))
+	}
+
+	bid = 0
+	clock = clock.Add(time.Minute)
+	if err := e.Step(ctx); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if open := m.Open(testTokenID); len(open) != 0 {
+		t.Errorf("orders %+v still open, want the bid pulled", open)
+	}
+}
+
+// staticBooks serves fixed books by token
+type staticBooks map[string]*OrderBookResponse
+
+func (b staticBooks) GetOrderBook(tokenID string) (*OrderBookResponse, error) {
+	return b[tokenID], nil
+}
+
+func TestQuotingEngineFeedsFillsAndMids(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	now := func() time.Time { return clock }
+	const placeholder = "5678"
+	books := staticBooks{
+		testTokenID: {Bids: []Order{{Price: "0.40", Size: "100"}}, Asks: []Order{{Price: "0.44", Size: "100"}}},
+		placeholder: {Bids: []Order{{Price: "0.001", Size: "100"}}, Asks: []Order{{Price: "0.999", Size: "100"}}},
+	}
+
+	m := newTestOrderManager(t, NewPaperExchange(100))
+	m.Now = now
+	model := QuoteModelFunc(func(QuoteContext) (float64, float64, error) { return 0.3, 0.5, nil })
+	e := NewQuotingEngine(m, model, 10)
+	e.Now, e.Books = now, books
+	e.Toxicity = NewToxicityTracker()
+	e.Toxicity.Horizons = []time.Duration{time.Minute}
+	e.Posteriors = NewPosteriorBook(PosteriorConfig{})
+	e.SetOpportunities([]Opportunity{{TokenID: testTokenID}, {TokenID: placeholder}})
+	for tokenID := range books {
+		e.Posteriors.Apply(tokenID, PricingQuote{FairValue: 0.2, Bid: 0.1, Ask: 0.3}, clock)
+	}
+
+	for tokenID := range books {
+		e.RecordFill(ManagedOrder{TokenID: tokenID, Side: SideBuy}, OrderFill{Price: 0.3, Size: 10, Time: clock}, CategorySports)
+	}
+	clock = clock.Add(2 * time.Minute)
+	if err := e.Step(context.Background()); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+
+	if stats := e.Toxicity.Stats(testTokenID)[time.Minute]; stats.Count != 1 {
+		t.Errorf("markout stats %+v, want the fill resolved against the mid", stats)
+	}
+	if est, _ := e.Posteriors.Get(testTokenID); est.Observations != 2 {
+		t.Errorf("posterior saw %d signals, want the fill and the mid", est.Observations)
+	}
+	// A placeholder's 0.5 mid is no price: the fill's markout waits and the posterior ignores it
+	if e.Toxicity.Pending() != 1 {
+		t.Errorf("%d markouts pending, want the placeholder token's", e.Toxicity.Pending())
+	}
+	if est, _ := e.Posteriors.Get(placeholder); est.Observations != 1 {
+		t.Errorf("placeholder posterior saw %d signals, want only the fill", est.Observations)
+	}
+}
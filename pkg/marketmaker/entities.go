@@ -0,0 +1,404 @@
+package marketmaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParsedQuestion is the structure extracted from a market question
+type ParsedQuestion struct {
+	Subject  string // Who or what the question is about ("Browns")
+	Event    string // What they have to achieve or what happens ("Super Bowl 2026")
+	Deadline string // Deadline phrase, if any ("December 31, 2025")
+	Cutoff   bool   // The deadline ends a window ("by", "before", "in") rather than dating the event
+}
+
+// questionPatterns extract subject and event from common question shapes, most specific first
+var questionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^will (?:the )?(.+?) win (?:the )?(.+?)$`),
+	regexp.MustCompile(`(?i)^will (?:the )?(.+?) (?:be|become) (?:the )?(.+?)$`),
+	regexp.MustCompile(`(?i)^will (?:the )?(.+?) (?:reach|hit|exceed|announce|release|sign|leave|resign|visit|meet) (.+?)$`),
+	regexp.MustCompile(`(?i)^(?:the )?(.+?) (?:to win|wins) (?:the )?(.+?)$`),
+	regexp.MustCompile(`(?i)^(?:the )?(.+?) (increases?|cuts?|hikes?|raises?) (.+?)$`),
+}
+
+// deadlinePattern finds "by/before/in <date>" at the end of a question
+var deadlinePattern = regexp.MustCompile(`(?i)\s+(by|before|in|on|after)\s+((?:the )?(?:end of )?(?:[a-z]+\.? )?(?:\d{1,2}(?:st|nd|rd|th)?,? )?\d{4}|(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?(?: \d{1,2}(?:st|nd|rd|th)?)?)$`)
+
+// ParseQuestion extracts subject, event and deadline from a market question
+func ParseQuestion(question string) ParsedQuestion {
+	q := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(question), "?"))
+
+	var parsed ParsedQuestion
+	if m := deadlinePattern.FindStringSubmatchIndex(q); m != nil {
+		parsed.Deadline = q[m[4]:m[5]]
+		switch strings.ToLower(q[m[2]:m[3]]) {
+		case "by", "before", "in":
+			parsed.Cutoff = true
+		}
+		q = q[:m[0]]
+	}
+
+	for i, pattern := range questionPatterns {
+		m := pattern.FindStringSubmatch(q)
+		if m == nil {
+			continue
+		}
+		parsed.Subject = strings.TrimSpace(m[1])
+		if i == len(questionPatterns)-1 {
+			// Rate moves: the action is part of the event ("increases rates by 25 bps")
+			parsed.Event = strings.TrimSpace(m[2] + " " + m[3])
+		} else {
+			parsed.Event = strings.TrimSpace(m[2])
+		}
+		return parsed
+	}
+
+	parsed.Subject = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(q, "Will "), "will "))
+	return parsed
+}
+
+// Entity is an external thing a question can be about: a team, candidate, FOMC meeting...
+type Entity struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"` // e.g. "team", "candidate", "meeting"
+	Aliases []string `json:"aliases"`
+}
+
+// EntityTable is a reference table of entities loaded from JSON
+type EntityTable struct {
+	Entities []Entity
+
+	byID map[string]*Entity
+}
+
+// NewEntityTable indexes entities, rejecting duplicate or missing IDs
+func NewEntityTable(entities []Entity) (*EntityTable, error) {
+	t := &EntityTable{Entities: entities, byID: make(map[string]*Entity)}
+	for i := range t.Entities {
+		e := &t.Entities[i]
+		if e.ID == "" || e.Name == "" {
+			return nil, fmt.Errorf("entities[%d]: id and name are required", i)
+		}
+		if _, dup := t.byID[e.ID]; dup {
+			return nil, fmt.Errorf("entities[%d]: duplicate id %q", i, e.ID)
+		}
+		t.byID[e.ID] = e
+	}
+	return t, nil
+}
+
+// LoadEntityTable reads a JSON array of entities
+func LoadEntityTable(path string) (*EntityTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity table: %w", err)
+	}
+
+	var entities []Entity
+	if err := json.Unmarshal(data, &entities); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entity table: %w", err)
+	}
+
+	return NewEntityTable(entities)
+}
+
+// Get returns an entity by ID
+func (t *EntityTable) Get(id string) (*Entity, bool) {
+	e, ok := t.byID[id]
+	return e, ok
+}
+
+// EntityMatch is a candidate entity for a question with its similarity score (0-1)
+type EntityMatch struct {
+	Entity *Entity
+	Score  float64
+	Alias  string // Name or alias that matched best
+}
+
+// Match scores every entity against the parsed subject and the full question text.
+// A multi-word alias appearing word for word in the question scores 0.9 (0.85 for a
+// single word); otherwise the Jaro-Winkler similarity of subject and alias is used.
+func (t *EntityTable) Match(question string, parsed ParsedQuestion) []EntityMatch {
+	text := newQuestionText(question)
+	subject := normalizeQuestion(parsed.Subject)
+
+	var matches []EntityMatch
+	for i := range t.Entities {
+		e := &t.Entities[i]
+		best := EntityMatch{Entity: e}
+		for _, alias := range append([]string{e.Name}, e.Aliases...) {
+			normalized := normalizeQuestion(alias)
+			if normalized == "" {
+				continue
+			}
+
+			score := 0.0
+			if subject == normalized {
+				score = 1
+			} else {
+				if subject != "" {
+					score = jaroWinkler(subject, normalized)
+				}
+				if words := tokenize(alias); len(words) > 0 && text.contains(keywordPhrase{words: words}) {
+					// A single word ("Bills") turns up in unrelated questions far more often than a full name
+					if len(words) == 1 {
+						score = max(score, 0.85)
+					} else {
+						score = max(score, 0.9)
+					}
+				}
+			}
+
+			if score > best.Score {
+				best.Score = score
+				best.Alias = alias
+			}
+		}
+		if best.Score >= minCandidateScore {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Entity.ID < matches[j].Entity.ID
+	})
+	return matches
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings (1 = identical)
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	window = max(window, 0)
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo, hi := max(0, i-window), min(len(rb)-1, i+window)
+		for j := lo; j <= hi; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// EntityMapping is a confirmed link from a question to an entity
+type EntityMapping struct {
+	Question    string    `json:"question"`
+	EntityID    string    `json:"entity_id"`
+	ConfirmedAt time.Time `json:"confirmed_at"`
+}
+
+// MappingStore persists confirmed question-to-entity mappings in a JSON file
+type MappingStore struct {
+	mu       sync.RWMutex
+	path     string
+	mappings map[string]EntityMapping // Keyed by normalized question
+}
+
+// OpenMappingStore loads the mappings file at path, starting empty if it does not exist
+func OpenMappingStore(path string) (*MappingStore, error) {
+	store := &MappingStore{path: path, mappings: make(map[string]EntityMapping)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mappings: %w", err)
+	}
+
+	var mappings []EntityMapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mappings: %w", err)
+	}
+	for _, m := range mappings {
+		store.mappings[normalizeQuestion(m.Question)] = m
+	}
+
+	return store, nil
+}
+
+// Lookup returns the confirmed mapping for a question
+func (s *MappingStore) Lookup(question string) (EntityMapping, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.mappings[normalizeQuestion(question)]
+	return m, ok
+}
+
+// Confirm records a mapping and writes the store to disk
+func (s *MappingStore) Confirm(question, entityID string) error {
+	s.mu.Lock()
+	s.mappings[normalizeQuestion(question)] = EntityMapping{
+		Question:    question,
+		EntityID:    entityID,
+		ConfirmedAt: time.Now().UTC(),
+	}
+	s.mu.Unlock()
+
+	return s.Save()
+}
+
+// Save writes every mapping to the store's file
+func (s *MappingStore) Save() error {
+	s.mu.RLock()
+	mappings := make([]EntityMapping, 0, len(s.mappings))
+	for _, m := range s.mappings {
+		mappings = append(mappings, m)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Question < mappings[j].Question })
+
+	data, err := json.MarshalIndent(mappings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal mappings: %w", err)
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Resolution sources
+const (
+	ResolvedConfirmed = "confirmed" // From a stored mapping
+	ResolvedMatched   = "matched"   // Best fuzzy match above the threshold
+)
+
+// Resolution is the outcome of linking a question to an entity
+type Resolution struct {
+	Parsed     ParsedQuestion
+	Entity     *Entity       // nil when unresolved
+	Score      float64       // Match score, 1 for confirmed mappings
+	Source     string        // ResolvedConfirmed or ResolvedMatched, empty when unresolved
+	Candidates []EntityMatch // Best matches, for a human to confirm
+}
+
+// defaultMinEntityScore is the match score needed to resolve without confirmation
+const defaultMinEntityScore = 0.88
+
+// minCandidateScore is the match score below which an entity is not worth suggesting
+const minCandidateScore = 0.7
+
+// maxEntityCandidates bounds the candidates returned with a resolution
+const maxEntityCandidates = 5
+
+// EntityResolver links market questions to entities in a reference table,
+// reusing confirmed mappings when they exist
+type EntityResolver struct {
+	Table    *EntityTable
+	Mappings *MappingStore // Optional; nil disables confirmed mappings
+	MinScore float64       // Score needed to resolve without confirmation (default 0.88)
+}
+
+// Resolve links a question to an entity
+func (r *EntityResolver) Resolve(question string) Resolution {
+	res := Resolution{Parsed: ParseQuestion(question)}
+
+	if r.Mappings != nil {
+		if m, ok := r.Mappings.Lookup(question); ok {
+			if e, ok := r.Table.Get(m.EntityID); ok {
+				res.Entity, res.Score, res.Source = e, 1, ResolvedConfirmed
+				return res
+			}
+		}
+	}
+
+	matches := r.Table.Match(question, res.Parsed)
+	if len(matches) > maxEntityCandidates {
+		matches = matches[:maxEntityCandidates]
+	}
+	res.Candidates = matches
+
+	minScore := r.MinScore
+	if minScore <= 0 {
+		minScore = defaultMinEntityScore
+	}
+
+	// Refuse to guess between two equally good candidates
+	if len(matches) > 0 && matches[0].Score >= minScore &&
+		(len(matches) == 1 || matches[1].Score < matches[0].Score) {
+		res.Entity, res.Score, res.Source = matches[0].Entity, matches[0].Score, ResolvedMatched
+	}
+
+	return res
+}
+
+// Confirm stores a question-to-entity mapping so future resolutions reuse it
+func (r *EntityResolver) Confirm(question, entityID string) error {
+	if r.Mappings == nil {
+		return errors.New("resolver has no mapping store")
+	}
+	if _, ok := r.Table.Get(entityID); !ok {
+		return fmt.Errorf("unknown entity %q", entityID)
+	}
+	return r.Mappings.Confirm(question, entityID)
+}
@@ -0,0 +1,170 @@
+package marketmaker
+
+import (
+	"math"
+)
+
+// KellyFraction returns the full-Kelly fraction of bankroll to stake on a binary contract
+// at price given our probability. Positive means buy YES; negative means sell YES (buy NO
+// at 1-price), staking |f| of bankroll.
+func KellyFraction(probability, price float64) float64 {
+	if probability <= 0 || probability >= 1 || price <= 0 || price >= 1 {
+		return 0
+	}
+	if probability > price {
+		return (probability - price) / (1 - price)
+	}
+	return -(price - probability) / price
+}
+
+// Position is what we hold in one market
+type Position struct {
+	Cash float64 // Bankroll available to this market
+	Yes  float64 // YES shares held
+	No   float64 // NO shares held
+}
+
+// wealth returns our wealth at resolution if the market resolves YES or NO
+func (p Position) wealth() (ifYes, ifNo float64) {
+	return p.Cash + p.Yes, p.Cash + p.No
+}
+
+// MakerQuote is a pair of resting orders to size
+type MakerQuote struct {
+	Bid         float64 // Price we buy YES at; 0 for no bid
+	Ask         float64 // Price we sell YES at (buy NO at 1-Ask)
+	BidFillProb float64 // Chance the bid fills before we requote
+	AskFillProb float64 // Chance the ask fills before we requote
+	Adverse     float64 // Log-odds a fill moves our probability against us
+}
+
+// KellySize is the Kelly size for each side of a quote, in YES shares. The per-side sizes
+// and notionals are order quantities, never negative; the side says which way each trades.
+// Only Net is signed.
+type KellySize struct {
+	Buy          float64 // YES shares to bid for, >= 0
+	Sell         float64 // YES shares to offer, >= 0; beyond what we hold this is NO bought at 1-Ask
+	BuyNotional  float64 // Dollars spent if the bid fills, >= 0
+	SellNotional float64 // Collateral posted if the ask fills: 1-Ask per share, >= 0
+	Net          float64 // Buy minus Sell: positive adds YES exposure if both fill, negative removes it
+}
+
+// MakerKelly sizes both sides of a resting quote by maximizing expected log wealth over
+// which orders fill. Each fill shifts our probability by Adverse in the unfavorable
+// direction, and the chance that both sides fill lets each side lean on the other as a
+// hedge. Existing holdings, including partial fills, are counted in pos, so the result is
+// what remains to be placed. Fractional Kelly (e.g. 0.25) is full Kelly on that fraction
+// of the cash, so shares already held count fully against the smaller stake.
+func MakerKelly(probability float64, pos Position, quote MakerQuote, fraction float64) KellySize {
+	if probability <= 0 || probability >= 1 || quote.Bid < 0 || quote.Ask >= 1 || quote.Bid >= quote.Ask {
+		return KellySize{}
+	}
+	pos.Cash *= fraction
+
+	if ifYes, ifNo := pos.wealth(); ifYes <= 0 || ifNo <= 0 {
+		return KellySize{}
+	}
+
+	pb := quote.BidFillProb
+	if quote.Bid == 0 {
+		pb = 0
+	}
+	pa := quote.AskFillProb
+	yesIfBid := logistic(logit(probability) - quote.Adverse)
+	yesIfAsk := logistic(logit(probability) + quote.Adverse)
+
+	// Scenarios: which orders filled, how likely that is, and what we then believe
+	type scenario struct {
+		bid, ask bool
+		weight   float64
+		yes      float64
+	}
+	scenarios := []scenario{
+		{bid: true, weight: pb * (1 - pa), yes: yesIfBid},
+		{ask: true, weight: (1 - pb) * pa, yes: yesIfAsk},
+		{bid: true, ask: true, weight: pb * pa, yes: probability},
+	}
+
+	base := func(s scenario, buy, sell float64) (ifYes, ifNo float64) {
+		ifYes, ifNo = pos.wealth()
+		if s.bid {
+			ifYes += buy * (1 - quote.Bid)
+			ifNo -= buy * quote.Bid
+		}
+		if s.ask {
+			ifYes -= sell * (1 - quote.Ask)
+			ifNo += sell * quote.Ask
+		}
+		return ifYes, ifNo
+	}
+
+	// The objective is concave in each size, so coordinate ascent with a bisection on each
+	// derivative converges
+	var buy, sell float64
+	for iter := 0; iter < 100; iter++ {
+		prevBuy, prevSell := buy, sell
+
+		buy = solveSide(func(x float64) (float64, bool) {
+			d := 0.0
+			for _, s := range scenarios {
+				if !s.bid || s.weight == 0 {
+					continue
+				}
+				ifYes, ifNo := base(s, x, sell)
+				if ifNo <= 0 {
+					return 0, false
+				}
+				d += s.weight * (s.yes*(1-quote.Bid)/ifYes - (1-s.yes)*quote.Bid/ifNo)
+			}
+			return d, true
+		})
+		sell = solveSide(func(x float64) (float64, bool) {
+			d := 0.0
+			for _, s := range scenarios {
+				if !s.ask || s.weight == 0 {
+					continue
+				}
+				ifYes, ifNo := base(s, buy, x)
+				if ifYes <= 0 {
+					return 0, false
+				}
+				d += s.weight * ((1-s.yes)*quote.Ask/ifNo - s.yes*(1-quote.Ask)/ifYes)
+			}
+			return d, true
+		})
+
+		if math.Abs(buy-prevBuy) < 1e-6 && math.Abs(sell-prevSell) < 1e-6 {
+			break
+		}
+	}
+
+	return KellySize{
+		Buy:          buy,
+		Sell:         sell,
+		BuyNotional:  buy * quote.Bid,
+		SellNotional: sell * (1 - quote.Ask),
+		Net:          buy - sell,
+	}
+}
+
+// solveSide finds where a decreasing derivative crosses zero for a size of at least zero.
+// derivative reports false once the size would risk all our wealth in some outcome.
+func solveSide(derivative func(size float64) (float64, bool)) float64 {
+	d, ok := derivative(0)
+	if !ok || d <= 0 {
+		return 0
+	}
+
+	hi := 1.0
+	for i := 0; i < 60; i++ {
+		if d, ok := derivative(hi); !ok || d <= 0 {
+			break
+		}
+		hi *= 2
+	}
+
+	return bisect(0, hi, func(size float64) bool {
+		d, ok := derivative(size)
+		return ok && d > 0
+	})
+}
@@ -0,0 +1,605 @@
+package marketmaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LedgerFill is one of our fills as the ledger records it
+type LedgerFill struct {
+	TradeID string    `json:"trade_id"`
+	OrderID string    `json:"order_id"` // Exchange order ID
+	TokenID string    `json:"token_id"`
+	Market  string    `json:"market,omitempty"` // Condition ID, when known
+	Side    Side      `json:"side"`
+	Price   float64   `json:"price"`
+	Size    float64   `json:"size"`
+	Fee     float64   `json:"fee,omitempty"` // Dollars
+	Time    time.Time `json:"time"`
+}
+
+// key identifies a fill across sources: one order's share of one trade
+func (f LedgerFill) key() string {
+	return f.TradeID + "/" + f.OrderID
+}
+
+// LedgerReward is income not tied to a fill, such as a liquidity reward
+type LedgerReward struct {
+	TokenID string    `json:"token_id,omitempty"` // Empty for account-wide rewards
+	Amount  float64   `json:"amount"`
+	Note    string    `json:"note,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// TokenInfo describes a token for per-market, per-event and per-category rollups and limits
+type TokenInfo struct {
+	Question string         `json:"question"`
+	Market   string         `json:"market,omitempty"` // Condition ID
+	Event    string         `json:"event,omitempty"`  // Event ID, shared by mutually exclusive markets
+	Category MarketCategory `json:"category"`
+}
+
+// marketKey groups a token with the other outcomes of its market
+func (i TokenInfo) marketKey(tokenID string) string {
+	switch {
+	case i.Market != "":
+		return i.Market
+	case i.Question != "":
+		return i.Question
+	}
+	return tokenID
+}
+
+// Ledger records what we own: every fill and reward, from which positions, average costs
+// and PnL are derived. Positions are kept current as fills arrive; a fill older than its
+// token's latest is replayed in time order on the next read. The ledger persists to a JSON
+// file and can be rebuilt from the exchange's trade history.
+type Ledger struct {
+	Now func() time.Time // Dates trades without a match time; nil uses time.Now
+
+	mu        sync.RWMutex
+	path      string
+	fills     []LedgerFill
+	seen      map[string]int // Fill key to index in fills
+	rewards   []LedgerReward
+	tokens    map[string]TokenInfo
+	positions map[string]*TokenPosition
+	latest    map[string]time.Time // Time of each token's latest applied fill
+	stale     map[string]bool      // Tokens with a fill out of time order, to replay before reading
+}
+
+// ledgerFile is the on-disk form of a ledger
+type ledgerFile struct {
+	Tokens  map[string]TokenInfo `json:"tokens"`
+	Fills   []LedgerFill         `json:"fills"`
+	Rewards []LedgerReward       `json:"rewards"`
+}
+
+// NewLedger creates an empty ledger that is not backed by a file
+func NewLedger() *Ledger {
+	return &Ledger{
+		seen:      make(map[string]int),
+		tokens:    make(map[string]TokenInfo),
+		positions: make(map[string]*TokenPosition),
+		latest:    make(map[string]time.Time),
+		stale:     make(map[string]bool),
+	}
+}
+
+// OpenLedger loads the ledger file at path, starting empty if it does not exist
+func OpenLedger(path string) (*Ledger, error) {
+	l := NewLedger()
+	l.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	var file ledgerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ledger: %w", err)
+	}
+	for tokenID, info := range file.Tokens {
+		l.tokens[tokenID] = info
+	}
+	for _, f := range file.Fills {
+		l.Record(f)
+	}
+	for _, r := range file.Rewards {
+		l.RecordReward(r)
+	}
+	return l, nil
+}
+
+// Save writes the ledger to its file
+func (l *Ledger) Save() error {
+	if l.path == "" {
+		return errors.New("failed to save ledger: no file")
+	}
+
+	l.mu.RLock()
+	data, err := json.MarshalIndent(ledgerFile{Tokens: l.tokens, Fills: l.fills, Rewards: l.rewards}, "", "  ")
+	l.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger: %w", err)
+	}
+	return writeFileAtomic(l.path, data)
+}
+
+// Describe names a token's market and category for rollups
+func (l *Ledger) Describe(tokenID string, info TokenInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if existing, ok := l.tokens[tokenID]; ok && info.Market == "" {
+		info.Market = existing.Market
+	}
+	l.tokens[tokenID] = info
+}
+
+// Info returns what the ledger knows about a token
+func (l *Ledger) Info(tokenID string) TokenInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.tokens[tokenID]
+}
+
+// Record adds a fill, reporting whether it was new. A fill already recorded from another
+// source is not counted twice; the repeat only supplies a fee the first record lacked.
+func (l *Ledger) Record(f LedgerFill) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i, ok := l.seen[f.key()]; ok {
+		existing := &l.fills[i]
+		p := l.position(existing.TokenID)
+		if existing.Fee == 0 && f.Fee > 0 {
+			existing.Fee = f.Fee
+			p.Fees += f.Fee
+		}
+		if existing.Market == "" {
+			existing.Market = f.Market
+		}
+		if p.Info.Market == "" {
+			p.Info.Market = f.Market
+		}
+		return false
+	}
+	l.seen[f.key()] = len(l.fills)
+	l.fills = append(l.fills, f)
+
+	p := l.position(f.TokenID)
+	if p.Info.Market == "" {
+		p.Info.Market = f.Market
+	}
+	switch {
+	case l.stale[f.TokenID]:
+	case f.Time.Before(l.latest[f.TokenID]):
+		l.stale[f.TokenID] = true
+	default:
+		p.apply(f)
+		l.latest[f.TokenID] = f.Time
+	}
+	return true
+}
+
+func (l *Ledger) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// position returns a token's position for updating, creating it on first use. The caller
+// holds the write lock.
+func (l *Ledger) position(tokenID string) *TokenPosition {
+	p, ok := l.positions[tokenID]
+	if !ok {
+		p = &TokenPosition{TokenID: tokenID}
+		l.positions[tokenID] = p
+	}
+	return p
+}
+
+// replay rebuilds the positions of tokens whose fills arrived out of time order. The caller
+// holds the write lock.
+func (l *Ledger) replay() {
+	if len(l.stale) == 0 {
+		return
+	}
+	var fills []LedgerFill
+	for _, f := range l.fills {
+		if l.stale[f.TokenID] {
+			fills = append(fills, f)
+		}
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Time.Before(fills[j].Time) })
+
+	for tokenID := range l.stale {
+		old := l.position(tokenID)
+		l.positions[tokenID] = &TokenPosition{TokenID: tokenID, Rewards: old.Rewards}
+	}
+	for _, f := range fills {
+		p := l.positions[f.TokenID]
+		if p.Info.Market == "" {
+			p.Info.Market = f.Market
+		}
+		p.apply(f)
+		l.latest[f.TokenID] = f.Time
+	}
+	clear(l.stale)
+}
+
+// snapshot copies a token's position, described as the ledger knows it. The caller holds a
+// lock and has replayed stale tokens.
+func (l *Ledger) snapshot(tokenID string) TokenPosition {
+	p := TokenPosition{TokenID: tokenID}
+	if existing, ok := l.positions[tokenID]; ok {
+		p = *existing
+	}
+	info := l.tokens[tokenID]
+	if info.Market == "" {
+		info.Market = p.Info.Market
+	}
+	p.Info = info
+	return p
+}
+
+// settled runs read with positions current, taking the write lock only when a stale token
+// must be replayed first
+func (l *Ledger) settled(read func()) {
+	l.mu.RLock()
+	if len(l.stale) == 0 {
+		defer l.mu.RUnlock()
+		read()
+		return
+	}
+	l.mu.RUnlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.replay()
+	read()
+}
+
+// RecordOrderFill records a fill reported by the order manager; set it as the manager's
+// OnFill. The manager does not know fees, which Sync fills in from the trade history.
+func (l *Ledger) RecordOrderFill(o ManagedOrder, f OrderFill) bool {
+	return l.Record(LedgerFill{
+		TradeID: f.TradeID,
+		OrderID: o.ExchangeID,
+		TokenID: o.TokenID,
+		Side:    o.Side,
+		Price:   f.Price,
+		Size:    f.Size,
+		Time:    f.Time,
+	})
+}
+
+// RecordReward adds income not tied to a fill
+func (l *Ledger) RecordReward(r LedgerReward) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rewards = append(l.rewards, r)
+	if r.TokenID != "" {
+		l.position(r.TokenID).Rewards += r.Amount
+	}
+}
+
+// ApplyTrades records our side of each trade, returning how many fills were new. A trade
+// we took is ours whole; of the maker orders a trade matched, only those owned by owner (an
+// API key or maker address) are ours, or all of them when owner is empty.
+func (l *Ledger) ApplyTrades(trades []Trade, owner string) int {
+	added := 0
+	for _, t := range trades {
+		if strings.EqualFold(t.Status, "FAILED") {
+			continue
+		}
+		at := t.MatchedAt(l.now())
+
+		if strings.EqualFold(t.TraderSide, "TAKER") {
+			var side Side
+			price, _ := parseFloat(t.Price)
+			size, _ := parseFloat(t.Size)
+			rate, _ := parseFloat(t.FeeRateBps)
+			if side.UnmarshalText([]byte(t.Side)) == nil && size > 0 && l.Record(LedgerFill{
+				TradeID: t.ID, OrderID: t.TakerOrderID, TokenID: t.AssetID, Market: t.Market,
+				Side: side, Price: price, Size: size, Fee: takerFee(rate, price, size), Time: at,
+			}) {
+				added++
+			}
+			continue
+		}
+
+		for _, mo := range t.MakerOrders {
+			if owner != "" && mo.Owner != owner && !SameAddress(mo.MakerAddress, owner) {
+				continue
+			}
+			var side Side
+			price, _ := parseFloat(mo.Price)
+			size, _ := parseFloat(mo.MatchedAmount)
+			tokenID := mo.AssetID
+			if tokenID == "" {
+				tokenID = t.AssetID
+			}
+			if side.UnmarshalText([]byte(mo.Side)) == nil && size > 0 && l.Record(LedgerFill{
+				TradeID: t.ID, OrderID: mo.OrderID, TokenID: tokenID, Market: t.Market,
+				Side: side, Price: price, Size: size, Time: at,
+			}) {
+				added++
+			}
+		}
+	}
+	return added
+}
+
+// Sync records every trade in the exchange's history that the ledger lacks. Run on an empty
+// ledger, it reconstructs the ledger from the exchange alone.
+func (l *Ledger) Sync(ctx context.Context, ex Exchange, owner string) (int, error) {
+	trades, err := ex.Trades(ctx, TradeFilter{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to sync ledger: %w", err)
+	}
+	return l.ApplyTrades(trades, owner), nil
+}
+
+// takerFee is the CLOB's fee on a taker fill: the rate applied to the cheaper of the
+// outcome and its complement
+func takerFee(rateBps, price, size float64) float64 {
+	return rateBps / 1e4 * math.Min(price, 1-price) * size
+}
+
+// TokenPosition is what we hold of one token, with average-cost accounting
+type TokenPosition struct {
+	TokenID  string
+	Info     TokenInfo
+	Shares   float64 // Negative when short
+	AvgCost  float64 // Average price of the shares held
+	Realized float64 // PnL on shares closed out, before fees
+	Fees     float64
+	Rewards  float64
+	Bought   float64 // Shares
+	Sold     float64 // Shares
+	Fills    int
+}
+
+// apply adds a fill to the position
+func (p *TokenPosition) apply(f LedgerFill) {
+	p.Fills++
+	p.Fees += f.Fee
+	if f.Side == SideBuy {
+		p.Bought += f.Size
+	} else {
+		p.Sold += f.Size
+	}
+
+	qty := f.Side.sign() * f.Size
+	if p.Shares == 0 || (p.Shares > 0) == (qty > 0) {
+		held := math.Abs(p.Shares)
+		p.AvgCost = (held*p.AvgCost + f.Size*f.Price) / (held + f.Size)
+		p.Shares += qty
+		return
+	}
+
+	// Closing: realize against the average cost, and open the other way with any excess
+	closed := math.Min(f.Size, math.Abs(p.Shares))
+	direction := 1.0
+	if p.Shares < 0 {
+		direction = -1
+	}
+	p.Realized += closed * (f.Price - p.AvgCost) * direction
+	p.Shares += qty
+	switch {
+	case math.Abs(p.Shares) < 1e-9:
+		p.Shares, p.AvgCost = 0, 0
+	case f.Size > closed:
+		p.AvgCost = f.Price
+	}
+}
+
+// Positions returns every token the ledger has seen, ordered by token ID
+func (l *Ledger) Positions() []TokenPosition {
+	var out []TokenPosition
+	l.settled(func() {
+		out = make([]TokenPosition, 0, len(l.positions))
+		for tokenID := range l.positions {
+			out = append(out, l.snapshot(tokenID))
+		}
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].TokenID < out[j].TokenID })
+	return out
+}
+
+// Position returns what the ledger holds of one token
+func (l *Ledger) Position(tokenID string) TokenPosition {
+	var p TokenPosition
+	l.settled(func() { p = l.snapshot(tokenID) })
+	return p
+}
+
+// Shares returns the shares held of a token; it fits QuotingEngine.Inventory
+func (l *Ledger) Shares(tokenID string) float64 {
+	return l.Position(tokenID).Shares
+}
+
+// Marker prices a token's shares for unrealized PnL; ok is false when it has no price
+type Marker func(tokenID string) (price float64, ok bool)
+
+// MidMarker marks to the mid of each token's current book. A placeholder book has no
+// price, so a fallback marker can supply one.
+func MidMarker(books BookSource) Marker {
+	return func(tokenID string) (float64, bool) {
+		book, err := books.GetOrderBook(tokenID)
+		if err != nil {
+			return 0, false
+		}
+		return realMid(book)
+	}
+}
+
+// PriceMarker marks to fixed prices per token, such as last trades or fair values
+func PriceMarker(prices map[string]float64) Marker {
+	return func(tokenID string) (float64, bool) {
+		price, ok := prices[tokenID]
+		return price, ok
+	}
+}
+
+// FallbackMarker uses the first marker that has a price
+func FallbackMarker(markers ...Marker) Marker {
+	return func(tokenID string) (float64, bool) {
+		for _, mark := range markers {
+			if price, ok := mark(tokenID); ok {
+				return price, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// PositionPnL is a position valued at a mark
+type PositionPnL struct {
+	TokenPosition
+	Mark       float64
+	Marked     bool // False when no mark was found and the position is held at cost
+	Value      float64
+	Unrealized float64
+}
+
+// PnLSummary totals PnL over a set of positions
+type PnLSummary struct {
+	Positions  int     // Open positions
+	Cost       float64 // Cost basis of open positions
+	Value      float64 // Marked value of open positions
+	Realized   float64
+	Unrealized float64
+	Fees       float64
+	Rewards    float64
+}
+
+// Net is realized and unrealized PnL plus rewards, less fees
+func (s PnLSummary) Net() float64 {
+	return s.Realized + s.Unrealized + s.Rewards - s.Fees
+}
+
+func (s *PnLSummary) add(p PositionPnL) {
+	if p.Shares != 0 {
+		s.Positions++
+	}
+	s.Cost += p.Shares * p.AvgCost
+	s.Value += p.Value
+	s.Realized += p.Realized
+	s.Unrealized += p.Unrealized
+	s.Fees += p.Fees
+	s.Rewards += p.Rewards
+}
+
+// PnLReport values every position and rolls PnL up by market, category and in total
+type PnLReport struct {
+	Positions  []PositionPnL
+	Markets    map[string]PnLSummary // Keyed by question, or condition ID when the token was never described
+	Categories map[MarketCategory]PnLSummary
+	Total      PnLSummary
+	Unmarked   []string // Tokens held at cost for want of a mark
+}
+
+// Report values the ledger at the given marks
+func (l *Ledger) Report(mark Marker) PnLReport {
+	report := PnLReport{
+		Markets:    make(map[string]PnLSummary),
+		Categories: make(map[MarketCategory]PnLSummary),
+	}
+
+	for _, p := range l.Positions() {
+		pnl := PositionPnL{TokenPosition: p, Mark: p.AvgCost}
+		if p.Shares != 0 {
+			if price, ok := mark(p.TokenID); ok {
+				pnl.Mark, pnl.Marked = price, true
+			} else {
+				report.Unmarked = append(report.Unmarked, p.TokenID)
+			}
+			pnl.Value = p.Shares * pnl.Mark
+			pnl.Unrealized = p.Shares * (pnl.Mark - p.AvgCost)
+		}
+		report.Positions = append(report.Positions, pnl)
+
+		market := p.Info.Question
+		if market == "" {
+			market = p.Info.Market
+		}
+		if market == "" {
+			market = p.TokenID
+		}
+		summary := report.Markets[market]
+		summary.add(pnl)
+		report.Markets[market] = summary
+
+		summary = report.Categories[p.Info.Category]
+		summary.add(pnl)
+		report.Categories[p.Info.Category] = summary
+
+		report.Total.add(pnl)
+	}
+
+	l.mu.RLock()
+	for _, r := range l.rewards {
+		if r.TokenID == "" {
+			report.Total.Rewards += r.Amount
+		}
+	}
+	l.mu.RUnlock()
+	return report
+}
+
+// String renders the report as a table of markets, then categories and the total
+func (r PnLReport) String() string {
+	var b strings.Builder
+	row := func(name string, s PnLSummary) {
+		fmt.Fprintf(&b, "%-50.50s %3d %9.2f %9.2f %9.2f %9.2f %7.2f %7.2f %9.2f\n",
+			name, s.Positions, s.Cost, s.Value, s.Realized, s.Unrealized, s.Fees, s.Rewards, s.Net())
+	}
+	header := func(title string) {
+		fmt.Fprintf(&b, "%-50s %3s %9s %9s %9s %9s %7s %7s %9s\n",
+			title, "Pos", "Cost", "Value", "Realized", "Unreal.", "Fees", "Rewards", "Net")
+	}
+
+	header("Market")
+	markets := make([]string, 0, len(r.Markets))
+	for m := range r.Markets {
+		markets = append(markets, m)
+	}
+	sort.Strings(markets)
+	for _, m := range markets {
+		row(m, r.Markets[m])
+	}
+
+	b.WriteString("\n")
+	header("Category")
+	categories := make([]MarketCategory, 0, len(r.Categories))
+	for c := range r.Categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].String() < categories[j].String() })
+	for _, c := range categories {
+		row(c.String(), r.Categories[c])
+	}
+
+	b.WriteString("\n")
+	row("TOTAL", r.Total)
+	if len(r.Unmarked) > 0 {
+		fmt.Fprintf(&b, "Held at cost, no mark: %s\n", strings.Join(r.Unmarked, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package marketmaker
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLedgerPositionsMatchTimeOrder(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	fills := []LedgerFill{
+		{TradeID: "t1", TokenID: testTokenID, Side: SideBuy, Price: 0.10, Size: 100, Time: start},
+		{TradeID: "t2", TokenID: testTokenID, Side: SideBuy, Price: 0.20, Size: 100, Time: start.Add(time.Minute)},
+		{TradeID: "t3", TokenID: testTokenID, Side: SideSell, Price: 0.30, Size: 150, Time: start.Add(2 * time.Minute)},
+		{TradeID: "t4", TokenID: testTokenID, Side: SideSell, Price: 0.25, Size: 100, Time: start.Add(3 * time.Minute)},
+	}
+	// Held 200 at 0.15, sold 150 at 0.30 and 50 at 0.25, then short 50 at 0.25
+	want := TokenPosition{TokenID: testTokenID, Shares: -50, AvgCost: 0.25, Realized: 150*0.15 + 50*0.10,
+		Fees: 0.5, Rewards: 1, Bought: 200, Sold: 250, Fills: 4}
+
+	orders := map[string][]int{
+		"in order":     {0, 1, 2, 3},
+		"out of order": {3, 1, 0, 2},
+		"reversed":     {3, 2, 1, 0},
+	}
+	for name, order := range orders {
+		t.Run(name, func(t *testing.T) {
+			l := NewLedger()
+			l.RecordReward(LedgerReward{TokenID: testTokenID, Amount: 1})
+			for i, idx := range order {
+				l.Record(fills[idx])
+				if i == 1 {
+					// Reading between fills must not disturb what follows
+					l.Shares(testTokenID)
+				}
+			}
+			// The trade history supplies a fee the first record lacked
+			withFee := fills[2]
+			withFee.Fee = 0.5
+			if l.Record(withFee) {
+				t.Error("repeat fill recorded as new")
+			}
+
+			got := l.Position(testTokenID)
+			if got.Shares != want.Shares || got.Fills != want.Fills || got.Bought != want.Bought || got.Sold != want.Sold ||
+				math.Abs(got.AvgCost-want.AvgCost) > 1e-9 || math.Abs(got.Realized-want.Realized) > 1e-9 ||
+				got.Fees != want.Fees || got.Rewards != want.Rewards {
+				t.Errorf("position %+v, want %+v", got, want)
+			}
+			if positions := l.Positions(); len(positions) != 1 || positions[0].Shares != want.Shares {
+				t.Errorf("positions %+v, want one short %v", positions, -want.Shares)
+			}
+		})
+	}
+}
+
+func TestLedgerDatesUntimedTradesWhenSeen(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	l := NewLedger()
+	l.Now = func() time.Time { return start.Add(time.Hour) }
+
+	trades := []Trade{
+		{ID: "t1", TakerOrderID: "o1", AssetID: testTokenID, Side: "BUY", Price: "0.1", Size: "100",
+			TraderSide: "TAKER", MatchTime: "1700000000"},
+		// Sold after the buy, though it carries no match time
+		{ID: "t2", TakerOrderID: "o2", AssetID: testTokenID, Side: "SELL", Price: "0.3", Size: "100",
+			TraderSide: "TAKER"},
+		// Recorded late but matched before the sale, forcing a replay in time order
+		{ID: "t3", TakerOrderID: "o3", AssetID: testTokenID, Side: "BUY", Price: "0.2", Size: "100",
+			TraderSide: "TAKER", MatchTime: "1700000060"},
+	}
+	if added := l.ApplyTrades(trades, ""); added != 3 {
+		t.Fatalf("added %d fills, want 3", added)
+	}
+
+	p := l.Position(testTokenID)
+	// 200 bought at 0.15 average, then 100 sold at 0.3
+	if p.Shares != 100 || math.Abs(p.AvgCost-0.15) > 1e-9 || math.Abs(p.Realized-15) > 1e-9 {
+		t.Errorf("position %+v, want 100 shares at 0.15 with 15 realized", p)
+	}
+}
+
+func TestMidMarkerSkipsPlaceholderBooks(t *testing.T) {
+	books := staticBooks{
+		"dust": paperBook([]string{"0.01", "1000"}, []string{"0.99", "1000"}),
+		"real": paperBook([]string{"0.30", "10"}, []string{"0.34", "10"}),
+	}
+	mark := FallbackMarker(MidMarker(books), PriceMarker(map[string]float64{"dust": 0.02}))
+	if price, ok := mark("dust"); !ok || price != 0.02 {
+		t.Errorf("dust mark %.4f, %v, want the 0.02 fallback rather than the placeholder mid", price, ok)
+	}
+	if price, ok := mark("real"); !ok || math.Abs(price-0.32) > 1e-9 {
+		t.Errorf("real mark %.4f, %v, want the 0.32 mid", price, ok)
+	}
+}
@@ -0,0 +1,316 @@
+package marketmaker
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Model priorities used by the default registry. Higher priorities are consulted first,
+// so custom models registered above PriorityDefault run before the built-in rules.
+const (
+	PriorityFallback = -1000 // Catch-all model used when nothing else applies
+	PriorityDefault  = 0     // Built-in rule, longshot and competitive models
+	PriorityOverride = 100   // Models that should pre-empt the built-in ones (e.g. external anchors)
+)
+
+// EventContext describes the event a market belongs to
+type EventContext struct {
+	Title   string
+	Markets []Market // All markets in the event, including the one being priced
+	Partial bool     // Markets holds only those seen in a scan, which may not be all of them
+}
+
+// PricingInput is everything a PricingModel may use to price a market
+type PricingInput struct {
+	Market   Market
+	Book     *OrderBookResponse // Current orderbook, nil if not fetched
+	Event    *EventContext      // Sibling markets, nil if unknown
+	Category MarketCategory
+	Rules    *RuleSet  // Rule set to price with, nil uses DefaultRules
+	Now      time.Time // Pricing time, zero uses the current time
+}
+
+// now returns the time to price at
+func (in PricingInput) now() time.Time {
+	if in.Now.IsZero() {
+		return time.Now()
+	}
+	return in.Now
+}
+
+// PricingQuote is a model's view of where a market should trade
+type PricingQuote struct {
+	Model       string  // Name of the model that produced the quote
+	FairValue   float64 // Estimated probability of YES
+	Bid         float64 // 0 means no bid
+	Ask         float64
+	Confidence  float64 // 0-1, how much weight the model puts on its own estimate
+	Explanation string
+	Steps       []PricingStep // How the quote was reached, oldest first
+}
+
+// PricingModel prices a market. Models return false when they do not apply to the input.
+type PricingModel interface {
+	Name() string
+	Price(input PricingInput) (PricingQuote, bool)
+}
+
+// ModelRegistry holds pricing models ordered by priority and acts as a chain of responsibility:
+// the highest-priority model that applies prices the market
+type ModelRegistry struct {
+	mu      sync.RWMutex
+	entries []registeredModel
+	seq     int
+}
+
+type registeredModel struct {
+	model    PricingModel
+	priority int
+	seq      int
+}
+
+// NewModelRegistry creates an empty registry
+func NewModelRegistry() *ModelRegistry {
+	return &ModelRegistry{}
+}
+
+// NewDefaultModelRegistry creates a registry holding the built-in models
+func NewDefaultModelRegistry() *ModelRegistry {
+	r := NewModelRegistry()
+	r.Register(DeadlineModel{}, PriorityDefault)
+	r.Register(RuleModel{}, PriorityDefault)
+	r.Register(LongshotModel{}, PriorityDefault)
+	r.Register(CompetitiveModel{}, PriorityDefault)
+	r.Register(fallbackModel{}, PriorityFallback)
+	return r
+}
+
+// DefaultModels is the registry used by a PricingStrategy without its own Models
+var DefaultModels = NewDefaultModelRegistry()
+
+// RegisterPricingModel adds a model to DefaultModels
+func RegisterPricingModel(model PricingModel, priority int) {
+	DefaultModels.Register(model, priority)
+}
+
+// Register adds a model. Models with equal priority are consulted in registration order.
+// Registering a model with the name of an existing one replaces it.
+func (r *ModelRegistry) Register(model PricingModel, priority int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeLocked(model.Name())
+	r.seq++
+	r.entries = append(r.entries, registeredModel{model: model, priority: priority, seq: r.seq})
+	sort.SliceStable(r.entries, func(i, j int) bool {
+		if r.entries[i].priority != r.entries[j].priority {
+			return r.entries[i].priority > r.entries[j].priority
+		}
+		return r.entries[i].seq < r.entries[j].seq
+	})
+}
+
+// Unregister removes a model by name, reporting whether it was present
+func (r *ModelRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.removeLocked(name)
+}
+
+func (r *ModelRegistry) removeLocked(name string) bool {
+	for i, e := range r.entries {
+		if e.model.Name() == name {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clone returns a copy of the registry that can be changed independently
+func (r *ModelRegistry) Clone() *ModelRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &ModelRegistry{
+		entries: append([]registeredModel(nil), r.entries...),
+		seq:     r.seq,
+	}
+}
+
+// Models returns the registered models in the order they are consulted
+func (r *ModelRegistry) Models() []PricingModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	models := make([]PricingModel, len(r.entries))
+	for i, e := range r.entries {
+		models[i] = e.model
+	}
+	return models
+}
+
+// Price returns the quote from the first registered model that applies
+func (r *ModelRegistry) Price(input PricingInput) (PricingQuote, bool) {
+	return Chain(r.Models()).Price(input)
+}
+
+// Chain is a PricingModel that returns the quote of the first model that applies
+type Chain []PricingModel
+
+// Name implements PricingModel
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, m := range c {
+		names[i] = m.Name()
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+// Price implements PricingModel
+func (c Chain) Price(input PricingInput) (PricingQuote, bool) {
+	for _, m := range c {
+		if quote, ok := m.Price(input); ok {
+			if quote.Model == "" {
+				quote.Model = m.Name()
+			}
+			return quote, true
+		}
+	}
+	return PricingQuote{}, false
+}
+
+// Ensemble is a PricingModel that blends every applicable model, weighting each quote
+// by its model weight times its confidence
+type Ensemble struct {
+	ModelName string
+	Models    []PricingModel
+	Weights   []float64 // Per-model weights; missing entries default to 1
+}
+
+// Name implements PricingModel
+func (e Ensemble) Name() string {
+	if e.ModelName != "" {
+		return e.ModelName
+	}
+	return "ensemble"
+}
+
+// Price implements PricingModel
+func (e Ensemble) Price(input PricingInput) (PricingQuote, bool) {
+	var (
+		blended      PricingQuote
+		noBid        bool
+		totalWeight  float64
+		baseWeight   float64
+		explanations []string
+		steps        []PricingStep
+	)
+
+	for i, m := range e.Models {
+		quote, ok := m.Price(input)
+		if !ok {
+			continue
+		}
+
+		w := 1.0
+		if i < len(e.Weights) {
+			w = e.Weights[i]
+		}
+		if w <= 0 {
+			continue
+		}
+
+		effective := w * quote.Confidence
+		if effective <= 0 {
+			// Zero-confidence quotes still count, just barely
+			effective = w * 1e-6
+		}
+
+		blended.FairValue += effective * quote.FairValue
+		blended.Bid += effective * quote.Bid
+		noBid = noBid || quote.Bid <= 0
+		blended.Ask += effective * quote.Ask
+		blended.Confidence += w * quote.Confidence
+		totalWeight += effective
+		baseWeight += w
+		explanations = append(explanations, m.Name()+": "+quote.Explanation)
+		steps = append(steps, PricingStep{
+			Stage:     "member",
+			Source:    m.Name(),
+			FairValue: quote.FairValue,
+			Bid:       quote.Bid,
+			Ask:       quote.Ask,
+			Note:      quote.Explanation,
+			Values:    map[string]float64{"weight": w, "confidence": quote.Confidence},
+		})
+	}
+
+	if totalWeight == 0 {
+		return PricingQuote{}, false
+	}
+
+	blended.Model = e.Name()
+	blended.FairValue /= totalWeight
+	blended.Bid /= totalWeight
+	if noBid {
+		// A member that will not bid at any price vetoes the blend's bid
+		blended.Bid = 0
+	}
+	blended.Ask /= totalWeight
+	blended.Confidence /= baseWeight
+	blended.Explanation = strings.Join(explanations, "; ")
+	blended.Steps = steps
+	blended = blended.withStep("ensemble", e.Name(), "confidence-weighted blend", nil)
+	return blended, true
+}
+
+// CategoryModel prices every market of one category with a band function
+type CategoryModel struct {
+	ModelName  string
+	Category   MarketCategory
+	Confidence float64
+	PriceFunc  func(question string) (float64, float64, string) // Returns (bid, ask, reasoning)
+}
+
+// Name implements PricingModel
+func (m CategoryModel) Name() string {
+	return m.ModelName
+}
+
+// Price implements PricingModel
+func (m CategoryModel) Price(input PricingInput) (PricingQuote, bool) {
+	if input.Category != m.Category {
+		return PricingQuote{}, false
+	}
+
+	bid, ask, reasoning := m.PriceFunc(input.Market.Question)
+	return PricingQuote{
+		Model:       m.ModelName,
+		FairValue:   (bid + ask) / 2,
+		Bid:         bid,
+		Ask:         ask,
+		Confidence:  m.Confidence,
+		Explanation: reasoning,
+	}, true
+}
+
+// fallbackModel applies to every market and quotes a conservative wide spread
+type fallbackModel struct{}
+
+func (fallbackModel) Name() string {
+	return "fallback"
+}
+
+func (fallbackModel) Price(input PricingInput) (PricingQuote, bool) {
+	return PricingQuote{
+		Model:       "fallback",
+		FairValue:   0.20,
+		Bid:         0.10,
+		Ask:         0.30,
+		Confidence:  0.1,
+		Explanation: "Unknown category - using conservative wide spread (10-30%)",
+	}, true
+}
@@ -0,0 +1,173 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+)
+
+// Probability ranges for the data-driven categories
+const (
+	LongshotMaxProbability    = 0.05 // Below this a market is a longshot
+	CompetitiveMinProbability = 0.40 // Between these a market is competitive
+	CompetitiveMaxProbability = 0.60
+)
+
+// Observation is a probability estimate derived from market data rather than question text
+type Observation struct {
+	Probability float64
+	Source      string  // "last trade", "outcome prices" or "event field size"
+	Confidence  float64 // How much the source says about fair value
+}
+
+// ObserveProbability estimates a market's YES probability from its last trade,
+// its outcome prices and, failing those, the number of sibling markets in its event.
+// Returns false when there is no usable data.
+func ObserveProbability(market Market, event *EventContext) (Observation, bool) {
+	if market.LastTradePrice > 0 && market.LastTradePrice < 1 {
+		return Observation{Probability: market.LastTradePrice, Source: "last trade", Confidence: 0.5}, true
+	}
+
+	// Gamma reports 0.5/0.5 for markets that never traded against a placeholder book,
+	// which says nothing about the outcome
+	if prices := parseOutcomePrices(market.OutcomePrices); len(prices) > 0 &&
+		prices[0] > 0 && prices[0] < 1 && prices[0] != 0.5 {
+		return Observation{Probability: prices[0], Source: "outcome prices", Confidence: 0.4}, true
+	}
+
+	if event != nil && len(event.Markets) > 1 {
+		return Observation{
+			Probability: 1 / float64(len(event.Markets)),
+			Source:      "event field size",
+			Confidence:  0.2,
+		}, true
+	}
+
+	return Observation{}, false
+}
+
+// MarketClassification is how a market's category was chosen from its text and its data
+type MarketClassification struct {
+	Category MarketCategory
+	Text     Classification // The question text's classification
+	Observed *Observation   // The observation that overrode the text category; nil if none did
+}
+
+// ClassifyMarketData classifies a market from its question text and observed data.
+// Markets whose observed probability is a longshot or near a coin flip are assigned
+// CategoryLongshot or CategoryCompetitive; otherwise the text category is used. The event
+// field size is only a guess, so it picks the category only when no text rule matched.
+func (ps *PricingStrategy) ClassifyMarketData(market Market, event *EventContext) MarketClassification {
+	text := ps.ClassifyMarket(market)
+	result := MarketClassification{Category: text.Category, Text: text}
+
+	obs, ok := ObserveProbability(market, event)
+	if !ok || (obs.Source == "event field size" && text.Category != CategoryUnknown) {
+		return result
+	}
+
+	switch {
+	case obs.Probability < LongshotMaxProbability:
+		result.Category = CategoryLongshot
+	case obs.Probability >= CompetitiveMinProbability && obs.Probability <= CompetitiveMaxProbability:
+		// A field-size estimate can only say a market is unlikely, not that it is a coin flip
+		if obs.Source == "event field size" {
+			return result
+		}
+		result.Category = CategoryCompetitive
+	default:
+		return result
+	}
+	result.Observed = &obs
+	return result
+}
+
+// CategorizeMarketData returns the category ClassifyMarketData chooses
+func (ps *PricingStrategy) CategorizeMarketData(market Market, event *EventContext) MarketCategory {
+	return ps.ClassifyMarketData(market, event).Category
+}
+
+// longshotTick is the finest tick Polymarket offers on cheap tokens
+const longshotTick = 0.001
+
+// LongshotModel quotes a tight band around the observed probability of a longshot,
+// with the ask further from fair value than the bid: buyers of longshots are the side
+// most likely to know something we do not
+type LongshotModel struct{}
+
+// Name implements PricingModel
+func (LongshotModel) Name() string {
+	return "longshot"
+}
+
+// Price implements PricingModel
+func (LongshotModel) Price(input PricingInput) (PricingQuote, bool) {
+	if input.Category != CategoryLongshot {
+		return PricingQuote{}, false
+	}
+
+	obs, ok := ObserveProbability(input.Market, input.Event)
+	if !ok {
+		return PricingQuote{}, false
+	}
+
+	fair := math.Min(obs.Probability, LongshotMaxProbability)
+	// The bid stays strictly below fair; at a fair value of one tick or less there is no
+	// price to bid at
+	bid := math.Max(longshotTick, floorToTick(fair*0.6, longshotTick))
+	if bid >= fair-probabilityEpsilon {
+		bid = 0
+	}
+	ask := math.Max(bid+longshotTick, ceilToTick(fair*1.8, longshotTick))
+
+	explanation := fmt.Sprintf("Longshot at %.1f%% (%s) - bid 40%% below, ask 80%% above fair", fair*100, obs.Source)
+	if bid == 0 {
+		explanation = fmt.Sprintf("Longshot at %.1f%% (%s) - no bid below fair, ask 80%% above", fair*100, obs.Source)
+	}
+	return PricingQuote{
+		Model:       "longshot",
+		FairValue:   fair,
+		Bid:         bid,
+		Ask:         ask,
+		Confidence:  obs.Confidence,
+		Explanation: explanation,
+	}, true
+}
+
+// competitiveTick is the standard Polymarket tick for mid-range prices
+const competitiveTick = 0.01
+
+// competitiveHalfWidth is how far either side of mid a competitive quote sits
+const competitiveHalfWidth = 0.05
+
+// CompetitiveModel quotes a symmetric band around the observed mid of a near coin-flip market
+type CompetitiveModel struct{}
+
+// Name implements PricingModel
+func (CompetitiveModel) Name() string {
+	return "competitive"
+}
+
+// Price implements PricingModel
+func (CompetitiveModel) Price(input PricingInput) (PricingQuote, bool) {
+	if input.Category != CategoryCompetitive {
+		return PricingQuote{}, false
+	}
+
+	obs, ok := ObserveProbability(input.Market, input.Event)
+	if !ok {
+		return PricingQuote{}, false
+	}
+
+	mid := obs.Probability
+	bid := floorToTick(mid-competitiveHalfWidth, competitiveTick)
+	ask := ceilToTick(mid+competitiveHalfWidth, competitiveTick)
+
+	return PricingQuote{
+		Model:       "competitive",
+		FairValue:   mid,
+		Bid:         bid,
+		Ask:         ask,
+		Confidence:  obs.Confidence,
+		Explanation: fmt.Sprintf("Competitive market at %.0f%% (%s) - symmetric 5¢ each side", mid*100, obs.Source),
+	}, true
+}
@@ -0,0 +1,49 @@
+package marketmaker
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCategorizeMarketDataFieldSizeDefersToText(t *testing.T) {
+	event := &EventContext{Title: "Super Bowl 2026"}
+	for i := 0; i < 32; i++ {
+		event.Markets = append(event.Markets, Market{Question: fmt.Sprintf("Will team %d win Super Bowl 2026?", i)})
+	}
+	ps := &PricingStrategy{}
+
+	// 1/32 is a longshot guess, but the Chiefs rule knows better
+	chiefs := Market{Question: "Will the Kansas City Chiefs win Super Bowl 2026?"}
+	if got := ps.CategorizeMarketData(chiefs, event); got != CategorySports {
+		t.Errorf("Chiefs categorized %s, want the text rule's sports", got)
+	}
+	unknown := Market{Question: "Will the Zorblax Quintet win it all?"}
+	if got := ps.CategorizeMarketData(unknown, event); got != CategoryLongshot {
+		t.Errorf("unmatched question categorized %s, want the field size's longshot", got)
+	}
+	traded := Market{Question: chiefs.Question, LastTradePrice: 0.02}
+	if got := ps.CategorizeMarketData(traded, event); got != CategoryLongshot {
+		t.Errorf("Chiefs trading at 2%% categorized %s, want longshot", got)
+	}
+}
+
+func TestLongshotModelBidsBelowFair(t *testing.T) {
+	for _, price := range []float64{0.001, 0.0015, 0.002, 0.01, 0.04} {
+		quote, ok := LongshotModel{}.Price(PricingInput{
+			Market:   Market{Question: "Will it happen?", LastTradePrice: price},
+			Category: CategoryLongshot,
+		})
+		if !ok {
+			t.Fatalf("no quote at %v", price)
+		}
+		if quote.Bid != 0 && (quote.Bid < longshotTick-1e-9 || quote.Bid >= quote.FairValue) {
+			t.Errorf("fair %v: bid %v not on the grid below fair", quote.FairValue, quote.Bid)
+		}
+		if quote.Ask <= quote.FairValue {
+			t.Errorf("fair %v: ask %v not above fair", quote.FairValue, quote.Ask)
+		}
+		if price <= longshotTick && quote.Bid != 0 {
+			t.Errorf("fair %v: bid %v, want no bid at one tick", quote.FairValue, quote.Bid)
+		}
+	}
+}
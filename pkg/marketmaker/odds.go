@@ -0,0 +1,548 @@
+package marketmaker
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Odds is a bookmaker price expressed as decimal odds (total return per unit staked)
+type Odds struct {
+	Decimal float64
+}
+
+// ParseOdds parses American ("+10000", "-150"), fractional ("5/2", "evens") or
+// decimal ("3.5") odds
+func ParseOdds(s string) (Odds, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Odds{}, errors.New("empty odds")
+	}
+
+	switch strings.ToLower(s) {
+	case "evens", "evs", "even":
+		return Odds{Decimal: 2}, nil
+	}
+
+	if s[0] == '+' || s[0] == '-' {
+		american, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Odds{}, fmt.Errorf("invalid American odds %q: %w", s, err)
+		}
+		return AmericanOdds(american)
+	}
+
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		if err != nil {
+			return Odds{}, fmt.Errorf("invalid fractional odds %q: %w", s, err)
+		}
+		d, err := strconv.ParseFloat(strings.TrimSpace(den), 64)
+		if err != nil {
+			return Odds{}, fmt.Errorf("invalid fractional odds %q: %w", s, err)
+		}
+		return FractionalOdds(n, d)
+	}
+
+	decimal, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Odds{}, fmt.Errorf("invalid decimal odds %q: %w", s, err)
+	}
+	return DecimalOdds(decimal)
+}
+
+// AmericanOdds converts moneyline odds: +150 pays 150 per 100 staked, -150 needs 150 to win 100
+func AmericanOdds(american float64) (Odds, error) {
+	switch {
+	case american >= 100:
+		return Odds{Decimal: 1 + american/100}, nil
+	case american <= -100:
+		return Odds{Decimal: 1 + 100/-american}, nil
+	default:
+		return Odds{}, fmt.Errorf("American odds must be <= -100 or >= +100, got %v", american)
+	}
+}
+
+// FractionalOdds converts UK-style odds: 5/2 pays 5 per 2 staked
+func FractionalOdds(numerator, denominator float64) (Odds, error) {
+	if numerator <= 0 || denominator <= 0 {
+		return Odds{}, fmt.Errorf("fractional odds must be positive, got %v/%v", numerator, denominator)
+	}
+	return Odds{Decimal: 1 + numerator/denominator}, nil
+}
+
+// DecimalOdds validates European-style odds
+func DecimalOdds(decimal float64) (Odds, error) {
+	if decimal <= 1 || math.IsInf(decimal, 0) || math.IsNaN(decimal) {
+		return Odds{}, fmt.Errorf("decimal odds must be greater than 1, got %v", decimal)
+	}
+	return Odds{Decimal: decimal}, nil
+}
+
+// ImpliedProbability is the bookmaker's probability including their margin
+func (o Odds) ImpliedProbability() float64 {
+	return 1 / o.Decimal
+}
+
+// DevigMethod selects how the bookmaker margin is removed from a book
+type DevigMethod int
+
+const (
+	DevigProportional DevigMethod = iota // Scale every probability by the same factor
+	DevigShin                            // Shin's model: margin comes from insider trading, hitting longshots hardest
+	DevigPower                           // Raise probabilities to a common power, also shrinking longshots most
+)
+
+// String returns the method name used in odds files
+func (m DevigMethod) String() string {
+	switch m {
+	case DevigShin:
+		return "shin"
+	case DevigPower:
+		return "power"
+	default:
+		return "proportional"
+	}
+}
+
+// ParseDevigMethod parses a de-vig method name
+func ParseDevigMethod(name string) (DevigMethod, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "proportional", "multiplicative":
+		return DevigProportional, nil
+	case "shin":
+		return DevigShin, nil
+	case "power":
+		return DevigPower, nil
+	default:
+		return DevigProportional, fmt.Errorf("unknown de-vig method %q", name)
+	}
+}
+
+// Overround is the bookmaker margin of a full book: the sum of implied probabilities minus one
+func Overround(implied []float64) float64 {
+	sum := 0.0
+	for _, q := range implied {
+		sum += q
+	}
+	return sum - 1
+}
+
+// RemoveVig converts the implied probabilities of every outcome in a book into fair
+// probabilities summing to one
+func RemoveVig(implied []float64, method DevigMethod) ([]float64, error) {
+	if len(implied) < 2 {
+		return nil, errors.New("need at least two outcomes to remove vig")
+	}
+	for _, q := range implied {
+		if q <= 0 || q >= 1 {
+			return nil, fmt.Errorf("implied probability %v outside (0,1)", q)
+		}
+	}
+
+	switch method {
+	case DevigShin:
+		return devigShin(implied), nil
+	case DevigPower:
+		return devigPower(implied), nil
+	default:
+		return devigProportional(implied), nil
+	}
+}
+
+func devigProportional(implied []float64) []float64 {
+	total := 1 + Overround(implied)
+	fair := make([]float64, len(implied))
+	for i, q := range implied {
+		fair[i] = q / total
+	}
+	return fair
+}
+
+// devigPower finds k such that the implied probabilities raised to k sum to one
+func devigPower(implied []float64) []float64 {
+	sumPow := func(k float64) float64 {
+		sum := 0.0
+		for _, q := range implied {
+			sum += math.Pow(q, k)
+		}
+		return sum
+	}
+
+	// Sum of powers decreases in k; bracket the root before bisecting
+	lo, hi := 0.01, 1.0
+	for sumPow(hi) > 1 {
+		hi *= 2
+	}
+	k := bisect(lo, hi, func(k float64) bool { return sumPow(k) > 1 })
+
+	fair := make([]float64, len(implied))
+	for i, q := range implied {
+		fair[i] = math.Pow(q, k)
+	}
+	return normalize(fair)
+}
+
+// devigShin solves for the insider proportion z in Shin (1993)
+func devigShin(implied []float64) []float64 {
+	total := 1 + Overround(implied)
+	shin := func(z float64) []float64 {
+		fair := make([]float64, len(implied))
+		for i, q := range implied {
+			fair[i] = (math.Sqrt(z*z+4*(1-z)*q*q/total) - z) / (2 * (1 - z))
+		}
+		return fair
+	}
+	sum := func(z float64) float64 {
+		s := 0.0
+		for _, p := range shin(z) {
+			s += p
+		}
+		return s
+	}
+
+	if total <= 1 {
+		// An underround book has no insider share to remove
+		return devigProportional(implied)
+	}
+
+	z := bisect(0, 0.999, func(z float64) bool { return sum(z) > 1 })
+	return normalize(shin(z))
+}
+
+// bisect finds the boundary in [lo, hi] where tooLow flips from true to false
+func bisect(lo, hi float64, tooLow func(float64) bool) float64 {
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if tooLow(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// normalize rescales values to sum to one, absorbing rounding error
+func normalize(values []float64) []float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	if sum > 0 {
+		for i := range values {
+			values[i] /= sum
+		}
+	}
+	return values
+}
+
+// OddsLine is one row of an external odds file
+type OddsLine struct {
+	Event     string  `json:"event"`     // Outcomes sharing an event form one book
+	Question  string  `json:"question"`  // Polymarket question the line prices
+	Entity    string  `json:"entity"`    // Entity ID the line prices, for questions linked by an EntityResolver
+	Odds      string  `json:"odds"`      // American, fractional or decimal
+	Source    string  `json:"source"`    // e.g. "DraftKings"
+	Overround float64 `json:"overround"` // Assumed margin when the file does not hold the full book
+}
+
+// ExternalProbability is a de-vigged external probability for a Polymarket question
+type ExternalProbability struct {
+	Question  string
+	Entity    string
+	Event     string
+	Source    string
+	Implied   float64 // Bookmaker probability including margin
+	Fair      float64 // Probability after removing the margin
+	Overround float64 // Margin of the book the line came from
+	Method    DevigMethod
+}
+
+// OddsBook holds external probabilities keyed by Polymarket question and by entity
+type OddsBook struct {
+	byQuestion map[string]ExternalProbability
+	byEntity   map[string][]ExternalProbability
+}
+
+// NewOddsBook de-vigs the lines event by event. Events with every outcome listed are
+// de-vigged as a full book; single lines and incomplete books use each line's assumed overround.
+func NewOddsBook(lines []OddsLine, method DevigMethod) (*OddsBook, error) {
+	book := &OddsBook{
+		byQuestion: make(map[string]ExternalProbability),
+		byEntity:   make(map[string][]ExternalProbability),
+	}
+
+	var order []string
+	events := make(map[string][]OddsLine)
+	for _, line := range lines {
+		key := line.Event
+		if key == "" {
+			key = line.Question + line.Entity
+		}
+		if _, ok := events[key]; !ok {
+			order = append(order, key)
+		}
+		events[key] = append(events[key], line)
+	}
+
+	for _, event := range order {
+		eventLines := events[event]
+		implied := make([]float64, len(eventLines))
+		for i, line := range eventLines {
+			odds, err := ParseOdds(line.Odds)
+			if err != nil {
+				return nil, fmt.Errorf("event %q, line %q: %w", event, line.Question+line.Entity, err)
+			}
+			implied[i] = odds.ImpliedProbability()
+		}
+
+		// A book whose implied probabilities sum below one is missing outcomes; de-vigging
+		// it would inflate every line, so each line falls back to its assumed overround
+		fair := make([]float64, len(implied))
+		overrounds := make([]float64, len(implied))
+		if len(eventLines) > 1 && Overround(implied) >= 0 {
+			var err error
+			if fair, err = RemoveVig(implied, method); err != nil {
+				return nil, fmt.Errorf("event %q: %w", event, err)
+			}
+			for i := range overrounds {
+				overrounds[i] = Overround(implied)
+			}
+		} else {
+			for i, line := range eventLines {
+				overrounds[i] = line.Overround
+				fair[i] = implied[i] / (1 + line.Overround)
+			}
+		}
+
+		for i, line := range eventLines {
+			source := line.Source
+			if source == "" {
+				source = "external odds"
+			}
+			ext := ExternalProbability{
+				Question:  line.Question,
+				Entity:    line.Entity,
+				Event:     event,
+				Source:    source,
+				Implied:   implied[i],
+				Fair:      fair[i],
+				Overround: overrounds[i],
+				Method:    method,
+			}
+			if line.Question != "" {
+				book.byQuestion[normalizeQuestion(line.Question)] = ext
+			}
+			if line.Entity != "" {
+				book.byEntity[line.Entity] = append(book.byEntity[line.Entity], ext)
+			}
+		}
+	}
+
+	return book, nil
+}
+
+// LoadOddsFile reads a .csv or .json odds file and de-vigs it. CSV files need a header
+// row naming the event, question, odds, source and (optionally) overround columns.
+func LoadOddsFile(path string, method DevigMethod) (*OddsBook, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open odds file: %w", err)
+	}
+	defer f.Close()
+
+	var lines []OddsLine
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		lines, err = readOddsJSON(f)
+	case ".csv":
+		lines, err = readOddsCSV(f)
+	default:
+		return nil, fmt.Errorf("unsupported odds file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return NewOddsBook(lines, method)
+}
+
+func readOddsJSON(r io.Reader) ([]OddsLine, error) {
+	var lines []OddsLine
+	if err := json.NewDecoder(r).Decode(&lines); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal odds: %w", err)
+	}
+	return lines, nil
+}
+
+func readOddsCSV(r io.Reader) ([]OddsLine, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read odds CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["odds"]; !ok {
+		return nil, errors.New("odds CSV is missing the \"odds\" column")
+	}
+	_, hasQuestion := columns["question"]
+	_, hasEntity := columns["entity"]
+	if !hasQuestion && !hasEntity {
+		return nil, errors.New("odds CSV needs a \"question\" or \"entity\" column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	lines := make([]OddsLine, 0, len(records)-1)
+	for n, record := range records[1:] {
+		line := OddsLine{
+			Event:    field(record, "event"),
+			Question: field(record, "question"),
+			Entity:   field(record, "entity"),
+			Odds:     field(record, "odds"),
+			Source:   field(record, "source"),
+		}
+		if raw := field(record, "overround"); raw != "" {
+			if line.Overround, err = strconv.ParseFloat(raw, 64); err != nil {
+				return nil, fmt.Errorf("row %d: invalid overround %q: %w", n+2, raw, err)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// Lookup returns the external probability for a Polymarket question
+func (b *OddsBook) Lookup(question string) (ExternalProbability, bool) {
+	if b == nil {
+		return ExternalProbability{}, false
+	}
+	p, ok := b.byQuestion[normalizeQuestion(question)]
+	return p, ok
+}
+
+// LookupEntity returns the external probability for an entity. When the entity has
+// lines in several events, the event whose name appears in eventHint is preferred.
+func (b *OddsBook) LookupEntity(entityID, eventHint string) (ExternalProbability, bool) {
+	if b == nil {
+		return ExternalProbability{}, false
+	}
+
+	lines := b.byEntity[entityID]
+	switch len(lines) {
+	case 0:
+		return ExternalProbability{}, false
+	case 1:
+		return lines[0], true
+	}
+
+	hint := newQuestionText(eventHint)
+	for _, line := range lines {
+		if words := tokenize(line.Event); len(words) > 0 && hint.contains(keywordPhrase{words: words}) {
+			return line, true
+		}
+	}
+	return ExternalProbability{}, false
+}
+
+// Len returns the number of lines in the book
+func (b *OddsBook) Len() int {
+	n := len(b.byQuestion)
+	for _, lines := range b.byEntity {
+		for _, line := range lines {
+			if line.Question == "" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// normalizeQuestion makes question lookups insensitive to case, punctuation and spacing
+func normalizeQuestion(question string) string {
+	return strings.Join(tokenize(question), " ")
+}
+
+// defaultAnchorHalfWidth is the log-odds distance from fair value to each side of an
+// anchored quote; 0.4 turns a 0.8% fair value into roughly 0.5%/1.2%
+const defaultAnchorHalfWidth = 0.4
+
+// OddsAnchorModel quotes around de-vigged external probabilities
+type OddsAnchorModel struct {
+	Book       *OddsBook
+	Resolver   *EntityResolver // Links questions to entity lines; nil matches by question only
+	HalfWidth  float64         // Log-odds distance from fair to bid and ask (default 0.4)
+	Confidence float64         // Confidence reported with anchored quotes (default 0.8)
+}
+
+// Name implements PricingModel
+func (m OddsAnchorModel) Name() string {
+	return "odds-anchor"
+}
+
+// Price implements PricingModel
+func (m OddsAnchorModel) Price(input PricingInput) (PricingQuote, bool) {
+	ext, ok := m.Book.Lookup(input.Market.Question)
+	if !ok && m.Resolver != nil {
+		if res := m.Resolver.Resolve(input.Market.Question); res.Entity != nil {
+			ext, ok = m.Book.LookupEntity(res.Entity.ID, input.Market.Question)
+		}
+	}
+	if !ok {
+		return PricingQuote{}, false
+	}
+
+	halfWidth := m.HalfWidth
+	if halfWidth <= 0 {
+		halfWidth = defaultAnchorHalfWidth
+	}
+	confidence := m.Confidence
+	if confidence <= 0 {
+		confidence = 0.8
+	}
+
+	// Both sides stay strictly inside (0, 1), as the exchange requires
+	center := logit(ext.Fair)
+	tick := tickSizeFor(ext.Fair)
+	bid := math.Min(1-2*tick, math.Max(tick, floorToTick(logistic(center-halfWidth), tick)))
+	ask := math.Max(bid+tick, math.Min(1-tick, ceilToTick(logistic(center+halfWidth), tick)))
+
+	return PricingQuote{
+		Model:      "odds-anchor",
+		FairValue:  ext.Fair,
+		Bid:        bid,
+		Ask:        ask,
+		Confidence: confidence,
+		Explanation: fmt.Sprintf("Anchored to %s: %.2f%% implied, %.2f%% after %s de-vig (%.1f%% overround)",
+			ext.Source, ext.Implied*100, ext.Fair*100, ext.Method, ext.Overround*100),
+	}, true
+}
+
+// AnchorToOdds makes the strategy quote around external probabilities wherever the
+// book has a line, ahead of every other model. The resolver, if not nil, links
+// questions to lines keyed by entity.
+func (ps *PricingStrategy) AnchorToOdds(book *OddsBook, resolver *EntityResolver) {
+	if ps.Models == nil {
+		ps.Models = DefaultModels.Clone()
+	}
+	ps.Models.Register(OddsAnchorModel{Book: book, Resolver: resolver}, PriorityOverride)
+}
@@ -0,0 +1,21 @@
+package marketmaker
+
+import "testing"
+
+func TestOddsAnchorModelQuotesInsideBounds(t *testing.T) {
+	for _, odds := range []string{"-1000000", "-100000", "+100000", "+1000000"} {
+		book, err := NewOddsBook([]OddsLine{{Question: "Will it happen?", Odds: odds, Source: "test"}}, DevigProportional)
+		if err != nil {
+			t.Fatalf("NewOddsBook: %v", err)
+		}
+		quote, ok := OddsAnchorModel{Book: book}.Price(PricingInput{Market: Market{Question: "Will it happen?"}})
+		if !ok {
+			t.Fatalf("odds %s: no quote", odds)
+		}
+		tick := tickSizeFor(quote.FairValue)
+		if quote.Bid < tick || quote.Ask > 1-tick+1e-9 || quote.Bid > 1-2*tick+1e-9 || quote.Ask < quote.Bid+tick-1e-9 {
+			t.Errorf("odds %s: quote %.4f/%.4f at fair %.4f is not inside (0, 1) on a %.3f grid",
+				odds, quote.Bid, quote.Ask, quote.FairValue, tick)
+		}
+	}
+}
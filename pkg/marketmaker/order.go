@@ -0,0 +1,355 @@
+package marketmaker
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// ZeroAddress is the taker of a public order: anyone may fill it
+const ZeroAddress = "0x0000000000000000000000000000000000000000"
+
+// Exchange contracts orders are signed against, by chain
+var (
+	ctfExchanges = map[int64]string{
+		PolygonChainID: "0x4bFb41d5B3570DeFd03C39a9A4D8dE6Bd8B8982E",
+		AmoyChainID:    "0xdFE02Eb6733538f8Ea35D585af8DE5958AD99E40",
+	}
+	negRiskExchanges = map[int64]string{
+		PolygonChainID: "0xC5d563A36AE78145C45a50134d48A1215220f80a",
+		AmoyChainID:    "0xd91E80cF2E7be2e162c6513ceD06f1dD0dA35296",
+	}
+)
+
+// ExchangeAddress returns the exchange contract for a chain. Markets in a negative-risk
+// event settle through a separate exchange, and an order signed for the wrong one is rejected.
+func ExchangeAddress(chainID int64, negRisk bool) (string, error) {
+	exchanges := ctfExchanges
+	if negRisk {
+		exchanges = negRiskExchanges
+	}
+	addr, ok := exchanges[chainID]
+	if !ok {
+		return "", fmt.Errorf("no exchange contract for chain %d", chainID)
+	}
+	return addr, nil
+}
+
+// orderTypeHash is the EIP-712 type hash of the exchange's Order struct
+var orderTypeHash = keccak256([]byte("Order(uint256 salt,address maker,address signer,address taker,uint256 tokenId," +
+	"uint256 makerAmount,uint256 takerAmount,uint256 expiration,uint256 nonce,uint256 feeRateBps,uint8 side,uint8 signatureType)"))
+
+// orderDomain is the EIP-712 domain of an exchange contract
+func orderDomain(chainID int64, negRisk bool) (EIP712Domain, error) {
+	exchange, err := ExchangeAddress(chainID, negRisk)
+	if err != nil {
+		return EIP712Domain{}, err
+	}
+	return EIP712Domain{Name: "Polymarket CTF Exchange", Version: "1", ChainID: chainID, VerifyingContract: exchange}, nil
+}
+
+// OrderHash is the EIP-712 digest of an order, the value its signature covers
+func OrderHash(o SignedOrder, chainID int64, negRisk bool) ([32]byte, error) {
+	var errs []error
+	uint256 := func(name, s string) [32]byte {
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+			errs = append(errs, fmt.Errorf("invalid %s %q", name, s))
+			return [32]byte{}
+		}
+		return abiUint(n)
+	}
+	address := func(name, s string) [32]byte {
+		word, err := abiAddress(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", name, err))
+		}
+		return word
+	}
+
+	var side int64
+	switch o.Side {
+	case SideBuy.String():
+	case SideSell.String():
+		side = 1
+	default:
+		errs = append(errs, fmt.Errorf("invalid side %q", o.Side))
+	}
+
+	fields := [][32]byte{
+		orderTypeHash,
+		uint256("salt", strconv.FormatInt(o.Salt, 10)),
+		address("maker", o.Maker),
+		address("signer", o.Signer),
+		address("taker", o.Taker),
+		uint256("token id", o.TokenID),
+		uint256("maker amount", o.MakerAmount),
+		uint256("taker amount", o.TakerAmount),
+		uint256("expiration", o.Expiration),
+		uint256("nonce", o.Nonce),
+		uint256("fee rate", o.FeeRateBps),
+		abiUint(big.NewInt(side)),
+		abiUint(big.NewInt(int64(o.SignatureType))),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return [32]byte{}, fmt.Errorf("failed to encode order: %w", err)
+	}
+
+	encoded := make([][]byte, len(fields))
+	for i := range fields {
+		encoded[i] = fields[i][:]
+	}
+	domain, err := orderDomain(chainID, negRisk)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return TypedDataHash(domain, keccak256(encoded...))
+}
+
+// VerifyOrderSignature checks that an order was signed by its signer for the given exchange
+func VerifyOrderSignature(o SignedOrder, chainID int64, negRisk bool) error {
+	hash, err := OrderHash(o, chainID, negRisk)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(o.Signature, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid order signature: %w", err)
+	}
+	signer, err := RecoverAddress(hash, sig)
+	if err != nil {
+		return err
+	}
+	if !SameAddress(signer, o.Signer) {
+		return fmt.Errorf("order signed by %s, not %s", signer, o.Signer)
+	}
+	return nil
+}
+
+// Side is the direction of an order or fill
+type Side int
+
+const (
+	SideBuy Side = iota
+	SideSell
+)
+
+// String returns the CLOB name of the side
+func (s Side) String() string {
+	if s == SideSell {
+		return "SELL"
+	}
+	return "BUY"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Side) UnmarshalText(text []byte) error {
+	switch strings.ToUpper(string(text)) {
+	case "BUY":
+		*s = SideBuy
+	case "SELL":
+		*s = SideSell
+	default:
+		return fmt.Errorf("unknown side %q", text)
+	}
+	return nil
+}
+
+// sign is +1 for buys and -1 for sells
+func (s Side) sign() float64 {
+	if s == SideSell {
+		return -1
+	}
+	return 1
+}
+
+// opposite is the side an order trades against
+func (s Side) opposite() Side {
+	if s == SideSell {
+		return SideBuy
+	}
+	return SideSell
+}
+
+// OrderArgs describes an order in market terms
+type OrderArgs struct {
+	TokenID    string
+	Side       Side
+	Price      float64 // Per share, on the market's tick grid
+	Size       float64 // Shares; rounded down to 0.01
+	Expiration int64   // Unix seconds for GTD orders, 0 for none
+	FeeRateBps int
+	Nonce      uint64 // Exchange nonce; bumping it on-chain cancels every order signed with the old one
+	Taker      string // Empty for a public order
+}
+
+// OrderOptions are the market's order constraints
+type OrderOptions struct {
+	TickSize float64 // 0.1, 0.01, 0.001 or 0.0001
+	MinSize  float64 // Smallest order size in shares
+	NegRisk  bool    // Sign against the negative-risk exchange
+}
+
+// defaultTickSize is the CLOB's tick for prices between 4% and 96%
+const defaultTickSize = 0.01
+
+// OrderOptionsFor reads a market's order constraints
+func OrderOptionsFor(m Market) OrderOptions {
+	tick := m.TickSize
+	if tick <= 0 {
+		tick = defaultTickSize
+	}
+	return OrderOptions{TickSize: tick, MinSize: m.MinOrderSize, NegRisk: m.NegRisk}
+}
+
+// OrderOptions reads the order constraints of an opportunity, preferring the orderbook's
+// tick and minimum size, which are current, over the market listing's
+func (o Opportunity) OrderOptions() OrderOptions {
+	opts := OrderOptionsFor(o.Market)
+	if o.Book == nil {
+		return opts
+	}
+	if tick, err := parseFloat(o.Book.TickSize); err == nil && tick > 0 {
+		opts.TickSize = tick
+	}
+	if size, err := parseFloat(o.Book.MinOrderSize); err == nil && size > 0 {
+		opts.MinSize = size
+	}
+	return opts
+}
+
+// sizeDecimals is the precision of order sizes
+const sizeDecimals = 2
+
+// OrderAmounts converts a price and size into the order's maker and taker amounts in base
+// units. Prices are whole ticks and sizes whole hundredths, so the product is exact.
+func OrderAmounts(side Side, price, size, tickSize float64) (maker, taker int64, err error) {
+	priceDecimals, err := tickDecimals(tickSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ticks := math.Round(price * math.Pow10(priceDecimals))
+	if math.Abs(ticks/math.Pow10(priceDecimals)-price) > 1e-9 {
+		return 0, 0, fmt.Errorf("price %v is not on the %v tick grid", price, tickSize)
+	}
+	tickUnits := int64(math.Round(tickSize * math.Pow10(priceDecimals)))
+	if ticks < float64(tickUnits) || ticks > math.Pow10(priceDecimals)-float64(tickUnits) {
+		return 0, 0, fmt.Errorf("price %v outside [%v, %v]", price, tickSize, 1-tickSize)
+	}
+
+	// 1e-9 absorbs float noise such as 21.04*100 = 2103.9999999999995
+	hundredths := int64(math.Floor(size*math.Pow10(sizeDecimals) + 1e-9))
+	if hundredths <= 0 {
+		return 0, 0, fmt.Errorf("size %v rounds to zero", size)
+	}
+
+	// shares * 10^6 and shares * price * 10^6, with price = ticks / 10^priceDecimals
+	shares := hundredths * int64(tokenDecimals) / 100
+	notional := hundredths * int64(ticks) * int64(math.Pow10(6-sizeDecimals-priceDecimals))
+	if side == SideBuy {
+		return notional, shares, nil
+	}
+	return shares, notional, nil
+}
+
+// tickDecimals is the number of decimal places in a valid tick size
+func tickDecimals(tickSize float64) (int, error) {
+	for decimals := 1; decimals <= 4; decimals++ {
+		if math.Abs(tickSize-math.Pow10(-decimals)) < 1e-12 {
+			return decimals, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported tick size %v", tickSize)
+}
+
+// OrderBuilder turns order arguments into signed orders
+type OrderBuilder struct {
+	Wallet        *Wallet
+	ChainID       int64
+	Funder        string       // Address holding the funds, for proxy wallets; empty uses the wallet
+	SignatureType int          // 0 EOA, 1 Polymarket proxy, 2 Gnosis Safe
+	Salt          func() int64 // Order salt source (nil draws at random)
+}
+
+// NewOrderBuilder creates a builder that signs with the wallet for the given chain
+func NewOrderBuilder(wallet *Wallet, chainID int64) *OrderBuilder {
+	return &OrderBuilder{Wallet: wallet, ChainID: chainID}
+}
+
+// Build validates an order against the market's constraints and signs it
+func (b *OrderBuilder) Build(args OrderArgs, opts OrderOptions) (SignedOrder, error) {
+	if args.TokenID == "" {
+		return SignedOrder{}, errors.New("failed to build order: missing token id")
+	}
+	size := math.Floor(args.Size*100+1e-9) / 100
+	if opts.MinSize > 0 && size < opts.MinSize {
+		return SignedOrder{}, fmt.Errorf("failed to build order: size %.2f below minimum %.2f", size, opts.MinSize)
+	}
+	maker, taker, err := OrderAmounts(args.Side, args.Price, size, opts.TickSize)
+	if err != nil {
+		return SignedOrder{}, fmt.Errorf("failed to build order: %w", err)
+	}
+
+	funder := b.Funder
+	if funder == "" {
+		funder = b.Wallet.Address()
+	}
+	takerAddr := args.Taker
+	if takerAddr == "" {
+		takerAddr = ZeroAddress
+	}
+	salt := b.Salt
+	if salt == nil {
+		// Kept below 2^53 so JSON clients that parse numbers as doubles see it exactly
+		salt = func() int64 { return rand.Int64N(1 << 53) }
+	}
+
+	order := SignedOrder{
+		Salt:          salt(),
+		Maker:         funder,
+		Signer:        b.Wallet.Address(),
+		Taker:         takerAddr,
+		TokenID:       args.TokenID,
+		MakerAmount:   strconv.FormatInt(maker, 10),
+		TakerAmount:   strconv.FormatInt(taker, 10),
+		Expiration:    strconv.FormatInt(args.Expiration, 10),
+		Nonce:         strconv.FormatUint(args.Nonce, 10),
+		FeeRateBps:    strconv.Itoa(args.FeeRateBps),
+		Side:          args.Side.String(),
+		SignatureType: b.SignatureType,
+	}
+
+	hash, err := OrderHash(order, b.ChainID, opts.NegRisk)
+	if err != nil {
+		return SignedOrder{}, fmt.Errorf("failed to build order: %w", err)
+	}
+	order.Signature = "0x" + hex.EncodeToString(b.Wallet.SignHash(hash))
+	return order, nil
+}
+
+// BuildQuote signs a two-sided quote on an opportunity's token at its suggested prices,
+// snapped outward to the tick grid: a bid to buy and an ask to sell size shares
+func (b *OrderBuilder) BuildQuote(opp Opportunity, size float64) (bid, ask SignedOrder, err error) {
+	opts := opp.OrderOptions()
+	bidPrice := math.Max(opts.TickSize, floorToTick(opp.SuggestedBuyPrice, opts.TickSize))
+	askPrice := math.Min(1-opts.TickSize, ceilToTick(opp.SuggestedSellPrice, opts.TickSize))
+
+	if bid, err = b.Build(OrderArgs{TokenID: opp.TokenID, Side: SideBuy, Price: bidPrice, Size: size}, opts); err != nil {
+		return SignedOrder{}, SignedOrder{}, err
+	}
+	if ask, err = b.Build(OrderArgs{TokenID: opp.TokenID, Side: SideSell, Price: askPrice, Size: size}, opts); err != nil {
+		return SignedOrder{}, SignedOrder{}, err
+	}
+	return bid, ask, nil
+}
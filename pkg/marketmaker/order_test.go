@@ -0,0 +1,199 @@
+package marketmaker
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+// TestTypedDataHashEIP712Vector checks the domain, digest and signature against the Mail
+// example published with EIP-712
+func TestTypedDataHashEIP712Vector(t *testing.T) {
+	domain := EIP712Domain{Name: "Ether Mail", Version: "1", ChainID: 1,
+		VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"}
+	separator, err := domain.Separator()
+	if err != nil {
+		t.Fatalf("Separator: %v", err)
+	}
+	if got, want := hex.EncodeToString(separator[:]), "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; got != want {
+		t.Errorf("separator %s, want %s", got, want)
+	}
+
+	personType := keccak256([]byte("Person(string name,address wallet)"))
+	person := func(name, wallet string) [32]byte {
+		nameHash := keccak256([]byte(name))
+		addr, err := abiAddress(wallet)
+		if err != nil {
+			t.Fatalf("abiAddress: %v", err)
+		}
+		return keccak256(personType[:], nameHash[:], addr[:])
+	}
+	mailType := keccak256([]byte("Mail(Person from,Person to,string contents)Person(string name,address wallet)"))
+	from := person("Cow", "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	to := person("Bob", "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")
+	contents := keccak256([]byte("Hello, Bob!"))
+	mail := keccak256(mailType[:], from[:], to[:], contents[:])
+	if got, want := hex.EncodeToString(mail[:]), "c52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"; got != want {
+		t.Errorf("struct hash %s, want %s", got, want)
+	}
+
+	digest, err := TypedDataHash(domain, mail)
+	if err != nil {
+		t.Fatalf("TypedDataHash: %v", err)
+	}
+	if got, want := hex.EncodeToString(digest[:]), "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; got != want {
+		t.Errorf("digest %s, want %s", got, want)
+	}
+
+	key := keccak256([]byte("cow"))
+	wallet, err := NewWallet(hex.EncodeToString(key[:]))
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	if got, want := wallet.Address(), "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"; got != want {
+		t.Errorf("address %s, want %s", got, want)
+	}
+	want := mustHex(t, "4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d"+
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562"+"1c")
+	if got := wallet.SignHash(digest); hex.EncodeToString(got) != hex.EncodeToString(want) {
+		t.Errorf("signature %x, want %x", got, want)
+	}
+	signer, err := RecoverAddress(digest, want)
+	if err != nil {
+		t.Fatalf("RecoverAddress: %v", err)
+	}
+	if signer != wallet.Address() {
+		t.Errorf("recovered %s, want %s", signer, wallet.Address())
+	}
+}
+
+// TestOrderTypeHash checks the Order type against ORDER_TYPEHASH in the exchange's
+// OrderStructs.sol
+func TestOrderTypeHash(t *testing.T) {
+	if got, want := hex.EncodeToString(orderTypeHash[:]), "a852566c4e14d00869b6db0220888a9090a13eccdaea03713ff0a3d27bf9767c"; got != want {
+		t.Errorf("order type hash %s, want %s", got, want)
+	}
+}
+
+func TestOrderSignatureDomains(t *testing.T) {
+	wallet, err := NewWallet("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	if got, want := wallet.Address(), "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"; got != want {
+		t.Fatalf("address %s, want %s", got, want)
+	}
+	builder := NewOrderBuilder(wallet, PolygonChainID)
+	builder.Salt = func() int64 { return 479249096354 }
+
+	for _, negRisk := range []bool{false, true} {
+		order, err := builder.Build(OrderArgs{TokenID: testTokenID, Side: SideBuy, Price: 0.5, Size: 100, FeeRateBps: 100},
+			OrderOptions{TickSize: 0.01, NegRisk: negRisk})
+		if err != nil {
+			t.Fatalf("negRisk=%v: Build: %v", negRisk, err)
+		}
+		if order.MakerAmount != "50000000" || order.TakerAmount != "100000000" {
+			t.Errorf("negRisk=%v: amounts %s/%s, want 50000000/100000000", negRisk, order.MakerAmount, order.TakerAmount)
+		}
+
+		hash, err := OrderHash(order, PolygonChainID, negRisk)
+		if err != nil {
+			t.Fatalf("negRisk=%v: OrderHash: %v", negRisk, err)
+		}
+		exchange, _ := ExchangeAddress(PolygonChainID, negRisk)
+		structHash := orderStructHash(t, order)
+		want, err := TypedDataHash(EIP712Domain{Name: "Polymarket CTF Exchange", Version: "1", ChainID: PolygonChainID,
+			VerifyingContract: exchange}, structHash)
+		if err != nil {
+			t.Fatalf("TypedDataHash: %v", err)
+		}
+		if hash != want {
+			t.Errorf("negRisk=%v: hash %x, want %x", negRisk, hash, want)
+		}
+
+		signer, err := RecoverAddress(hash, mustHex(t, order.Signature))
+		if err != nil {
+			t.Fatalf("negRisk=%v: RecoverAddress: %v", negRisk, err)
+		}
+		if signer != wallet.Address() {
+			t.Errorf("negRisk=%v: recovered %s, want %s", negRisk, signer, wallet.Address())
+		}
+		if err := VerifyOrderSignature(order, PolygonChainID, negRisk); err != nil {
+			t.Errorf("negRisk=%v: VerifyOrderSignature: %v", negRisk, err)
+		}
+		// An order signed for one exchange is not valid on the other
+		if err := VerifyOrderSignature(order, PolygonChainID, !negRisk); err == nil {
+			t.Errorf("negRisk=%v: order verified against the other exchange", negRisk)
+		}
+	}
+}
+
+// orderStructHash encodes an order's fields by hand, for comparison with OrderHash
+func orderStructHash(t *testing.T, o SignedOrder) [32]byte {
+	t.Helper()
+	word := func(hexWord string) []byte {
+		return mustHex(t, strings.Repeat("0", 64-len(hexWord))+hexWord)
+	}
+	addr := func(a string) []byte { return word(strings.ToLower(strings.TrimPrefix(a, "0x"))) }
+	// Salt 479249096354 and amounts as built above; side 0 is BUY, signature type 0 is EOA
+	return keccak256(orderTypeHash[:],
+		word("6f9578dea2"), addr(o.Maker), addr(o.Signer), addr(o.Taker),
+		word("4d2"), word("2faf080"), word("5f5e100"), word("0"), word("0"), word("64"),
+		word("0"), word("0"))
+}
+
+func TestOrderAmounts(t *testing.T) {
+	tests := []struct {
+		name         string
+		side         Side
+		price, size  float64
+		tick         float64
+		maker, taker int64
+		wantErr      bool
+	}{
+		{name: "tenth tick buy", side: SideBuy, price: 0.5, size: 10, tick: 0.1, maker: 5_000_000, taker: 10_000_000},
+		{name: "tenth tick lowest", side: SideSell, price: 0.1, size: 1, tick: 0.1, maker: 1_000_000, taker: 100_000},
+		{name: "tenth tick off grid", side: SideBuy, price: 0.55, size: 10, tick: 0.1, wantErr: true},
+		{name: "cent tick buy", side: SideBuy, price: 0.55, size: 21.04, tick: 0.01, maker: 11_572_000, taker: 21_040_000},
+		{name: "cent tick float noise", side: SideBuy, price: 0.57, size: 3, tick: 0.01, maker: 1_710_000, taker: 3_000_000},
+		{name: "cent tick off grid", side: SideBuy, price: 0.555, size: 10, tick: 0.01, wantErr: true},
+		{name: "cent tick highest", side: SideSell, price: 0.99, size: 2, tick: 0.01, maker: 2_000_000, taker: 1_980_000},
+		{name: "cent tick at zero", side: SideBuy, price: 0, size: 10, tick: 0.01, wantErr: true},
+		{name: "cent tick at one", side: SideSell, price: 1, size: 10, tick: 0.01, wantErr: true},
+		{name: "milli tick sell", side: SideSell, price: 0.123, size: 100, tick: 0.001, maker: 100_000_000, taker: 12_300_000},
+		{name: "milli tick off grid", side: SideSell, price: 0.1234, size: 100, tick: 0.001, wantErr: true},
+		{name: "milli tick below lowest", side: SideBuy, price: 0.0005, size: 100, tick: 0.001, wantErr: true},
+		{name: "tenth-mil tick lowest", side: SideBuy, price: 0.0001, size: 5, tick: 0.0001, maker: 500, taker: 5_000_000},
+		{name: "tenth-mil tick size floored", side: SideSell, price: 0.9999, size: 1.005, tick: 0.0001, maker: 1_000_000, taker: 999_900},
+		{name: "tenth-mil tick at one", side: SideSell, price: 1, size: 1, tick: 0.0001, wantErr: true},
+		{name: "size floors to zero", side: SideBuy, price: 0.5, size: 0.004, tick: 0.01, wantErr: true},
+		{name: "zero size", side: SideBuy, price: 0.5, size: 0, tick: 0.01, wantErr: true},
+		{name: "unsupported tick", side: SideBuy, price: 0.5, size: 10, tick: 0.05, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maker, taker, err := OrderAmounts(tt.side, tt.price, tt.size, tt.tick)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %d/%d, want an error", maker, taker)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OrderAmounts: %v", err)
+			}
+			if maker != tt.maker || taker != tt.taker {
+				t.Errorf("got maker %d taker %d, want %d %d", maker, taker, tt.maker, tt.taker)
+			}
+		})
+	}
+}
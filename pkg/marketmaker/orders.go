@@ -0,0 +1,667 @@
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exchange is a venue orders can be placed on. CLOBClient is the live exchange; a simulated
+// one lets the same quoting code run without real money.
+type Exchange interface {
+	PostOrder(ctx context.Context, order SignedOrder, orderType OrderType) (PostOrderResponse, error)
+	CancelOrders(ctx context.Context, orderIDs []string) (CancelResponse, error)
+	Order(ctx context.Context, orderID string) (OpenOrder, error)
+	OpenOrders(ctx context.Context, filter OrderFilter) ([]OpenOrder, error)
+	Trades(ctx context.Context, filter TradeFilter) ([]Trade, error)
+}
+
+// OrderSigner turns order arguments into a signed order; OrderBuilder is the usual one
+type OrderSigner interface {
+	Build(args OrderArgs, opts OrderOptions) (SignedOrder, error)
+}
+
+var (
+	_ Exchange    = (*CLOBClient)(nil)
+	_ OrderSigner = (*OrderBuilder)(nil)
+)
+
+// OrderStatus is where an order is in its lifecycle
+type OrderStatus int
+
+const (
+	StatusPending         OrderStatus = iota // Sent, not yet acknowledged
+	StatusLive                               // Resting on the book
+	StatusPartiallyFilled                    // Resting with some size matched
+	StatusFilled
+	StatusCancelled
+	StatusExpired
+	StatusRejected
+)
+
+// String returns the status name
+func (s OrderStatus) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusLive:
+		return "live"
+	case StatusPartiallyFilled:
+		return "partially filled"
+	case StatusFilled:
+		return "filled"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusExpired:
+		return "expired"
+	case StatusRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// Open reports whether the order may still trade
+func (s OrderStatus) Open() bool {
+	return s == StatusPending || s == StatusLive || s == StatusPartiallyFilled
+}
+
+// OrderFill is one match against a managed order
+type OrderFill struct {
+	TradeID string
+	Price   float64
+	Size    float64
+	Time    time.Time
+}
+
+// ManagedOrder is the manager's view of one order
+type ManagedOrder struct {
+	ClientID   string // Assigned by the manager; "x-" prefixed for orders adopted from the exchange
+	ExchangeID string // Empty until the exchange acknowledges the order
+	TokenID    string
+	Side       Side
+	Price      float64
+	Size       float64
+	Filled     float64
+	Status     OrderStatus
+	Type       OrderType
+	Expiration time.Time // Zero for orders that do not expire
+	Reason     string    // Why the order was rejected, cancelled or expired
+	ReplacedBy string    // Client ID of the order that replaced this one
+	Fills      []OrderFill
+	Created    time.Time
+	Updated    time.Time
+}
+
+// Remaining is the size still open
+func (o ManagedOrder) Remaining() float64 {
+	if !o.Status.Open() {
+		return 0
+	}
+	return math.Max(0, o.Size-o.Filled)
+}
+
+// OrderRequest is an order to place
+type OrderRequest struct {
+	TokenID    string
+	Side       Side
+	Price      float64
+	Size       float64
+	Options    OrderOptions
+	Type       OrderType // Empty places a GTC order
+	Expiration time.Time // Required for GTD orders
+}
+
+// OrderManager is the single owner of order state: it places and cancels orders, tracks
+// each one from placement to a terminal status, and reconciles against the exchange
+type OrderManager struct {
+	Exchange       Exchange
+	Signer         OrderSigner
+	PendingTimeout time.Duration                    // How long an unacknowledged order may go unseen before it is rejected (default 30s)
+	OnFill         func(ManagedOrder, OrderFill)    // Called for every new fill, after the order is updated
+	OnUpdate       func(before, after ManagedOrder) // Called on every status change
+	Risk           *RiskManager                     // Pre-trade checks for every order; nil places orders unchecked
+	Now            func() time.Time                 // nil uses time.Now
+
+	mu         sync.Mutex
+	orders     map[string]*ManagedOrder
+	byExchange map[string]string // Exchange ID to client ID
+	seenFills  map[string]bool   // "trade/order" pairs already applied
+	posting    map[string]int    // Posts in flight per token, whose orders must not be adopted
+	nextID     int
+	tradesFrom time.Time
+}
+
+// NewOrderManager creates a manager that signs with signer and trades on ex
+func NewOrderManager(ex Exchange, signer OrderSigner) *OrderManager {
+	return &OrderManager{
+		Exchange:   ex,
+		Signer:     signer,
+		orders:     make(map[string]*ManagedOrder),
+		byExchange: make(map[string]string),
+		seenFills:  make(map[string]bool),
+		posting:    make(map[string]int),
+	}
+}
+
+// event is a callback to run once the lock is released
+type event func()
+
+// Place signs and posts an order. With a risk manager set, the order is checked first and
+// may be shrunk; the result says so in its Reason. A rejected order is returned with
+// StatusRejected and an error. If the exchange cannot be reached or fails, the order stays
+// pending and is rejected once PendingTimeout passes. The exchange side carries no client ID,
+// so if the post did reach the exchange, reconciliation adopts the order separately.
+func (m *OrderManager) Place(ctx context.Context, req OrderRequest) (ManagedOrder, error) {
+	if req.Type == "" {
+		req.Type = OrderGTC
+	}
+
+	var order *ManagedOrder
+	var signed SignedOrder
+	var err error
+	if m.Risk != nil {
+		order, signed, err = m.Risk.admit(m, req, m.register)
+		var riskErr *RiskError
+		if errors.As(err, &riskErr) {
+			rejected := ManagedOrder{TokenID: req.TokenID, Side: req.Side, Price: req.Price, Size: req.Size,
+				Status: StatusRejected, Type: req.Type, Reason: err.Error(), Created: m.now(), Updated: m.now()}
+			return rejected, fmt.Errorf("failed to place %s %s: %w", req.Side, req.TokenID, err)
+		}
+	} else if signed, err = m.sign(req); err == nil {
+		order = m.register(req, signed, "")
+	}
+	if err != nil {
+		return ManagedOrder{}, err
+	}
+
+	resp, err := m.Exchange.PostOrder(ctx, signed, req.Type)
+
+	m.mu.Lock()
+	m.posting[req.TokenID]--
+	if m.posting[req.TokenID] <= 0 {
+		delete(m.posting, req.TokenID)
+	}
+	var events []event
+	var clobErr *CLOBError
+	switch {
+	case err == nil:
+		order.ExchangeID = resp.OrderID
+		m.byExchange[resp.OrderID] = order.ClientID
+		events = m.setStatus(order, exchangeStatus(resp.Status, 0, order.Size), "")
+	case resp.ErrorMsg != "" || (errors.As(err, &clobErr) && clobErr.Status < 500):
+		events = m.setStatus(order, StatusRejected, err.Error())
+	default:
+		order.Reason = err.Error()
+	}
+	out := *order
+	m.mu.Unlock()
+	runEvents(events)
+
+	if err != nil {
+		return out, fmt.Errorf("failed to place %s %s: %w", req.Side, req.TokenID, err)
+	}
+	return out, nil
+}
+
+// sign builds the signed order for a request
+func (m *OrderManager) sign(req OrderRequest) (SignedOrder, error) {
+	var expiration int64
+	if !req.Expiration.IsZero() {
+		expiration = req.Expiration.Unix()
+	}
+
+	signed, err := m.Signer.Build(OrderArgs{
+		TokenID:    req.TokenID,
+		Side:       req.Side,
+		Price:      req.Price,
+		Size:       req.Size,
+		Expiration: expiration,
+	}, req.Options)
+	if err != nil {
+		return SignedOrder{}, fmt.Errorf("failed to sign order: %w", err)
+	}
+	return signed, nil
+}
+
+// register starts tracking a signed order as pending, with its post in flight
+func (m *OrderManager) register(req OrderRequest, signed SignedOrder, reason string) *ManagedOrder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.posting[req.TokenID]++
+	m.nextID++
+	now := m.now()
+	order := &ManagedOrder{
+		ClientID:   fmt.Sprintf("c-%d", m.nextID),
+		TokenID:    req.TokenID,
+		Side:       req.Side,
+		Price:      req.Price,
+		Size:       math.Floor(req.Size*100+1e-9) / 100,
+		Status:     StatusPending,
+		Type:       req.Type,
+		Expiration: req.Expiration,
+		Reason:     reason,
+		Created:    now,
+		Updated:    now,
+	}
+	m.orders[order.ClientID] = order
+	return order
+}
+
+// Cancel cancels one order by client ID
+func (m *OrderManager) Cancel(ctx context.Context, clientID string) error {
+	return m.cancel(ctx, []string{clientID})
+}
+
+// CancelToken cancels every open order on a token, or on every token when tokenID is empty
+func (m *OrderManager) CancelToken(ctx context.Context, tokenID string) error {
+	var ids []string
+	for _, o := range m.Open(tokenID) {
+		ids = append(ids, o.ClientID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.cancel(ctx, ids)
+}
+
+func (m *OrderManager) cancel(ctx context.Context, clientIDs []string) error {
+	m.mu.Lock()
+	var exchangeIDs []string
+	var errs []error
+	for _, id := range clientIDs {
+		o, ok := m.orders[id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("unknown order %s", id))
+		case !o.Status.Open():
+			errs = append(errs, fmt.Errorf("order %s is already %s", id, o.Status))
+		case o.ExchangeID == "":
+			errs = append(errs, fmt.Errorf("order %s has not been acknowledged yet", id))
+		default:
+			exchangeIDs = append(exchangeIDs, o.ExchangeID)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(exchangeIDs) == 0 {
+		return errors.Join(errs...)
+	}
+	resp, err := m.Exchange.CancelOrders(ctx, exchangeIDs)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("failed to cancel orders: %w", err))...)
+	}
+
+	m.mu.Lock()
+	var events []event
+	for _, id := range resp.Canceled {
+		if o := m.byExchangeID(id); o != nil {
+			events = append(events, m.setStatus(o, StatusCancelled, "cancelled by request")...)
+		}
+	}
+	for id, reason := range resp.NotCanceled {
+		if o := m.byExchangeID(id); o != nil {
+			errs = append(errs, fmt.Errorf("order %s not cancelled: %s", o.ClientID, reason))
+		}
+	}
+	m.mu.Unlock()
+	runEvents(events)
+
+	return errors.Join(errs...)
+}
+
+// Replace cancels an order and places a new one on the same token and side at a new price
+// and size. If the cancel fails, for example because the order filled, nothing is placed.
+func (m *OrderManager) Replace(ctx context.Context, clientID string, price, size float64, opts OrderOptions) (ManagedOrder, error) {
+	old, ok := m.Order(clientID)
+	if !ok {
+		return ManagedOrder{}, fmt.Errorf("unknown order %s", clientID)
+	}
+	if err := m.Cancel(ctx, clientID); err != nil {
+		return ManagedOrder{}, fmt.Errorf("failed to replace %s: %w", clientID, err)
+	}
+
+	placed, err := m.Place(ctx, OrderRequest{
+		TokenID:    old.TokenID,
+		Side:       old.Side,
+		Price:      price,
+		Size:       size,
+		Options:    opts,
+		Type:       old.Type,
+		Expiration: old.Expiration,
+	})
+	if placed.ClientID != "" {
+		m.mu.Lock()
+		m.orders[clientID].ReplacedBy = placed.ClientID
+		m.mu.Unlock()
+	}
+	return placed, err
+}
+
+// Order returns a managed order by client ID
+func (m *OrderManager) Order(clientID string) (ManagedOrder, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[clientID]
+	if !ok {
+		return ManagedOrder{}, false
+	}
+	return copyOrder(o), true
+}
+
+// Open returns the open orders on a token, or on every token when tokenID is empty, oldest first
+func (m *OrderManager) Open(tokenID string) []ManagedOrder {
+	return m.Orders(func(o ManagedOrder) bool {
+		return o.Status.Open() && (tokenID == "" || o.TokenID == tokenID)
+	})
+}
+
+// Orders returns every managed order that keep accepts, oldest first
+func (m *OrderManager) Orders(keep func(ManagedOrder) bool) []ManagedOrder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []ManagedOrder
+	for _, o := range m.orders {
+		if keep == nil || keep(*o) {
+			out = append(out, copyOrder(o))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Created.Equal(out[j].Created) {
+			return out[i].Created.Before(out[j].Created)
+		}
+		return out[i].ClientID < out[j].ClientID
+	})
+	return out
+}
+
+// Reconcile brings every order up to date with the exchange: open orders are matched by
+// exchange ID, unknown ones are adopted so they can be managed, orders no longer open are
+// resolved, and new trades are applied as fills. Call it on startup and periodically.
+func (m *OrderManager) Reconcile(ctx context.Context) error {
+	open, err := m.Exchange.OpenOrders(ctx, OrderFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile orders: %w", err)
+	}
+
+	m.mu.Lock()
+	tradesFrom := m.tradesFrom
+	m.mu.Unlock()
+	started := m.now()
+	// Trades are listed by match time in whole seconds, so look back a little to be sure
+	// none are missed; seenFills drops the repeats
+	filter := TradeFilter{}
+	if !tradesFrom.IsZero() {
+		filter.After = tradesFrom.Add(-time.Minute)
+	}
+	trades, err := m.Exchange.Trades(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile trades: %w", err)
+	}
+
+	m.mu.Lock()
+	var events []event
+	seen := make(map[string]bool, len(open))
+	for _, oo := range open {
+		seen[oo.ID] = true
+		events = append(events, m.syncOpen(oo)...)
+	}
+	for _, t := range trades {
+		events = append(events, m.applyTrade(t)...)
+	}
+
+	var missing []*ManagedOrder
+	for _, o := range m.orders {
+		switch {
+		case !o.Status.Open() || seen[o.ExchangeID]:
+		case o.ExchangeID != "":
+			missing = append(missing, o)
+		case m.posting[o.TokenID] == 0 && started.Sub(o.Created) > m.pendingTimeout():
+			events = append(events, m.setStatus(o, StatusRejected, "never acknowledged by the exchange")...)
+		}
+	}
+	m.tradesFrom = started
+	m.mu.Unlock()
+	runEvents(events)
+
+	// Orders that left the book are looked up one by one to learn how they ended
+	var errs []error
+	for _, o := range missing {
+		oo, err := m.Exchange.Order(ctx, o.ExchangeID)
+
+		m.mu.Lock()
+		var events []event
+		var clobErr *CLOBError
+		switch {
+		case err == nil:
+			events = m.syncClosed(o, oo)
+		case errors.As(err, &clobErr) && clobErr.Status == 404:
+			events = m.setStatus(o, StatusCancelled, "no longer on the exchange")
+		default:
+			errs = append(errs, fmt.Errorf("failed to resolve order %s: %w", o.ClientID, err))
+		}
+		m.mu.Unlock()
+		runEvents(events)
+	}
+	return errors.Join(errs...)
+}
+
+// Run reconciles every interval until ctx is cancelled, reporting failures to onError
+func (m *OrderManager) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Reconcile(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncOpen updates or adopts an order the exchange lists as open. An unknown order on a
+// token with a post in flight may be that post's, so it is left for the next pass, by which
+// time Place has recorded its exchange ID.
+func (m *OrderManager) syncOpen(oo OpenOrder) []event {
+	o := m.byExchangeID(oo.ID)
+	if o == nil {
+		if m.posting[oo.AssetID] > 0 {
+			return nil
+		}
+		o = m.adopt(oo)
+	}
+	matched, _ := parseFloat(oo.SizeMatched)
+	o.Filled = math.Max(o.Filled, matched)
+	return m.setStatus(o, exchangeStatus(oo.Status, o.Filled, o.Size), "")
+}
+
+// syncClosed resolves an order that is no longer open
+func (m *OrderManager) syncClosed(o *ManagedOrder, oo OpenOrder) []event {
+	matched, _ := parseFloat(oo.SizeMatched)
+	o.Filled = math.Max(o.Filled, matched)
+
+	status := exchangeStatus(oo.Status, o.Filled, o.Size)
+	reason := ""
+	switch {
+	case status.Open():
+		// Listed as live but missing from the open listing; the next pass will tell
+		return nil
+	case status == StatusCancelled && !o.Expiration.IsZero() && !m.now().Before(o.Expiration):
+		status, reason = StatusExpired, "expired at "+o.Expiration.Format(time.RFC3339)
+	case status == StatusCancelled:
+		reason = "cancelled on the exchange"
+	}
+	return m.setStatus(o, status, reason)
+}
+
+// adopt takes ownership of an open order placed outside this manager
+func (m *OrderManager) adopt(oo OpenOrder) *ManagedOrder {
+	price, _ := parseFloat(oo.Price)
+	size, _ := parseFloat(oo.OriginalSize)
+	side := SideBuy
+	if strings.EqualFold(oo.Side, SideSell.String()) {
+		side = SideSell
+	}
+	var expiration time.Time
+	if exp, err := strconv.ParseInt(oo.Expiration, 10, 64); err == nil && exp > 0 {
+		expiration = time.Unix(exp, 0)
+	}
+
+	o := &ManagedOrder{
+		ClientID:   "x-" + oo.ID,
+		ExchangeID: oo.ID,
+		TokenID:    oo.AssetID,
+		Side:       side,
+		Price:      price,
+		Size:       size,
+		Status:     StatusLive,
+		Type:       OrderType(oo.OrderType),
+		Expiration: expiration,
+		Created:    time.Unix(oo.CreatedAt, 0),
+		Updated:    m.now(),
+	}
+	m.orders[o.ClientID] = o
+	m.byExchange[oo.ID] = o.ClientID
+	return o
+}
+
+// applyTrade records the fills a trade made against our orders
+func (m *OrderManager) applyTrade(t Trade) []event {
+	at := t.MatchedAt(m.now())
+	if strings.EqualFold(t.Status, "FAILED") {
+		return nil
+	}
+
+	var events []event
+	fill := func(orderID, price, size string) {
+		o := m.byExchangeID(orderID)
+		key := t.ID + "/" + orderID
+		if o == nil || m.seenFills[key] {
+			return
+		}
+		m.seenFills[key] = true
+
+		p, _ := parseFloat(price)
+		s, _ := parseFloat(size)
+		f := OrderFill{TradeID: t.ID, Price: p, Size: s, Time: at}
+		o.Fills = append(o.Fills, f)
+
+		// The open-orders listing may already have counted this fill
+		fillsTotal := 0.0
+		for _, f := range o.Fills {
+			fillsTotal += f.Size
+		}
+		o.Filled = math.Min(o.Size, math.Max(o.Filled, fillsTotal))
+
+		status := o.Status
+		if status.Open() || o.Filled >= o.Size-1e-9 {
+			status = exchangeStatus("LIVE", o.Filled, o.Size)
+		}
+		events = append(events, m.setStatus(o, status, "")...)
+		if m.OnFill != nil {
+			snapshot, cb := copyOrder(o), m.OnFill
+			events = append(events, func() { cb(snapshot, f) })
+		}
+	}
+
+	if strings.EqualFold(t.TraderSide, "TAKER") {
+		fill(t.TakerOrderID, t.Price, t.Size)
+	}
+	for _, mo := range t.MakerOrders {
+		fill(mo.OrderID, mo.Price, mo.MatchedAmount)
+	}
+	return events
+}
+
+// setStatus moves an order to a status, queueing OnUpdate if it changed
+func (m *OrderManager) setStatus(o *ManagedOrder, status OrderStatus, reason string) []event {
+	if o.Status == status {
+		return nil
+	}
+	// Terminal states are final, except that a fill seen late upgrades a cancel
+	if !o.Status.Open() && !(status == StatusFilled && o.Status == StatusCancelled) {
+		return nil
+	}
+
+	before := copyOrder(o)
+	o.Status = status
+	o.Updated = m.now()
+	if reason != "" {
+		o.Reason = reason
+	}
+	if m.OnUpdate == nil {
+		return nil
+	}
+	after, cb := copyOrder(o), m.OnUpdate
+	return []event{func() { cb(before, after) }}
+}
+
+// exchangeStatus maps a CLOB status string and fill to a lifecycle status
+func exchangeStatus(status string, filled, size float64) OrderStatus {
+	switch strings.ToUpper(status) {
+	case "MATCHED":
+		return StatusFilled
+	case "CANCELED", "CANCELLED", "UNMATCHED":
+		if size > 0 && filled >= size-1e-9 {
+			return StatusFilled
+		}
+		return StatusCancelled
+	case "DELAYED":
+		return StatusPending
+	}
+	switch {
+	case size > 0 && filled >= size-1e-9:
+		return StatusFilled
+	case filled > 0:
+		return StatusPartiallyFilled
+	}
+	return StatusLive
+}
+
+func (m *OrderManager) byExchangeID(id string) *ManagedOrder {
+	if clientID, ok := m.byExchange[id]; ok {
+		return m.orders[clientID]
+	}
+	return nil
+}
+
+func (m *OrderManager) pendingTimeout() time.Duration {
+	if m.PendingTimeout > 0 {
+		return m.PendingTimeout
+	}
+	return 30 * time.Second
+}
+
+func (m *OrderManager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// copyOrder copies an order without sharing its fill history
+func copyOrder(o *ManagedOrder) ManagedOrder {
+	out := *o
+	out.Fills = append([]OrderFill(nil), o.Fills...)
+	return out
+}
+
+func runEvents(events []event) {
+	for _, e := range events {
+		e()
+	}
+}
@@ -0,0 +1,62 @@
+package marketmaker
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// testTokenID is a valid uint256 token ID for orders in tests
+const testTokenID = "1234"
+
+// newTestOrderManager returns a manager on ex signing with a fixed throwaway key
+func newTestOrderManager(t *testing.T, ex Exchange) *OrderManager {
+	t.Helper()
+	wallet, err := NewWallet(strings.Repeat("11", 32))
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	return NewOrderManager(ex, NewOrderBuilder(wallet, PolygonChainID))
+}
+
+// reconcilingExchange reconciles its manager while a post is in flight, after the exchange
+// has accepted the order but before Place has seen the response
+type reconcilingExchange struct {
+	*PaperExchange
+	manager *OrderManager
+}
+
+func (e *reconcilingExchange) PostOrder(ctx context.Context, order SignedOrder, orderType OrderType) (PostOrderResponse, error) {
+	resp, err := e.PaperExchange.PostOrder(ctx, order, orderType)
+	if rerr := e.manager.Reconcile(ctx); rerr != nil {
+		return resp, rerr
+	}
+	return resp, err
+}
+
+func TestPlaceDoesNotAdoptOrderInFlight(t *testing.T) {
+	ex := &reconcilingExchange{PaperExchange: NewPaperExchange(100)}
+	m := newTestOrderManager(t, ex)
+	ex.manager = m
+
+	ctx := context.Background()
+	placed, err := m.Place(ctx, OrderRequest{TokenID: testTokenID, Side: SideBuy, Price: 0.5, Size: 10,
+		Options: OrderOptions{TickSize: 0.01}})
+	if err != nil {
+		t.Fatalf("Place: %v", err)
+	}
+	if err := m.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	open := m.Open("")
+	if len(open) != 1 {
+		t.Fatalf("got %d open orders, want 1: %+v", len(open), open)
+	}
+	if open[0].ClientID != placed.ClientID {
+		t.Errorf("open order is %s, want the placed %s", open[0].ClientID, placed.ClientID)
+	}
+	if open[0].Status != StatusLive {
+		t.Errorf("status %s, want live", open[0].Status)
+	}
+}
@@ -0,0 +1,762 @@
+package marketmaker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MarketTrade is a public trade print on a token
+type MarketTrade struct {
+	Side  string  `json:"side"` // Taker's side, "BUY" or "SELL"
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// MarketEvent is one market data update: an orderbook snapshot, a trade print, or both
+type MarketEvent struct {
+	Time    time.Time          `json:"time"`
+	TokenID string             `json:"token_id"`
+	Market  *Market            `json:"market,omitempty"` // Listing of the token's market, recorded once so a replay can price it
+	Book    *OrderBookResponse `json:"book,omitempty"`
+	Trade   *MarketTrade       `json:"trade,omitempty"`
+}
+
+// LoadMarketTape reads recorded market events, one JSON object per line, in time order
+func LoadMarketTape(path string) ([]MarketEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open market tape: %w", err)
+	}
+	defer f.Close()
+
+	var events []MarketEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var ev MarketEvent
+		if err := json.Unmarshal([]byte(text), &ev); err != nil {
+			return nil, fmt.Errorf("failed to parse market tape line %d: %w", line, err)
+		}
+		if ev.TokenID == "" {
+			return nil, fmt.Errorf("market tape line %d has no token id", line)
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read market tape: %w", err)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// AppendMarketTape appends events to a tape file, creating it if needed
+func AppendMarketTape(path string, events []MarketEvent) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open market tape: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to encode market event: %w", err)
+		}
+	}
+	if err := errors.Join(w.Flush(), f.Close()); err != nil {
+		return fmt.Errorf("failed to write market tape: %w", err)
+	}
+	return nil
+}
+
+// PaperFillModel decides how much of a resting paper order market activity fills. A new order
+// queues behind the size already displayed at its price; prints at that price work through the
+// queue before reaching it, and prints or asks through its price fill it outright.
+type PaperFillModel struct {
+	QueueSkip      float64 // Share of the displayed size at our price a new order is placed ahead of: 0 joins the back of the queue, 1 the front
+	Participation  float64 // Share of the volume reaching our order that fills it, for competition the book does not show (default 1)
+	CountDepletion bool    // Treat shrinking size at our price as trades; otherwise only prints and crossing books fill
+}
+
+func (m PaperFillModel) participation() float64 {
+	if m.Participation > 0 {
+		return math.Min(1, m.Participation)
+	}
+	return 1
+}
+
+// PaperExchange is a simulated exchange that matches our orders against live or recorded
+// books and trades and keeps simulated balances. It implements Exchange, so the order
+// manager and quoting engine run on it unchanged.
+//
+// Orders that cross the book when placed take the displayed liquidity at its prices. Orders
+// that rest fill as PaperFillModel allows, always at their own price. Taker fills pay the
+// order's fee rate; maker fills pay nothing.
+type PaperExchange struct {
+	Fills PaperFillModel
+	Mark  Marker           // Fair value to mark positions at; nil, or no price, marks to the market. Must not call back into the exchange.
+	Now   func() time.Time // nil uses the time of the latest market event, or the clock before any
+
+	mu         sync.Mutex
+	collateral float64
+	positions  map[string]float64
+	books      map[string]*OrderBookResponse
+	marks      map[string]float64 // Latest print, fill or real mid per token; placeholder books leave it alone
+	orders     map[string]*paperOrder
+	sequence   []string
+	trades     []Trade
+	fees       float64
+	nextID     int
+	clock      time.Time
+}
+
+// paperOrder is a simulated order and its place in the queue
+type paperOrder struct {
+	OpenOrder
+	side       Side
+	price      float64
+	size       float64
+	filled     float64
+	feeRateBps float64
+	expiration int64   // Unix seconds, 0 for none
+	queue      float64 // Shares displayed ahead of the order at its price
+}
+
+func (o *paperOrder) remaining() float64 {
+	return math.Max(0, o.size-o.filled)
+}
+
+// NewPaperExchange creates a paper exchange holding the given collateral
+func NewPaperExchange(collateral float64) *PaperExchange {
+	return &PaperExchange{
+		collateral: collateral,
+		positions:  make(map[string]float64),
+		books:      make(map[string]*OrderBookResponse),
+		marks:      make(map[string]float64),
+		orders:     make(map[string]*paperOrder),
+	}
+}
+
+var _ Exchange = (*PaperExchange)(nil)
+
+// paperOwner stands in for the API key on paper orders and trades
+const paperOwner = "paper"
+
+// PostOrder implements Exchange. Orders are checked against available balances the way the
+// CLOB checks them: bids need collateral not locked by other bids, asks need shares not
+// offered by other asks.
+func (p *PaperExchange) PostOrder(ctx context.Context, order SignedOrder, orderType OrderType) (PostOrderResponse, error) {
+	reject := func(msg string) (PostOrderResponse, error) {
+		resp := PostOrderResponse{ErrorMsg: msg}
+		return resp, fmt.Errorf("order rejected: %s", msg)
+	}
+
+	price, size, err := signedPriceSize(order)
+	if err != nil {
+		return reject(err.Error())
+	}
+	if order.TokenID == "" {
+		return reject("missing token id")
+	}
+	side := SideBuy
+	if order.Side == SideSell.String() {
+		side = SideSell
+	}
+	expiration, _ := strconv.ParseInt(order.Expiration, 10, 64)
+	feeRate, _ := strconv.ParseFloat(order.FeeRateBps, 64)
+	if orderType == "" {
+		orderType = OrderGTC
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if expiration > 0 && expiration <= p.now().Unix() {
+		return reject("order expired")
+	}
+	if side == SideBuy && price*size > p.collateral-p.lockedCollateral()+1e-9 {
+		return reject("not enough balance / allowance")
+	}
+	if side == SideSell && size > p.positions[order.TokenID]-p.lockedShares(order.TokenID)+1e-9 {
+		return reject("not enough balance / allowance")
+	}
+	if orderType == OrderFOK && p.takeable(order.TokenID, side, price) < size-1e-9 {
+		return reject("order couldn't be fully filled, FOK orders are fully filled or killed")
+	}
+
+	p.nextID++
+	id := fmt.Sprintf("paper-%d", p.nextID)
+	o := &paperOrder{
+		OpenOrder: OpenOrder{
+			ID:           id,
+			Status:       "LIVE",
+			Owner:        paperOwner,
+			MakerAddress: order.Maker,
+			Market:       "paper-" + order.TokenID,
+			AssetID:      order.TokenID,
+			Side:         side.String(),
+			OriginalSize: formatPaperAmount(size),
+			SizeMatched:  "0",
+			Price:        formatPaperAmount(price),
+			Outcome:      "Yes",
+			Expiration:   order.Expiration,
+			OrderType:    string(orderType),
+			CreatedAt:    p.now().Unix(),
+		},
+		side:       side,
+		price:      price,
+		size:       size,
+		feeRateBps: feeRate,
+		expiration: expiration,
+	}
+	p.orders[id] = o
+	p.sequence = append(p.sequence, id)
+
+	p.take(o, false)
+	status := "live"
+	switch {
+	case o.remaining() < 1e-9:
+		status = "matched"
+	case orderType == OrderFOK || orderType == OrderFAK:
+		o.Status = "CANCELED"
+		status = "unmatched"
+	default:
+		o.queue = p.displayed(o.AssetID, side, price) * (1 - math.Min(1, math.Max(0, p.Fills.QueueSkip)))
+	}
+	return PostOrderResponse{Success: true, OrderID: id, Status: status}, nil
+}
+
+// CancelOrders implements Exchange
+func (p *PaperExchange) CancelOrders(ctx context.Context, orderIDs []string) (CancelResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp := CancelResponse{Canceled: []string{}, NotCanceled: map[string]string{}}
+	for _, id := range orderIDs {
+		o, ok := p.orders[id]
+		switch {
+		case !ok:
+			resp.NotCanceled[id] = "order not found"
+		case o.Status != "LIVE":
+			resp.NotCanceled[id] = "order is " + strings.ToLower(o.Status)
+		default:
+			o.Status = "CANCELED"
+			resp.Canceled = append(resp.Canceled, id)
+		}
+	}
+	return resp, nil
+}
+
+// Order implements Exchange
+func (p *PaperExchange) Order(ctx context.Context, orderID string) (OpenOrder, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o, ok := p.orders[orderID]
+	if !ok {
+		return OpenOrder{}, fmt.Errorf("failed to get order %s: %w", orderID, &CLOBError{Status: 404, Message: "order not found"})
+	}
+	return o.OpenOrder, nil
+}
+
+// OpenOrders implements Exchange
+func (p *PaperExchange) OpenOrders(ctx context.Context, filter OrderFilter) ([]OpenOrder, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var out []OpenOrder
+	for _, id := range p.sequence {
+		o := p.orders[id]
+		if o.Status != "LIVE" || !matchesFilter(filter.ID, o.ID) || !matchesFilter(filter.Market, o.Market) ||
+			!matchesFilter(filter.AssetID, o.AssetID) {
+			continue
+		}
+		out = append(out, o.OpenOrder)
+	}
+	return out, nil
+}
+
+// Trades implements Exchange
+func (p *PaperExchange) Trades(ctx context.Context, filter TradeFilter) ([]Trade, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var out []Trade
+	for _, t := range p.trades {
+		at, _ := strconv.ParseInt(t.MatchTime, 10, 64)
+		if !matchesFilter(filter.ID, t.ID) || !matchesFilter(filter.Market, t.Market) || !matchesFilter(filter.AssetID, t.AssetID) ||
+			(filter.Maker != "" && !SameAddress(filter.Maker, t.MakerAddress)) ||
+			(!filter.Before.IsZero() && at >= filter.Before.Unix()) || (!filter.After.IsZero() && at <= filter.After.Unix()) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// BalanceAllowance reports simulated balances like the CLOB: collateral when tokenID is
+// empty, otherwise shares of the token. Allowances are unlimited.
+func (p *PaperExchange) BalanceAllowance(ctx context.Context, asset AssetType, tokenID string) (BalanceAllowance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if asset == AssetConditional {
+		return BalanceAllowance{Balance: p.positions[tokenID], Allowance: math.MaxFloat64}, nil
+	}
+	return BalanceAllowance{Balance: p.collateral, Allowance: math.MaxFloat64}, nil
+}
+
+// CollateralBankroll is a bankroll source backed by the simulated collateral balance
+func (p *PaperExchange) CollateralBankroll(ctx context.Context) BankrollSource {
+	return BankrollFunc(func() (float64, error) {
+		b, err := p.BalanceAllowance(ctx, AssetCollateral, "")
+		return b.Balance, err
+	})
+}
+
+// Position returns the shares held of a token
+func (p *PaperExchange) Position(tokenID string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.positions[tokenID]
+}
+
+// Apply feeds one market event to the simulation: book snapshots and trade prints fill
+// resting orders, and the event's time advances the clock, expiring GTD orders
+func (p *PaperExchange) Apply(ev MarketEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !ev.Time.IsZero() && ev.Time.After(p.clock) {
+		p.clock = ev.Time
+	}
+	if ev.Book != nil {
+		p.updateBook(ev.TokenID, ev.Book)
+	}
+	if ev.Trade != nil {
+		p.applyPrint(ev.TokenID, *ev.Trade)
+	}
+	p.expire()
+}
+
+// PollBooks fetches the current books of the given tokens and applies them, returning the
+// events so they can be recorded
+func (p *PaperExchange) PollBooks(books BookSource, tokenIDs []string) ([]MarketEvent, error) {
+	var events []MarketEvent
+	var errs []error
+	for _, tokenID := range tokenIDs {
+		book, err := books.GetOrderBook(tokenID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to poll %s: %w", tokenID, err))
+			continue
+		}
+		ev := MarketEvent{Time: time.Now(), TokenID: tokenID, Book: book}
+		p.Apply(ev)
+		events = append(events, ev)
+	}
+	return events, errors.Join(errs...)
+}
+
+// GetOrderBook returns the latest book applied for a token, less liquidity our orders took,
+// so the paper exchange can be the quoting engine's BookSource
+func (p *PaperExchange) GetOrderBook(tokenID string) (*OrderBookResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	book := p.books[tokenID]
+	if book == nil {
+		return nil, fmt.Errorf("no book for token %s yet", tokenID)
+	}
+	copied := *book
+	copied.Bids = append([]Order(nil), book.Bids...)
+	copied.Asks = append([]Order(nil), book.Asks...)
+	return &copied, nil
+}
+
+// PaperAccount is a snapshot of simulated balances
+type PaperAccount struct {
+	Collateral float64            // Cash, including what open bids lock
+	Locked     float64            // Cash locked by open bids
+	Positions  map[string]float64 // Shares held per token
+	Value      float64            // Positions marked to fair value, or the latest print, fill or real mid
+	Fees       float64            // Taker fees paid
+	Trades     int
+}
+
+// Equity is cash plus the marked value of positions
+func (a PaperAccount) Equity() float64 {
+	return a.Collateral + a.Value
+}
+
+// Account returns the current simulated balances
+func (p *PaperExchange) Account() PaperAccount {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	a := PaperAccount{
+		Collateral: p.collateral,
+		Locked:     p.lockedCollateral(),
+		Positions:  make(map[string]float64),
+		Fees:       p.fees,
+		Trades:     len(p.trades),
+	}
+	for tokenID, shares := range p.positions {
+		if math.Abs(shares) < 1e-9 {
+			continue
+		}
+		a.Positions[tokenID] = shares
+		price, _ := p.markPrice(tokenID)
+		a.Value += shares * price
+	}
+	return a
+}
+
+// MarkPrice is the price a token's shares are valued at: its fair value from Mark, or else
+// the latest print, fill or real mid. A placeholder book's mid of 0.5 is never a mark. It
+// fits Ledger.Report.
+func (p *PaperExchange) MarkPrice(tokenID string) (float64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.markPrice(tokenID)
+}
+
+func (p *PaperExchange) markPrice(tokenID string) (float64, bool) {
+	if p.Mark != nil {
+		if price, ok := p.Mark(tokenID); ok {
+			return price, true
+		}
+	}
+	price, ok := p.marks[tokenID]
+	return price, ok
+}
+
+// take fills an order against the displayed liquidity it crosses. An incoming order takes it
+// at the book's prices; a resting order the book has crossed is filled at its own price.
+func (p *PaperExchange) take(o *paperOrder, resting bool) {
+	book := p.books[o.AssetID]
+	if book == nil {
+		return
+	}
+	levels := &book.Asks
+	if o.side == SideSell {
+		levels = &book.Bids
+	}
+
+	for i := range *levels {
+		level := &(*levels)[i]
+		price, err1 := parseFloat(level.Price)
+		size, err2 := parseFloat(level.Size)
+		if err1 != nil || err2 != nil || size <= 0 || !crosses(o.side, o.price, price) {
+			continue
+		}
+		qty := math.Min(o.remaining(), size)
+		if resting {
+			qty = math.Min(o.remaining(), size*p.Fills.participation())
+			price = o.price
+		}
+		p.fill(o, qty, price, !resting)
+		// Taken liquidity stays gone until the next snapshot
+		level.Size = formatPaperAmount(size - qty)
+		if o.remaining() < 1e-9 {
+			break
+		}
+	}
+
+	kept := (*levels)[:0]
+	for _, level := range *levels {
+		if size, err := parseFloat(level.Size); err != nil || size > 1e-9 {
+			kept = append(kept, level)
+		}
+	}
+	*levels = kept
+}
+
+// takeable is the displayed size an order at price could take
+func (p *PaperExchange) takeable(tokenID string, side Side, price float64) float64 {
+	book := p.books[tokenID]
+	if book == nil {
+		return 0
+	}
+	levels := book.Asks
+	if side == SideSell {
+		levels = book.Bids
+	}
+	total := 0.0
+	for _, level := range levels {
+		lp, err1 := parseFloat(level.Price)
+		size, err2 := parseFloat(level.Size)
+		if err1 == nil && err2 == nil && crosses(side, price, lp) {
+			total += size
+		}
+	}
+	return total
+}
+
+// displayed is the size shown on our side of the book at exactly price
+func (p *PaperExchange) displayed(tokenID string, side Side, price float64) float64 {
+	book := p.books[tokenID]
+	if book == nil {
+		return 0
+	}
+	levels := book.Bids
+	if side == SideSell {
+		levels = book.Asks
+	}
+	total := 0.0
+	for _, level := range levels {
+		lp, err1 := parseFloat(level.Price)
+		size, err2 := parseFloat(level.Size)
+		if err1 == nil && err2 == nil && math.Abs(lp-price) < 1e-9 {
+			total += size
+		}
+	}
+	return total
+}
+
+// updateBook stores a snapshot and fills resting orders it implies traded
+func (p *PaperExchange) updateBook(tokenID string, book *OrderBookResponse) {
+	before := make(map[string]float64)
+	for _, o := range p.live(tokenID) {
+		before[o.ID] = p.displayed(tokenID, o.side, o.price)
+	}
+
+	copied := *book
+	copied.Bids = append([]Order(nil), book.Bids...)
+	copied.Asks = append([]Order(nil), book.Asks...)
+	p.books[tokenID] = &copied
+	if mid, ok := realMid(&copied); ok {
+		p.marks[tokenID] = mid
+	}
+
+	for _, o := range p.live(tokenID) {
+		// Real books never cross, so size through our price is size the orders ahead of us
+		// could not absorb: it would have matched us first, and the queue is gone
+		if p.takeable(tokenID, o.side, o.price) > 0 {
+			o.queue = 0
+			p.take(o, true)
+			continue
+		}
+
+		now := p.displayed(tokenID, o.side, o.price)
+		if shrunk := before[o.ID] - now; p.Fills.CountDepletion && shrunk > 0 {
+			if reached := shrunk - o.queue; reached > 0 {
+				p.fill(o, math.Min(o.remaining(), reached*p.Fills.participation()), o.price, false)
+			}
+		}
+		// Whatever is no longer displayed at our price cannot still be ahead of us
+		o.queue = math.Min(o.queue, now)
+	}
+}
+
+// applyPrint fills resting orders a public trade reached
+func (p *PaperExchange) applyPrint(tokenID string, t MarketTrade) {
+	if t.Size <= 0 {
+		return
+	}
+	p.marks[tokenID] = t.Price
+
+	// A taker sell hits bids, a taker buy lifts asks
+	side := SideBuy
+	if strings.EqualFold(t.Side, SideBuy.String()) {
+		side = SideSell
+	}
+	var resting []*paperOrder
+	for _, o := range p.live(tokenID) {
+		if o.side == side && crosses(side.opposite(), t.Price, o.price) {
+			resting = append(resting, o)
+		}
+	}
+	// Best price first, then oldest
+	sort.SliceStable(resting, func(i, j int) bool {
+		if resting[i].price != resting[j].price {
+			return (resting[i].price > resting[j].price) == (side == SideBuy)
+		}
+		return false
+	})
+
+	volume := t.Size
+	for _, o := range resting {
+		if volume <= 1e-9 {
+			return
+		}
+		reached := volume
+		if math.Abs(o.price-t.Price) < 1e-9 {
+			// A print at our price works through the queue ahead of us first
+			reached = math.Max(0, volume-o.queue)
+			o.queue = math.Max(0, o.queue-volume)
+		}
+		qty := math.Min(o.remaining(), reached*p.Fills.participation())
+		if qty <= 1e-9 {
+			continue
+		}
+		p.fill(o, qty, o.price, false)
+		volume -= qty
+	}
+}
+
+// fill executes qty of an order at price, moving balances and recording the trade
+func (p *PaperExchange) fill(o *paperOrder, qty, price float64, taker bool) {
+	qty = math.Floor(qty*100+1e-9) / 100
+	if qty <= 0 {
+		return
+	}
+
+	o.filled += qty
+	o.SizeMatched = formatPaperAmount(o.filled)
+	if o.remaining() < 1e-9 {
+		o.Status = "MATCHED"
+	}
+
+	p.positions[o.AssetID] += o.side.sign() * qty
+	p.collateral -= o.side.sign() * qty * price
+	// Our fill is a print too, and on a placeholder book the only price there is
+	p.marks[o.AssetID] = price
+	fee := 0.0
+	if taker {
+		fee = takerFee(o.feeRateBps, price, qty)
+		p.collateral -= fee
+		p.fees += fee
+	}
+
+	p.nextID++
+	trade := Trade{
+		ID:           fmt.Sprintf("paper-trade-%d", p.nextID),
+		Market:       o.Market,
+		AssetID:      o.AssetID,
+		Size:         formatPaperAmount(qty),
+		FeeRateBps:   "0",
+		Price:        formatPaperAmount(price),
+		Status:       "MATCHED",
+		MatchTime:    strconv.FormatInt(p.now().Unix(), 10),
+		Outcome:      o.Outcome,
+		Owner:        paperOwner,
+		MakerAddress: o.MakerAddress,
+	}
+	if taker {
+		trade.TakerOrderID = o.ID
+		trade.Side = o.Side
+		trade.TraderSide = "TAKER"
+		trade.FeeRateBps = strconv.FormatFloat(o.feeRateBps, 'f', -1, 64)
+	} else {
+		trade.TakerOrderID = fmt.Sprintf("paper-taker-%d", p.nextID)
+		trade.Side = o.side.opposite().String()
+		trade.TraderSide = "MAKER"
+		trade.MakerOrders = []MakerOrder{{
+			OrderID:       o.ID,
+			Owner:         paperOwner,
+			MakerAddress:  o.MakerAddress,
+			MatchedAmount: formatPaperAmount(qty),
+			Price:         o.Price,
+			AssetID:       o.AssetID,
+			Side:          o.Side,
+			Outcome:       o.Outcome,
+		}}
+	}
+	o.AssociateTrades = append(o.AssociateTrades, trade.ID)
+	p.trades = append(p.trades, trade)
+}
+
+// expire cancels GTD orders whose expiration has passed
+func (p *PaperExchange) expire() {
+	now := p.now().Unix()
+	for _, o := range p.orders {
+		if o.Status == "LIVE" && o.expiration > 0 && o.expiration <= now {
+			o.Status = "CANCELED"
+		}
+	}
+}
+
+// live returns the live orders on a token, oldest first
+func (p *PaperExchange) live(tokenID string) []*paperOrder {
+	var out []*paperOrder
+	for _, id := range p.sequence {
+		if o := p.orders[id]; o.Status == "LIVE" && o.AssetID == tokenID {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// lockedCollateral is the cash open bids would spend
+func (p *PaperExchange) lockedCollateral() float64 {
+	locked := 0.0
+	for _, o := range p.orders {
+		if o.Status == "LIVE" && o.side == SideBuy {
+			locked += o.remaining() * o.price
+		}
+	}
+	return locked
+}
+
+// lockedShares is the shares of a token open asks offer
+func (p *PaperExchange) lockedShares(tokenID string) float64 {
+	locked := 0.0
+	for _, o := range p.live(tokenID) {
+		if o.side == SideSell {
+			locked += o.remaining()
+		}
+	}
+	return locked
+}
+
+func (p *PaperExchange) now() time.Time {
+	switch {
+	case p.Now != nil:
+		return p.Now()
+	case !p.clock.IsZero():
+		return p.clock
+	}
+	return time.Now()
+}
+
+// crosses reports whether an order on side at price trades with a counter order at other
+func crosses(side Side, price, other float64) bool {
+	if side == SideBuy {
+		return other <= price+1e-9
+	}
+	return other >= price-1e-9
+}
+
+// signedPriceSize recovers price and size from an order's base-unit amounts
+func signedPriceSize(o SignedOrder) (float64, float64, error) {
+	maker, err1 := strconv.ParseFloat(o.MakerAmount, 64)
+	taker, err2 := strconv.ParseFloat(o.TakerAmount, 64)
+	if err1 != nil || err2 != nil || maker <= 0 || taker <= 0 {
+		return 0, 0, fmt.Errorf("invalid amounts %q/%q", o.MakerAmount, o.TakerAmount)
+	}
+	var price, size float64
+	switch o.Side {
+	case SideBuy.String():
+		price, size = maker/taker, taker/tokenDecimals
+	case SideSell.String():
+		price, size = taker/maker, maker/tokenDecimals
+	default:
+		return 0, 0, fmt.Errorf("invalid side %q", o.Side)
+	}
+	// Amounts are exact for prices on the tick grid; rounding drops the division's noise
+	return math.Round(price*1e6) / 1e6, size, nil
+}
+
+func matchesFilter(filter, value string) bool {
+	return filter == "" || filter == value
+}
+
+func formatPaperAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
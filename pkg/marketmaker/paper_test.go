@@ -0,0 +1,202 @@
+package marketmaker
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// paperBook builds a book from alternating price and size strings
+func paperBook(bids, asks []string) *OrderBookResponse {
+	levels := func(flat []string) []Order {
+		var out []Order
+		for i := 0; i+1 < len(flat); i += 2 {
+			out = append(out, Order{Price: flat[i], Size: flat[i+1]})
+		}
+		return out
+	}
+	return &OrderBookResponse{Bids: levels(bids), Asks: levels(asks)}
+}
+
+// postPaper signs and posts an order to a paper exchange
+func postPaper(t *testing.T, p *PaperExchange, side Side, price, size float64, orderType OrderType) (PostOrderResponse, error) {
+	t.Helper()
+	wallet, err := NewWallet(strings.Repeat("11", 32))
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	order, err := NewOrderBuilder(wallet, PolygonChainID).Build(
+		OrderArgs{TokenID: testTokenID, Side: side, Price: price, Size: size, FeeRateBps: 100},
+		OrderOptions{TickSize: 0.01})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return p.PostOrder(context.Background(), order, orderType)
+}
+
+// mustPostPaper posts an order that must be accepted
+func mustPostPaper(t *testing.T, p *PaperExchange, side Side, price, size float64) string {
+	t.Helper()
+	resp, err := postPaper(t, p, side, price, size, OrderGTC)
+	if err != nil {
+		t.Fatalf("PostOrder %s %.2f @ %.2f: %v", side, size, price, err)
+	}
+	return resp.OrderID
+}
+
+func newTestPaper(fills PaperFillModel) *PaperExchange {
+	p := NewPaperExchange(100)
+	p.Fills = fills
+	clock := time.Unix(1_700_000_000, 0)
+	p.Now = func() time.Time { return clock }
+	return p
+}
+
+func paperMatched(t *testing.T, p *PaperExchange, id string) float64 {
+	t.Helper()
+	o, err := p.Order(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+	matched, _ := parseFloat(o.SizeMatched)
+	return matched
+}
+
+func TestPaperCrossingOrderTakesBook(t *testing.T) {
+	p := newTestPaper(PaperFillModel{})
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.40", "50"}, []string{"0.45", "5", "0.46", "10"})})
+
+	resp, err := postPaper(t, p, SideBuy, 0.46, 10, OrderGTC)
+	if err != nil || resp.Status != "matched" {
+		t.Fatalf("PostOrder: %+v, %v", resp, err)
+	}
+	// 5 at 0.45 and 5 at 0.46, paying the taker fee on each
+	fee := takerFee(100, 0.45, 5) + takerFee(100, 0.46, 5)
+	a := p.Account()
+	if a.Positions[testTokenID] != 10 || math.Abs(a.Collateral-(100-4.55-fee)) > 1e-9 || math.Abs(a.Fees-fee) > 1e-9 {
+		t.Errorf("account %+v, want 10 shares for $4.55 plus $%.4f fees", a, fee)
+	}
+	book, _ := p.GetOrderBook(testTokenID)
+	if len(book.Asks) != 1 || book.Asks[0].Size != "5" {
+		t.Errorf("asks %+v, want 5 left at 0.46 until the next snapshot", book.Asks)
+	}
+}
+
+func TestPaperQueuePosition(t *testing.T) {
+	p := newTestPaper(PaperFillModel{})
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.40", "100"}, []string{"0.50", "100"})})
+	id := mustPostPaper(t, p, SideBuy, 0.40, 10)
+
+	// Prints at our price work through the 100 shares ahead of us first
+	p.Apply(MarketEvent{TokenID: testTokenID, Trade: &MarketTrade{Side: "SELL", Price: 0.40, Size: 60}})
+	if got := paperMatched(t, p, id); got != 0 {
+		t.Fatalf("matched %v with 40 shares still ahead, want 0", got)
+	}
+	p.Apply(MarketEvent{TokenID: testTokenID, Trade: &MarketTrade{Side: "SELL", Price: 0.40, Size: 45}})
+	if got := paperMatched(t, p, id); got != 5 {
+		t.Errorf("matched %v, want the 5 that reached us", got)
+	}
+	// A print through our price fills the rest outright, at our price
+	p.Apply(MarketEvent{TokenID: testTokenID, Trade: &MarketTrade{Side: "SELL", Price: 0.39, Size: 20}})
+	if got := paperMatched(t, p, id); got != 10 {
+		t.Errorf("matched %v after a print through our price, want 10", got)
+	}
+	if a := p.Account(); math.Abs(a.Collateral-96) > 1e-9 || a.Fees != 0 {
+		t.Errorf("account %+v, want $4 spent and no maker fees", a)
+	}
+}
+
+func TestPaperQueueSkipAndParticipation(t *testing.T) {
+	p := newTestPaper(PaperFillModel{QueueSkip: 1, Participation: 0.5})
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.40", "100"}, []string{"0.50", "100"})})
+	id := mustPostPaper(t, p, SideBuy, 0.40, 10)
+
+	// At the front of the queue, half the print's volume reaches us
+	p.Apply(MarketEvent{TokenID: testTokenID, Trade: &MarketTrade{Side: "SELL", Price: 0.40, Size: 8}})
+	if got := paperMatched(t, p, id); got != 4 {
+		t.Errorf("matched %v, want 4", got)
+	}
+}
+
+func TestPaperCrossingSnapshotFillsAtOurPrice(t *testing.T) {
+	p := newTestPaper(PaperFillModel{})
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.40", "100"}, []string{"0.50", "100"})})
+	id := mustPostPaper(t, p, SideBuy, 0.45, 10)
+
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.38", "100"}, []string{"0.42", "6"})})
+	if got := paperMatched(t, p, id); got != 6 {
+		t.Fatalf("matched %v, want the 6 displayed through our price", got)
+	}
+	if a := p.Account(); math.Abs(a.Collateral-(100-6*0.45)) > 1e-9 {
+		t.Errorf("collateral %.4f, want fills at our 0.45", a.Collateral)
+	}
+}
+
+func TestPaperDepletion(t *testing.T) {
+	for _, count := range []bool{false, true} {
+		p := newTestPaper(PaperFillModel{CountDepletion: count})
+		level := func(size string) {
+			p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.40", size}, []string{"0.50", "100"})})
+		}
+		level("20")
+		id := mustPostPaper(t, p, SideBuy, 0.40, 10)
+
+		// The 20 ahead of us leave, then others join behind us and 8 of them leave
+		level("0")
+		level("30")
+		level("22")
+		want := 0.0
+		if count {
+			want = 8
+		}
+		if got := paperMatched(t, p, id); got != want {
+			t.Errorf("CountDepletion=%v: matched %v, want %v", count, got, want)
+		}
+	}
+}
+
+func TestPaperBalanceChecks(t *testing.T) {
+	p := newTestPaper(PaperFillModel{})
+
+	if _, err := postPaper(t, p, SideBuy, 0.50, 201, OrderGTC); err == nil {
+		t.Error("accepted a bid costing more than the collateral")
+	}
+	mustPostPaper(t, p, SideBuy, 0.50, 150)
+	// $75 is locked, so a $30 bid does not fit in the $25 left
+	if _, err := postPaper(t, p, SideBuy, 0.30, 100, OrderGTC); err == nil {
+		t.Error("accepted a bid against collateral locked by another bid")
+	}
+	if _, err := postPaper(t, p, SideSell, 0.60, 1, OrderGTC); err == nil {
+		t.Error("accepted an ask for shares not held")
+	}
+	if _, err := postPaper(t, p, SideBuy, 0.20, 10, OrderFOK); err == nil {
+		t.Error("accepted a FOK order with nothing to fill it")
+	}
+}
+
+func TestPaperMarksIgnorePlaceholderBooks(t *testing.T) {
+	p := newTestPaper(PaperFillModel{})
+	placeholder := paperBook([]string{"0.001", "1000"}, []string{"0.999", "1000"})
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: placeholder})
+	mustPostPaper(t, p, SideBuy, 0.02, 100)
+	p.Apply(MarketEvent{TokenID: testTokenID, Trade: &MarketTrade{Side: "SELL", Price: 0.02, Size: 2000}})
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: placeholder})
+
+	// A dust bid filled at 0.02 is worth 0.02 a share, not the placeholder's 0.5 mid
+	a := p.Account()
+	if a.Positions[testTokenID] != 100 || math.Abs(a.Value-2) > 1e-9 || math.Abs(a.Equity()-100) > 1e-9 {
+		t.Errorf("account %+v equity %.2f, want 100 shares worth $2 and no gain", a, a.Equity())
+	}
+
+	p.Mark = func(string) (float64, bool) { return 0.05, true }
+	if a := p.Account(); math.Abs(a.Value-5) > 1e-9 {
+		t.Errorf("value %.2f at a 0.05 fair value, want 5", a.Value)
+	}
+	p.Mark = nil
+	p.Apply(MarketEvent{TokenID: testTokenID, Book: paperBook([]string{"0.03", "10"}, []string{"0.05", "10"})})
+	if price, ok := p.MarkPrice(testTokenID); !ok || math.Abs(price-0.04) > 1e-9 {
+		t.Errorf("mark %.4f, %v, want the real book's 0.04 mid", price, ok)
+	}
+}
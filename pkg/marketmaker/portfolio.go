@@ -0,0 +1,501 @@
+package marketmaker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// PortfolioOutcome is something that can happen, with our probability of it
+type PortfolioOutcome struct {
+	ID          string
+	Event       string // Outcomes sharing an event are mutually exclusive; empty means independent
+	Probability float64
+}
+
+// PortfolioBet buys one side of an outcome
+type PortfolioBet struct {
+	Outcome  string
+	Side     Side    // SideBuy buys YES at Price; SideSell sells YES, i.e. buys NO at 1-Price
+	Price    float64 // YES price
+	FillProb float64 // Chance the order fills, in (0,1]; 1 for an order that takes liquidity
+}
+
+// OutcomeCorrelation links two independent outcomes through a Gaussian copula
+type OutcomeCorrelation struct {
+	A, B string
+	Rho  float64
+}
+
+// Portfolio is a set of bets on related outcomes to size together
+type Portfolio struct {
+	Outcomes     []PortfolioOutcome
+	Bets         []PortfolioBet
+	Correlations []OutcomeCorrelation // Only between outcomes without an event
+}
+
+// PortfolioOptions controls portfolio sizing
+type PortfolioOptions struct {
+	Fraction    float64 // Kelly fraction (default 0.25)
+	MaxExposure float64 // Cap on total stake as a fraction of bankroll (default 0.3)
+	Samples     int     // Monte Carlo scenarios when exact enumeration is too large (default 20000)
+	Seed        uint64  // Seed for Monte Carlo sampling
+}
+
+// PortfolioResult is a jointly optimal allocation
+type PortfolioResult struct {
+	Fractions        []float64 // Stake per bet as a fraction of bankroll, in bet order
+	Independent      []float64 // What per-market fractional Kelly would stake on each bet alone
+	Total            float64
+	IndependentTotal float64
+	Growth           float64 // Expected log growth per round at Fractions
+	Scenarios        int
+	Exact            bool // Scenarios were enumerated rather than sampled
+}
+
+// maxExactScenarios bounds exact enumeration of joint outcomes
+const maxExactScenarios = 1 << 14
+
+// portfolioScenario is one joint outcome: which outcomes won and which bets filled
+type portfolioScenario struct {
+	won    []bool
+	filled []bool
+}
+
+// portfolioGroup is a set of outcomes of which exactly one state happens: the winner of a
+// mutually exclusive event (or none of its listed outcomes), or YES/NO for an independent one
+type portfolioGroup struct {
+	outcomes []int     // Outcome indexes
+	probs    []float64 // Probability of each outcome winning; the residual is "none"
+	none     float64
+}
+
+// OptimizePortfolio sizes every bet together by maximizing expected log wealth over joint
+// outcome scenarios, subject to a cap on total stake. Mutually exclusive outcomes can
+// only win one at a time, so a set covering a whole event is sized as the hedge it is
+// instead of as independent longshots. Each bet fills independently with its FillProb,
+// so a bid and an ask on the same market are only a locked-in spread when both fill.
+func OptimizePortfolio(p Portfolio, opts PortfolioOptions) (PortfolioResult, error) {
+	fraction := orDefault(opts.Fraction, 0.25)
+	maxExposure := orDefault(opts.MaxExposure, 0.3)
+	samples := opts.Samples
+	if samples <= 0 {
+		samples = 20000
+	}
+
+	index := make(map[string]int, len(p.Outcomes))
+	for i, o := range p.Outcomes {
+		if _, dup := index[o.ID]; dup {
+			return PortfolioResult{}, fmt.Errorf("duplicate outcome %q", o.ID)
+		}
+		if o.Probability < 0 || o.Probability > 1 {
+			return PortfolioResult{}, fmt.Errorf("outcome %q: probability %.4f outside [0,1]", o.ID, o.Probability)
+		}
+		index[o.ID] = i
+	}
+
+	groups, err := portfolioGroups(p.Outcomes)
+	if err != nil {
+		return PortfolioResult{}, err
+	}
+
+	returns := make([]func(portfolioScenario) float64, len(p.Bets))
+	result := PortfolioResult{
+		Fractions:   make([]float64, len(p.Bets)),
+		Independent: make([]float64, len(p.Bets)),
+	}
+	for i, bet := range p.Bets {
+		o, ok := index[bet.Outcome]
+		if !ok {
+			return PortfolioResult{}, fmt.Errorf("bet %d: unknown outcome %q", i, bet.Outcome)
+		}
+		if bet.Price <= 0 || bet.Price >= 1 {
+			return PortfolioResult{}, fmt.Errorf("bet %d: price %.4f outside (0,1)", i, bet.Price)
+		}
+		if bet.FillProb <= 0 || bet.FillProb > 1 {
+			return PortfolioResult{}, fmt.Errorf("bet %d: fill probability %.4f outside (0,1]", i, bet.FillProb)
+		}
+
+		cost, yes := bet.Price, true
+		if bet.Side == SideSell {
+			cost, yes = 1-bet.Price, false
+		}
+		returns[i] = func(s portfolioScenario) float64 {
+			switch {
+			case !s.filled[i]:
+				return 0
+			case s.won[o] == yes:
+				return (1 - cost) / cost
+			default:
+				return -1
+			}
+		}
+
+		single := KellyFraction(p.Outcomes[o].Probability, bet.Price)
+		if bet.Side == SideSell {
+			single = -single
+		}
+		result.Independent[i] = math.Max(0, single) * fraction
+		result.IndependentTotal += result.Independent[i]
+	}
+
+	scenarios, weights, exact, err := portfolioScenarios(p, index, groups, samples, opts.Seed)
+	if err != nil {
+		return PortfolioResult{}, err
+	}
+	result.Scenarios, result.Exact = len(weights), exact
+
+	// Per-scenario return of each bet
+	r := make([][]float64, len(scenarios))
+	for s, scenario := range scenarios {
+		r[s] = make([]float64, len(p.Bets))
+		for i := range p.Bets {
+			r[s][i] = returns[i](scenario)
+		}
+	}
+
+	// Full Kelly under a cap of MaxExposure/Fraction, then scaled down: fractional Kelly
+	// stays jointly optimal and the scaled total respects MaxExposure
+	full := maximizeGrowth(r, weights, maxExposure/fraction)
+	for i, f := range full {
+		result.Fractions[i] = f * fraction
+		result.Total += result.Fractions[i]
+	}
+	result.Growth = expectedLogGrowth(r, weights, result.Fractions)
+
+	return result, nil
+}
+
+// portfolioGroups collects outcomes into independent groups
+func portfolioGroups(outcomes []PortfolioOutcome) ([]portfolioGroup, error) {
+	var groups []portfolioGroup
+	byEvent := make(map[string]int)
+	for i, o := range outcomes {
+		if o.Event == "" {
+			groups = append(groups, portfolioGroup{outcomes: []int{i}, probs: []float64{o.Probability}})
+			continue
+		}
+		g, ok := byEvent[o.Event]
+		if !ok {
+			g = len(groups)
+			byEvent[o.Event] = g
+			groups = append(groups, portfolioGroup{})
+		}
+		groups[g].outcomes = append(groups[g].outcomes, i)
+		groups[g].probs = append(groups[g].probs, o.Probability)
+	}
+
+	for i := range groups {
+		sum := 0.0
+		for _, p := range groups[i].probs {
+			sum += p
+		}
+		if sum > 1+1e-9 {
+			return nil, fmt.Errorf("event %q: exclusive outcome probabilities sum to %.4f", outcomes[groups[i].outcomes[0]].Event, sum)
+		}
+		groups[i].none = math.Max(0, 1-sum)
+	}
+	return groups, nil
+}
+
+// portfolioScenarios enumerates joint outcomes and fills when there are few enough and
+// nothing is correlated, and samples them otherwise
+func portfolioScenarios(p Portfolio, index map[string]int, groups []portfolioGroup, samples int, seed uint64) ([]portfolioScenario, []float64, bool, error) {
+	total := 1
+	for _, g := range groups {
+		total *= len(g.outcomes) + 1
+		if total > maxExactScenarios {
+			break
+		}
+	}
+	for _, bet := range p.Bets {
+		if total > maxExactScenarios {
+			break
+		}
+		if bet.FillProb < 1 {
+			total *= 2
+		}
+	}
+
+	if len(p.Correlations) == 0 && total <= maxExactScenarios {
+		filled := make([]bool, len(p.Bets))
+		for i := range filled {
+			filled[i] = true
+		}
+		scenarios := []portfolioScenario{{won: make([]bool, len(p.Outcomes)), filled: filled}}
+		weights := []float64{1}
+		for _, g := range groups {
+			var nextS []portfolioScenario
+			var nextW []float64
+			for s, scenario := range scenarios {
+				for k := 0; k <= len(g.outcomes); k++ {
+					w := g.none
+					if k < len(g.outcomes) {
+						w = g.probs[k]
+					}
+					if w == 0 {
+						continue
+					}
+					won := append([]bool(nil), scenario.won...)
+					if k < len(g.outcomes) {
+						won[g.outcomes[k]] = true
+					}
+					nextS = append(nextS, portfolioScenario{won: won, filled: scenario.filled})
+					nextW = append(nextW, weights[s]*w)
+				}
+			}
+			scenarios, weights = nextS, nextW
+		}
+
+		for i, bet := range p.Bets {
+			if bet.FillProb >= 1 {
+				continue
+			}
+			var nextS []portfolioScenario
+			var nextW []float64
+			for s, scenario := range scenarios {
+				missed := append([]bool(nil), scenario.filled...)
+				missed[i] = false
+				nextS = append(nextS, scenario, portfolioScenario{won: scenario.won, filled: missed})
+				nextW = append(nextW, weights[s]*bet.FillProb, weights[s]*(1-bet.FillProb))
+			}
+			scenarios, weights = nextS, nextW
+		}
+		return scenarios, weights, true, nil
+	}
+
+	// Correlated independent outcomes are drawn through a Gaussian copula
+	var latent []int
+	latentIndex := make(map[int]int)
+	for _, g := range groups {
+		if len(g.outcomes) == 1 && p.Outcomes[g.outcomes[0]].Event == "" {
+			latentIndex[g.outcomes[0]] = len(latent)
+			latent = append(latent, g.outcomes[0])
+		}
+	}
+	corr := make([][]float64, len(latent))
+	for i := range corr {
+		corr[i] = make([]float64, len(latent))
+		corr[i][i] = 1
+	}
+	for _, c := range p.Correlations {
+		a, okA := index[c.A]
+		b, okB := index[c.B]
+		if !okA || !okB {
+			return nil, nil, false, fmt.Errorf("correlation %s/%s: unknown outcome", c.A, c.B)
+		}
+		la, okA := latentIndex[a]
+		lb, okB := latentIndex[b]
+		if !okA || !okB {
+			return nil, nil, false, fmt.Errorf("correlation %s/%s: only independent outcomes can be correlated", c.A, c.B)
+		}
+		if c.Rho <= -1 || c.Rho >= 1 {
+			return nil, nil, false, fmt.Errorf("correlation %s/%s: rho %.3f outside (-1,1)", c.A, c.B, c.Rho)
+		}
+		corr[la][lb], corr[lb][la] = c.Rho, c.Rho
+	}
+	chol, err := cholesky(corr)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	scenarios := make([]portfolioScenario, samples)
+	weights := make([]float64, samples)
+	z := make([]float64, len(latent))
+	for s := range scenarios {
+		won := make([]bool, len(p.Outcomes))
+
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		for i, o := range latent {
+			x := 0.0
+			for j := 0; j <= i; j++ {
+				x += chol[i][j] * z[j]
+			}
+			won[o] = normalCDF(x) < p.Outcomes[o].Probability
+		}
+
+		for _, g := range groups {
+			if len(g.outcomes) == 1 && p.Outcomes[g.outcomes[0]].Event == "" {
+				continue
+			}
+			u := rng.Float64()
+			for k, prob := range g.probs {
+				if u < prob {
+					won[g.outcomes[k]] = true
+					break
+				}
+				u -= prob
+			}
+		}
+
+		filled := make([]bool, len(p.Bets))
+		for i, bet := range p.Bets {
+			filled[i] = rng.Float64() < bet.FillProb
+		}
+
+		scenarios[s] = portfolioScenario{won: won, filled: filled}
+		weights[s] = 1 / float64(samples)
+	}
+	return scenarios, weights, false, nil
+}
+
+// cholesky factors a correlation matrix, failing if it is not positive definite
+func cholesky(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, errors.New("correlation matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// expectedLogGrowth is the weighted mean of log wealth; -Inf if any scenario is ruinous
+func expectedLogGrowth(r [][]float64, weights []float64, f []float64) float64 {
+	g := 0.0
+	for s, w := range weights {
+		wealth := 1.0
+		for i, x := range r[s] {
+			wealth += f[i] * x
+		}
+		if wealth <= 0 {
+			return math.Inf(-1)
+		}
+		g += w * math.Log(wealth)
+	}
+	return g
+}
+
+// maximizeGrowth runs projected gradient ascent on expected log growth over stakes that
+// are non-negative and sum to at most budget
+func maximizeGrowth(r [][]float64, weights []float64, budget float64) []float64 {
+	n := 0
+	if len(r) > 0 {
+		n = len(r[0])
+	}
+	f := make([]float64, n)
+	growth := expectedLogGrowth(r, weights, f)
+	grad := make([]float64, n)
+	next := make([]float64, n)
+
+	step := 1.0
+	for iter := 0; iter < 2000; iter++ {
+		for i := range grad {
+			grad[i] = 0
+		}
+		for s, w := range weights {
+			wealth := 1.0
+			for i, x := range r[s] {
+				wealth += f[i] * x
+			}
+			for i, x := range r[s] {
+				grad[i] += w * x / wealth
+			}
+		}
+
+		// Backtrack until the projected step improves growth
+		improved := false
+		for step > 1e-12 {
+			for i := range next {
+				next[i] = f[i] + step*grad[i]
+			}
+			projectCapped(next, budget)
+			if g := expectedLogGrowth(r, weights, next); g > growth+1e-15 {
+				copy(f, next)
+				growth = g
+				improved = true
+				step *= 2
+				break
+			}
+			step /= 2
+		}
+		if !improved {
+			break
+		}
+	}
+	return f
+}
+
+// projectCapped projects x onto {x >= 0, sum(x) <= budget}
+func projectCapped(x []float64, budget float64) {
+	sum := 0.0
+	for i := range x {
+		x[i] = math.Max(0, x[i])
+		sum += x[i]
+	}
+	if sum <= budget {
+		return
+	}
+
+	hi := 0.0
+	for _, v := range x {
+		hi = math.Max(hi, v)
+	}
+	tau := bisect(0, hi, func(t float64) bool {
+		s := 0.0
+		for _, v := range x {
+			s += math.Max(0, v-t)
+		}
+		return s > budget
+	})
+	for i := range x {
+		x[i] = math.Max(0, x[i]-tau)
+	}
+}
+
+// QuotedMarket is a market with our resting quote, for portfolio sizing
+type QuotedMarket struct {
+	Market Market
+	Event  *EventContext
+	Quote  PricingQuote
+}
+
+// QuotePortfolio builds a portfolio that buys YES at each quote's bid and NO at its ask,
+// with markets of a mutually exclusive event grouped as exclusive outcomes. Each side is
+// a resting order that fills with the same chance MakerKelly assumes, so the pair is not
+// sized as a spread that is certain to be captured.
+func QuotePortfolio(markets []QuotedMarket) Portfolio {
+	var p Portfolio
+	for _, m := range markets {
+		id := m.Market.Question
+		if ids := m.Market.TokenIDs(); len(ids) > 0 {
+			id = ids[0]
+		}
+
+		var event string
+		if m.Event.MutuallyExclusive() {
+			event = m.Event.Title
+			if len(m.Market.Events) > 0 {
+				event = m.Market.Events[0].ID
+			}
+		}
+
+		p.Outcomes = append(p.Outcomes, PortfolioOutcome{ID: id, Event: event, Probability: m.Quote.FairValue})
+		if m.Quote.Bid > 0 {
+			p.Bets = append(p.Bets, PortfolioBet{Outcome: id, Side: SideBuy, Price: m.Quote.Bid, FillProb: defaultMakerFillProbability})
+		}
+		p.Bets = append(p.Bets, PortfolioBet{Outcome: id, Side: SideSell, Price: m.Quote.Ask, FillProb: defaultMakerFillProbability})
+	}
+	return p
+}
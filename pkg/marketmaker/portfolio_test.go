@@ -0,0 +1,156 @@
+package marketmaker
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestOptimizePortfolioSingleBetIsFractionalKelly(t *testing.T) {
+	p := Portfolio{
+		Outcomes: []PortfolioOutcome{{ID: "a", Probability: 0.6}},
+		Bets:     []PortfolioBet{{Outcome: "a", Side: SideBuy, Price: 0.5, FillProb: 1}},
+	}
+	res, err := OptimizePortfolio(p, PortfolioOptions{Fraction: 0.25, MaxExposure: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 0.25 * KellyFraction(0.6, 0.5)
+	if math.Abs(res.Fractions[0]-want) > 1e-4 || math.Abs(res.Independent[0]-want) > 1e-9 {
+		t.Errorf("stake %.5f (independent %.5f), want %.5f", res.Fractions[0], res.Independent[0], want)
+	}
+	if !res.Exact {
+		t.Error("one bet was sampled, want exact enumeration")
+	}
+}
+
+func TestOptimizePortfolioAccountsForDependence(t *testing.T) {
+	bets := []PortfolioBet{
+		{Outcome: "a", Side: SideBuy, Price: 0.40, FillProb: 1},
+		{Outcome: "b", Side: SideBuy, Price: 0.40, FillProb: 1},
+	}
+	opts := PortfolioOptions{Fraction: 0.25, MaxExposure: 1, Seed: 1}
+
+	// Only one finalist can win, so backing both is a hedge worth more than either alone
+	exclusive, err := OptimizePortfolio(Portfolio{
+		Outcomes: []PortfolioOutcome{
+			{ID: "a", Event: "final", Probability: 0.45},
+			{ID: "b", Event: "final", Probability: 0.45},
+		},
+		Bets: bets,
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exclusive.Total <= exclusive.IndependentTotal {
+		t.Errorf("exclusive total %.4f, want more than independent %.4f", exclusive.Total, exclusive.IndependentTotal)
+	}
+
+	// Outcomes that tend to happen together are one bet, and summing per-market Kelly overbets it
+	correlated, err := OptimizePortfolio(Portfolio{
+		Outcomes:     []PortfolioOutcome{{ID: "a", Probability: 0.45}, {ID: "b", Probability: 0.45}},
+		Bets:         bets,
+		Correlations: []OutcomeCorrelation{{A: "a", B: "b", Rho: 0.9}},
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if correlated.Total >= 0.8*correlated.IndependentTotal {
+		t.Errorf("correlated total %.4f, want well under independent %.4f", correlated.Total, correlated.IndependentTotal)
+	}
+	if math.Abs(exclusive.Fractions[0]-exclusive.Fractions[1]) > 1e-4 {
+		t.Errorf("symmetric bets staked %.4f and %.4f", exclusive.Fractions[0], exclusive.Fractions[1])
+	}
+}
+
+func TestQuotePortfolioDoesNotTreatSpreadAsRiskless(t *testing.T) {
+	markets := []QuotedMarket{{
+		Market: Market{Question: "Will it happen?"},
+		Quote:  PricingQuote{FairValue: 0.5, Bid: 0.45, Ask: 0.55},
+	}}
+	p := QuotePortfolio(markets)
+	if len(p.Bets) != 2 || p.Bets[0].FillProb >= 1 || p.Bets[1].FillProb >= 1 {
+		t.Fatalf("bets %+v, want two resting orders that may not fill", p.Bets)
+	}
+
+	opts := PortfolioOptions{Fraction: 0.25, MaxExposure: 0.3}
+	res, err := OptimizePortfolio(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// If both sides always filled, the pair would be an arbitrage staking the whole cap
+	if res.Total > 0.5*opts.MaxExposure {
+		t.Errorf("total stake %.4f, want well under the %.2f cap", res.Total, opts.MaxExposure)
+	}
+	if res.Total <= 0 || math.Abs(res.Fractions[0]-res.Fractions[1]) > 1e-3 {
+		t.Errorf("stakes %v, want equal positive stakes on a symmetric quote", res.Fractions)
+	}
+
+	// Certain fills make the same pair riskless, which the optimizer does stake to the cap
+	for i := range p.Bets {
+		p.Bets[i].FillProb = 1
+	}
+	if res, err = OptimizePortfolio(p, opts); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(res.Total-opts.MaxExposure) > 1e-3 {
+		t.Errorf("certain spread staked %.4f, want the %.2f cap", res.Total, opts.MaxExposure)
+	}
+}
+
+func TestQuotePortfolioSkipsMissingBid(t *testing.T) {
+	p := QuotePortfolio([]QuotedMarket{{
+		Market: Market{Question: "Will it happen?"},
+		Quote:  PricingQuote{FairValue: 0.01, Ask: 0.02},
+	}})
+	if len(p.Bets) != 1 || p.Bets[0].Side != SideSell {
+		t.Errorf("bets %+v, want only the sell side", p.Bets)
+	}
+}
+
+func TestOptimizePortfolioSampledMatchesExact(t *testing.T) {
+	p := Portfolio{
+		Outcomes: []PortfolioOutcome{{ID: "a", Probability: 0.6}, {ID: "b", Probability: 0.3}},
+		Bets: []PortfolioBet{
+			{Outcome: "a", Side: SideBuy, Price: 0.5, FillProb: 0.5},
+			{Outcome: "b", Side: SideSell, Price: 0.4, FillProb: 0.8},
+		},
+	}
+	exact, err := OptimizePortfolio(p, PortfolioOptions{Fraction: 0.25, MaxExposure: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exact.Exact || exact.Scenarios != 16 {
+		t.Fatalf("%d scenarios (exact %v), want 16 enumerated", exact.Scenarios, exact.Exact)
+	}
+
+	// A zero correlation forces sampling without changing the joint distribution
+	p.Correlations = []OutcomeCorrelation{{A: "a", B: "b"}}
+	sampled, err := OptimizePortfolio(p, PortfolioOptions{Fraction: 0.25, MaxExposure: 1, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range exact.Fractions {
+		if math.Abs(sampled.Fractions[i]-exact.Fractions[i]) > 0.01 {
+			t.Errorf("bet %d: sampled %.4f, exact %.4f", i, sampled.Fractions[i], exact.Fractions[i])
+		}
+	}
+}
+
+func TestOptimizePortfolioRejectsInvalidBets(t *testing.T) {
+	outcomes := []PortfolioOutcome{{ID: "a", Probability: 0.5}}
+	tests := []struct {
+		bet     PortfolioBet
+		wantErr string
+	}{
+		{PortfolioBet{Outcome: "b", Price: 0.5, FillProb: 1}, `unknown outcome "b"`},
+		{PortfolioBet{Outcome: "a", Price: 1, FillProb: 1}, "price 1.0000 outside (0,1)"},
+		{PortfolioBet{Outcome: "a", Price: 0.5}, "fill probability 0.0000 outside (0,1]"},
+	}
+	for _, tt := range tests {
+		_, err := OptimizePortfolio(Portfolio{Outcomes: outcomes, Bets: []PortfolioBet{tt.bet}}, PortfolioOptions{})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: error %v, want one containing %q", tt.bet, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// SignalKind is the type of market evidence an estimator learns from
+type SignalKind int
+
+const (
+	SignalTrade SignalKind = iota // Someone else traded at a price
+	SignalFill                    // Someone traded against our quote
+	SignalBook                    // The book's mid moved
+)
+
+// String returns the signal name
+func (k SignalKind) String() string {
+	switch k {
+	case SignalFill:
+		return "fill"
+	case SignalBook:
+		return "book"
+	default:
+		return "trade"
+	}
+}
+
+// Signal is one observation of where a token trades
+type Signal struct {
+	Kind  SignalKind
+	Price float64
+	Size  float64 // Shares; ignored for book signals
+	Side  Side    // For fills, the side we traded
+	Time  time.Time
+}
+
+// SignalWeights sets how much each kind of signal moves the estimate. A weight of 2
+// halves the signal's noise variance; 0 uses the default.
+type SignalWeights struct {
+	Trade float64 // default 1
+	Fill  float64 // default 1.5
+	Book  float64 // default 0.5
+}
+
+// PosteriorConfig tunes the fair-value estimators
+type PosteriorConfig struct {
+	Weights       SignalWeights
+	SignalNoise   float64 // Log-odds standard deviation of one reference-size observation (default 0.5)
+	ReferenceSize float64 // Shares at which a trade counts once; larger trades count more, up to 4x (default 100)
+	FillShift     float64 // Log-odds a fill implies fair value lies beyond our price (default 0.2)
+	Drift         float64 // Log-odds standard deviation the fair value wanders per day (default 0.1)
+	QuoteLevel    float64 // Posterior interval quoted as bid and ask (default 0.8)
+	MinHalfWidth  float64 // Narrowest log-odds distance from fair to each side (default 0.1)
+}
+
+// FairValueEstimator is a logit-normal belief about a token's fair value: the log-odds of
+// the probability are normally distributed with mean Mean and variance Variance. It starts
+// from a pricing model's prior and is updated by Kalman steps on each signal.
+type FairValueEstimator struct {
+	Mean          float64 // Log-odds
+	Variance      float64
+	PriorFair     float64 // Fair value of the prior the estimate started from
+	PriorVariance float64
+	Observations  int
+	Updated       time.Time
+}
+
+// priorZ treats a model's bid/ask band as a 90% interval
+const priorZ = 1.645
+
+// NewFairValueEstimator starts an estimate from a model quote, reading its band as a 90%
+// interval
+func NewFairValueEstimator(prior PricingQuote, now time.Time) *FairValueEstimator {
+	fair := clampProbability(prior.FairValue)
+	sd := (logit(clampProbability(prior.Ask)) - logit(clampProbability(prior.Bid))) / (2 * priorZ)
+	if prior.Bid <= 0 {
+		// Without a bid, the ask side alone is half the interval
+		sd = (logit(clampProbability(prior.Ask)) - logit(fair)) / priorZ
+	}
+	if sd <= 0 {
+		sd = 1
+	}
+	return &FairValueEstimator{Mean: logit(fair), Variance: sd * sd, PriorFair: fair, PriorVariance: sd * sd, Updated: now}
+}
+
+// Fair returns the posterior median probability
+func (e *FairValueEstimator) Fair() float64 {
+	return logistic(e.Mean)
+}
+
+// Interval returns the central posterior interval holding the given probability mass
+func (e *FairValueEstimator) Interval(level float64) (lo, hi float64) {
+	z := math.Sqrt2 * math.Erfinv(level)
+	sd := math.Sqrt(e.Variance)
+	return logistic(e.Mean - z*sd), logistic(e.Mean + z*sd)
+}
+
+// Reprior moves the estimate by however much the model's prior has moved since the
+// estimate started, keeping what was learned from signals
+func (e *FairValueEstimator) Reprior(prior PricingQuote) {
+	fair := clampProbability(prior.FairValue)
+	e.Mean += logit(fair) - logit(e.PriorFair)
+	e.PriorFair = fair
+}
+
+// Observe updates the estimate with one signal
+func (e *FairValueEstimator) Observe(signal Signal, cfg PosteriorConfig) {
+	if signal.Price <= 0 || signal.Price >= 1 {
+		return
+	}
+
+	// The fair value may have wandered since the last update
+	if !e.Updated.IsZero() && signal.Time.After(e.Updated) {
+		days := signal.Time.Sub(e.Updated).Hours() / 24
+		drift := orDefault(cfg.Drift, 0.1)
+		e.Variance += drift * drift * days
+	}
+	if signal.Time.After(e.Updated) {
+		e.Updated = signal.Time
+	}
+
+	observed := logit(signal.Price)
+	var weight float64
+	switch signal.Kind {
+	case SignalFill:
+		// Being hit on our bid says fair value is probably below it, and vice versa
+		observed -= signal.Side.sign() * orDefault(cfg.FillShift, 0.2)
+		weight = orDefault(cfg.Weights.Fill, 1.5) * sizeWeight(signal.Size, cfg)
+	case SignalBook:
+		weight = orDefault(cfg.Weights.Book, 0.5)
+	default:
+		weight = orDefault(cfg.Weights.Trade, 1) * sizeWeight(signal.Size, cfg)
+	}
+
+	noise := orDefault(cfg.SignalNoise, 0.5)
+	r := noise * noise / weight
+	gain := e.Variance / (e.Variance + r)
+	e.Mean += gain * (observed - e.Mean)
+	e.Variance *= 1 - gain
+	e.Observations++
+}
+
+// Quote builds a two-sided quote from the posterior
+func (e *FairValueEstimator) Quote(prior PricingQuote, cfg PosteriorConfig) PricingQuote {
+	level := cfg.QuoteLevel
+	if level <= 0 || level >= 1 {
+		level = 0.8
+	}
+	halfWidth := math.Max(math.Sqrt2*math.Erfinv(level)*math.Sqrt(e.Variance), orDefault(cfg.MinHalfWidth, 0.1))
+
+	fair := e.Fair()
+	tick := tickSizeFor(fair)
+	bid := math.Min(1-2*tick, math.Max(tick, floorToTick(logistic(e.Mean-halfWidth), tick)))
+	ask := math.Max(bid+tick, math.Min(1-tick, ceilToTick(logistic(e.Mean+halfWidth), tick)))
+	if prior.Bid <= 0 {
+		// Observations do not overrule a model that will not bid
+		bid = 0
+	}
+	lo, hi := e.Interval(0.9)
+
+	// Confidence grows from the prior's as the posterior narrows
+	narrowing := math.Max(0, 1-math.Sqrt(e.Variance/e.PriorVariance))
+	confidence := prior.Confidence + (1-prior.Confidence)*narrowing
+
+	note := fmt.Sprintf("posterior %.1f%% (prior %.1f%%, 90%% interval %.1f-%.1f%%, %d observations)",
+		fair*100, e.PriorFair*100, lo*100, hi*100, e.Observations)
+	quote := prior
+	quote.Model = "posterior/" + prior.Model
+	quote.FairValue, quote.Bid, quote.Ask, quote.Confidence = fair, bid, ask, confidence
+	quote.Explanation = prior.Explanation + " | " + note
+	return quote.withStep("posterior", "", note, map[string]float64{
+		"prior_fair":   e.PriorFair,
+		"interval_lo":  lo,
+		"interval_hi":  hi,
+		"observations": float64(e.Observations),
+	})
+}
+
+// sizeWeight counts a trade in units of the reference size, between a quarter and four
+func sizeWeight(size float64, cfg PosteriorConfig) float64 {
+	if size <= 0 {
+		return 1
+	}
+	return math.Max(0.25, math.Min(4, size/orDefault(cfg.ReferenceSize, 100)))
+}
+
+func orDefault(v, def float64) float64 {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+func clampProbability(p float64) float64 {
+	return math.Max(probabilityEpsilon, math.Min(1-probabilityEpsilon, p))
+}
+
+// PosteriorBook holds a fair-value estimator per token
+type PosteriorBook struct {
+	Config PosteriorConfig
+
+	mu         sync.Mutex
+	estimators map[string]*FairValueEstimator
+}
+
+// NewPosteriorBook creates an empty book
+func NewPosteriorBook(cfg PosteriorConfig) *PosteriorBook {
+	return &PosteriorBook{Config: cfg, estimators: make(map[string]*FairValueEstimator)}
+}
+
+// Observe feeds a signal to a token's estimator. Signals for a token that has not been
+// priced yet are dropped, since there is no prior to update.
+func (b *PosteriorBook) Observe(tokenID string, signal Signal) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.estimators[tokenID]
+	if !ok {
+		return false
+	}
+	e.Observe(signal, b.Config)
+	return true
+}
+
+// ObserveFill feeds one of our fills to the token's estimator
+func (b *PosteriorBook) ObserveFill(fill Fill) bool {
+	return b.Observe(fill.TokenID, Signal{Kind: SignalFill, Price: fill.Price, Size: fill.Size, Side: fill.Side, Time: fill.Time})
+}
+
+// Get returns a copy of a token's estimator
+func (b *PosteriorBook) Get(tokenID string) (FairValueEstimator, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.estimators[tokenID]
+	if !ok {
+		return FairValueEstimator{}, false
+	}
+	return *e, true
+}
+
+// Apply seeds a token's estimator from a model quote the first time it is seen, and
+// otherwise replaces the quote with one built from the posterior
+func (b *PosteriorBook) Apply(tokenID string, prior PricingQuote, now time.Time) PricingQuote {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.estimators[tokenID]
+	if !ok {
+		b.estimators[tokenID] = NewFairValueEstimator(prior, now)
+		return prior
+	}
+	e.Reprior(prior)
+	if e.Observations == 0 {
+		return prior
+	}
+	return e.Quote(prior, b.Config)
+}
@@ -0,0 +1,19 @@
+package marketmaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFairValueEstimatorQuotesInsideBounds(t *testing.T) {
+	for _, fair := range []float64{0.00001, 0.5, 0.99999} {
+		prior := PricingQuote{FairValue: fair, Bid: fair / 2, Ask: (1 + fair) / 2}
+		e := NewFairValueEstimator(prior, time.Now())
+		e.Mean, e.Variance = logit(fair), 1e-6
+		quote := e.Quote(prior, PosteriorConfig{MinHalfWidth: 0.01})
+		tick := tickSizeFor(quote.FairValue)
+		if quote.Bid < tick-1e-9 || quote.Ask > 1-tick+1e-9 || quote.Ask < quote.Bid+tick-1e-9 {
+			t.Errorf("fair %v: quote %.4f/%.4f not inside (0, 1) on a %.3f grid", fair, quote.Bid, quote.Ask, tick)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RiskLimits are the pre-trade limits every order is checked against. Exposure is the cost of
+// shares held plus the collateral open bids could spend. Zero disables a limit.
+type RiskLimits struct {
+	MaxOrderNotional    float64                    // Dollars in a single order
+	MaxMarketExposure   float64                    // Dollars per market, across its outcomes
+	MaxEventExposure    float64                    // Dollars per event, across its markets
+	MaxCategoryExposure map[MarketCategory]float64 // Dollars per category
+	MaxTotalExposure    float64                    // Dollars across everything
+	MaxOpenCollateral   float64                    // Dollars open bids may lock
+	MaxWorstCaseLoss    float64                    // Dollars lost if every market resolves against us
+	MaxOrdersPerMinute  int                        // Placements in any trailing minute
+}
+
+// RiskLimitsFor turns a risk profile's limits into dollar limits at its current bankroll: a
+// market may hold up to the maximum ticket, and total exposure, open collateral and
+// worst-case loss are capped at the profile's share of bankroll
+func RiskLimitsFor(profile RiskProfile) (RiskLimits, error) {
+	if profile.Bankroll == nil {
+		return RiskLimits{}, fmt.Errorf("risk profile %q has no bankroll source", profile.Name)
+	}
+	bankroll, err := profile.Bankroll.Bankroll()
+	if err != nil {
+		return RiskLimits{}, fmt.Errorf("failed to get bankroll: %w", err)
+	}
+
+	limits := RiskLimits{
+		MaxOrderNotional:    profile.MaxTicket,
+		MaxMarketExposure:   profile.MaxTicket,
+		MaxCategoryExposure: make(map[MarketCategory]float64),
+		MaxTotalExposure:    profile.MaxExposure * bankroll,
+		MaxOpenCollateral:   profile.MaxExposure * bankroll,
+		MaxWorstCaseLoss:    profile.MaxExposure * bankroll,
+	}
+	for category, limit := range profile.CategoryCaps {
+		limits.MaxCategoryExposure[category] = limit * bankroll
+	}
+	return limits, nil
+}
+
+// RiskError is an order the risk manager refused
+type RiskError struct {
+	Limit  string // Which limit refused it, such as "market exposure"
+	Reason string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("risk check failed: %s", e.Reason)
+}
+
+// RiskManager checks orders before they are signed, rejecting them or shrinking them to fit
+// the limits. Only orders placed through an OrderManager whose Risk is set are checked. The
+// exchange returned by Guard refuses any order that was not, but only if the caller hands
+// out nothing else: code holding the unwrapped exchange, or a manager without Risk, places
+// orders unchecked.
+type RiskManager struct {
+	Limits RiskLimits
+	Ledger *Ledger                                  // Positions and token descriptions; nil counts fills seen by the order manager
+	Logf   func(format string, args ...interface{}) // Reports every resize and rejection
+	Now    func() time.Time                         // nil uses time.Now
+
+	mu       sync.Mutex
+	placed   []time.Time         // Recent placements, for the rate limit
+	approved map[string]struct{} // Signatures of checked orders not yet posted
+}
+
+// NewRiskManager creates a risk manager enforcing limits on the positions in ledger
+func NewRiskManager(limits RiskLimits, ledger *Ledger) *RiskManager {
+	return &RiskManager{Limits: limits, Ledger: ledger, approved: make(map[string]struct{})}
+}
+
+// exposure is what we stand to spend and lose on one token
+type exposure struct {
+	cost       float64 // Cost of shares held plus collateral open bids lock
+	shares     float64 // Shares held plus shares open bids would buy
+	collateral float64 // Collateral open bids lock
+}
+
+// riskBook is the exposure on every token, grouped the ways limits apply
+type riskBook struct {
+	tokens map[string]*exposure
+	info   map[string]TokenInfo
+}
+
+// book gathers exposure from the ledger and the manager's open orders
+func (r *RiskManager) book(m *OrderManager) riskBook {
+	b := riskBook{tokens: make(map[string]*exposure), info: make(map[string]TokenInfo)}
+	token := func(tokenID string) *exposure {
+		e, ok := b.tokens[tokenID]
+		if !ok {
+			e = &exposure{}
+			b.tokens[tokenID] = e
+			if r.Ledger != nil {
+				b.info[tokenID] = r.Ledger.Info(tokenID)
+			}
+		}
+		return e
+	}
+
+	if r.Ledger != nil {
+		for _, p := range r.Ledger.Positions() {
+			// The CLOB only sells shares we hold, so a short is a recording gap, not a risk
+			if p.Shares > 0 {
+				e := token(p.TokenID)
+				e.cost += p.Shares * p.AvgCost
+				e.shares += p.Shares
+			}
+		}
+	} else {
+		// Without a ledger, positions are what the manager has seen fill: shares net of
+		// sales, at cost net of proceeds
+		held := make(map[string]*exposure)
+		for _, o := range m.Orders(func(o ManagedOrder) bool { return o.Filled > 0 }) {
+			h, ok := held[o.TokenID]
+			if !ok {
+				h = &exposure{}
+				held[o.TokenID] = h
+			}
+			h.shares += o.Side.sign() * o.Filled
+			h.cost += o.Side.sign() * o.Filled * o.Price
+		}
+		for tokenID, h := range held {
+			if h.shares > 0 {
+				e := token(tokenID)
+				e.cost += math.Max(0, h.cost)
+				e.shares += h.shares
+			}
+		}
+	}
+	// Pending orders count too: they may already be resting
+	for _, o := range m.Orders(func(o ManagedOrder) bool { return o.Status.Open() && o.Side == SideBuy }) {
+		e := token(o.TokenID)
+		notional := math.Max(0, o.Size-o.Filled) * o.Price
+		e.cost += notional
+		e.collateral += notional
+		e.shares += math.Max(0, o.Size-o.Filled)
+	}
+	return b
+}
+
+// sum totals cost over the tokens a key function groups with key
+func (b riskBook) sum(key string, keyOf func(tokenID string, info TokenInfo) string) float64 {
+	total := 0.0
+	for tokenID, e := range b.tokens {
+		if keyOf(tokenID, b.info[tokenID]) == key {
+			total += e.cost
+		}
+	}
+	return total
+}
+
+// worstCaseLoss is what resolution could cost across all markets: in each market, whichever
+// outcome pays us least wins. A market with one known outcome may resolve to none of them.
+func (b riskBook) worstCaseLoss() float64 {
+	markets := make(map[string][]*exposure)
+	for tokenID, e := range b.tokens {
+		key := b.info[tokenID].marketKey(tokenID)
+		markets[key] = append(markets[key], e)
+	}
+
+	total := 0.0
+	for _, outcomes := range markets {
+		cost := 0.0
+		payout := math.Inf(1)
+		for _, e := range outcomes {
+			cost += e.cost
+			payout = math.Min(payout, e.shares)
+		}
+		if len(outcomes) == 1 {
+			payout = 0
+		}
+		total += math.Max(0, cost-payout)
+	}
+	return total
+}
+
+// check fits an order to the limits, returning it unchanged, shrunk, or refused. The caller
+// holds r.mu so that checks and placements do not interleave.
+func (r *RiskManager) check(m *OrderManager, req OrderRequest) (OrderRequest, string, error) {
+	now := r.now()
+	recent := r.placed[:0]
+	for _, t := range r.placed {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	r.placed = recent
+	if limit := r.Limits.MaxOrdersPerMinute; limit > 0 && len(recent) >= limit {
+		return req, "", &RiskError{Limit: "order rate",
+			Reason: fmt.Sprintf("%d orders placed in the last minute, limit %d", len(recent), limit)}
+	}
+	if req.Price <= 0 || req.Size <= 0 {
+		return req, "", &RiskError{Limit: "order", Reason: fmt.Sprintf("invalid order %.2f @ %.4f", req.Size, req.Price)}
+	}
+
+	// Each limit allows some more notional; the tightest decides
+	type headroom struct {
+		limit  string
+		room   float64
+		detail string
+	}
+	var rooms []headroom
+	add := func(limit string, max, used float64, scope string) {
+		if max > 0 {
+			rooms = append(rooms, headroom{limit, max - used,
+				fmt.Sprintf("%s $%.2f of $%.2f used%s", limit, used, max, scope)})
+		}
+	}
+	add("order notional", r.Limits.MaxOrderNotional, 0, "")
+
+	// Sells only reduce what we can lose at resolution
+	if req.Side == SideBuy {
+		b := r.book(m)
+		info := b.info[req.TokenID]
+		if r.Ledger != nil {
+			info = r.Ledger.Info(req.TokenID)
+		}
+
+		market := info.marketKey(req.TokenID)
+		label := info.Question
+		if label == "" {
+			label = market
+		}
+		add("market exposure", r.Limits.MaxMarketExposure,
+			b.sum(market, func(id string, i TokenInfo) string { return i.marketKey(id) }), fmt.Sprintf(" on %q", label))
+		if info.Event != "" {
+			add("event exposure", r.Limits.MaxEventExposure,
+				b.sum(info.Event, func(_ string, i TokenInfo) string { return i.Event }), " in event "+info.Event)
+		}
+		add("category exposure", r.Limits.MaxCategoryExposure[info.Category],
+			b.sum(info.Category.String(), func(_ string, i TokenInfo) string { return i.Category.String() }), " in "+info.Category.String())
+		add("total exposure", r.Limits.MaxTotalExposure, b.sum("", func(string, TokenInfo) string { return "" }), "")
+
+		collateral := 0.0
+		for _, e := range b.tokens {
+			collateral += e.collateral
+		}
+		add("open collateral", r.Limits.MaxOpenCollateral, collateral, "")
+		// A new bid adds at most its notional to the worst case
+		add("worst-case loss", r.Limits.MaxWorstCaseLoss, b.worstCaseLoss(), "")
+	}
+
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].room < rooms[j].room })
+	notional := req.Price * req.Size
+	if len(rooms) == 0 || rooms[0].room >= notional-1e-9 {
+		return req, "", nil
+	}
+
+	tightest := rooms[0]
+	size := math.Floor(math.Max(0, tightest.room)/req.Price*100+1e-9) / 100
+	minSize := math.Max(req.Options.MinSize, 0.01)
+	if size < minSize {
+		return req, "", &RiskError{Limit: tightest.limit, Reason: fmt.Sprintf("%s %.2f @ %.4f ($%.2f) refused: %s",
+			req.Side, req.Size, req.Price, notional, tightest.detail)}
+	}
+	note := fmt.Sprintf("resized from %.2f to %.2f shares: %s", req.Size, size, tightest.detail)
+	req.Size = size
+	return req, note, nil
+}
+
+// admit checks, signs and registers an order as one step, so that concurrent placements see
+// each other's exposure
+func (r *RiskManager) admit(m *OrderManager, req OrderRequest, register func(OrderRequest, SignedOrder, string) *ManagedOrder) (*ManagedOrder, SignedOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	checked, note, err := r.check(m, req)
+	if err != nil {
+		r.logf("REJECT %s %s: %v", req.Side, req.TokenID, err)
+		return nil, SignedOrder{}, err
+	}
+	if note != "" {
+		r.logf("RESIZE %s %s: %s", req.Side, req.TokenID, note)
+	}
+	signed, err := m.sign(checked)
+	if err != nil {
+		return nil, SignedOrder{}, err
+	}
+
+	r.placed = append(r.placed, r.now())
+	if r.approved == nil {
+		r.approved = make(map[string]struct{})
+	}
+	r.approved[signed.Signature] = struct{}{}
+	return register(checked, signed, note), signed, nil
+}
+
+// Guard wraps an exchange so that it only accepts orders this risk manager checked
+func (r *RiskManager) Guard(ex Exchange) Exchange {
+	return &riskGuard{Exchange: ex, risk: r}
+}
+
+// riskGuard is an exchange that refuses unchecked orders
+type riskGuard struct {
+	Exchange
+	risk *RiskManager
+}
+
+// PostOrder implements Exchange
+func (g *riskGuard) PostOrder(ctx context.Context, order SignedOrder, orderType OrderType) (PostOrderResponse, error) {
+	g.risk.mu.Lock()
+	_, ok := g.risk.approved[order.Signature]
+	delete(g.risk.approved, order.Signature)
+	g.risk.mu.Unlock()
+
+	if !ok {
+		msg := "order was not checked by the risk manager"
+		return PostOrderResponse{ErrorMsg: msg}, errors.New("order rejected: " + msg)
+	}
+	return g.Exchange.PostOrder(ctx, order, orderType)
+}
+
+func (r *RiskManager) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *RiskManager) logf(format string, args ...interface{}) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
@@ -1,210 +1,225 @@
 package marketmaker
 
 import (
+	"fmt"
 	"math"
 	"strings"
 )
 
 // PricingStrategy contains logic for pricing different types of markets
-type PricingStrategy struct{}
+type PricingStrategy struct {
+	Models *ModelRegistry // Pricing models to consult (nil uses DefaultModels)
+	Rules  *RuleStore     // Categorization and price band rules (nil uses DefaultRules)
+
+	Toxicity   *ToxicityTracker // Widens quotes after toxic fills (nil never widens)
+	Posteriors *PosteriorBook   // Updates fair values from observed trades (nil keeps model quotes)
+	Risk       *RiskProfile     // Sizing limits (nil uses DustRiskProfile)
+}
 
 // MarketCategory represents different types of markets
 type MarketCategory int
 
 const (
-	CategoryUnknown MarketCategory = iota
-	CategorySports                 // Sports outcomes (Super Bowl, etc.)
-	CategoryPolitics               // Elections, political events
-	CategoryEconomic               // Fed rates, inflation, etc.
-	CategoryLongshot              // Very unlikely events (< 5%)
-	CategoryCompetitive           // 40-60% probability range
+	CategoryUnknown     MarketCategory = iota
+	CategorySports                     // Sports outcomes (Super Bowl, etc.)
+	CategoryPolitics                   // Elections, political events
+	CategoryEconomic                   // Fed rates, inflation, etc.
+	CategoryLongshot                   // Very unlikely events (< 5%)
+	CategoryCompetitive                // 40-60% probability range
 )
 
-// CategorizeMarket attempts to categorize a market based on its question
-func (ps *PricingStrategy) CategorizeMarket(question string) MarketCategory {
-	lowerQ := strings.ToLower(question)
+// categoryNames maps categories to the names used in rules files and reports
+var categoryNames = map[MarketCategory]string{
+	CategoryUnknown:     "unknown",
+	CategorySports:      "sports",
+	CategoryPolitics:    "politics",
+	CategoryEconomic:    "economic",
+	CategoryLongshot:    "longshot",
+	CategoryCompetitive: "competitive",
+}
 
-	// Sports keywords
-	sportsKeywords := []string{"super bowl", "win", "championship", "nfl", "nba", "mlb", "soccer", "world cup"}
-	for _, keyword := range sportsKeywords {
-		if strings.Contains(lowerQ, keyword) {
-			return CategorySports
-		}
+// String returns the category name used in rules files
+func (c MarketCategory) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
 	}
+	return fmt.Sprintf("MarketCategory(%d)", int(c))
+}
 
-	// Politics keywords
-	politicsKeywords := []string{"election", "president", "mayor", "senator", "win the", "elected"}
-	for _, keyword := range politicsKeywords {
-		if strings.Contains(lowerQ, keyword) {
-			return CategoryPolitics
+// ParseMarketCategory parses a category name as used in rules files
+func ParseMarketCategory(name string) (MarketCategory, error) {
+	for category, n := range categoryNames {
+		if strings.EqualFold(name, n) {
+			return category, nil
 		}
 	}
+	return CategoryUnknown, fmt.Errorf("unknown market category %q", name)
+}
 
-	// Economic keywords
-	economicKeywords := []string{"fed", "interest rate", "inflation", "gdp", "recession", "unemployment"}
-	for _, keyword := range economicKeywords {
-		if strings.Contains(lowerQ, keyword) {
-			return CategoryEconomic
-		}
+// MarshalText implements encoding.TextMarshaler so categories serialize by name
+func (c MarketCategory) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (c *MarketCategory) UnmarshalText(text []byte) error {
+	category, err := ParseMarketCategory(string(text))
+	if err != nil {
+		return err
 	}
+	*c = category
+	return nil
+}
 
-	return CategoryUnknown
+// CategorizeMarket attempts to categorize a market based on its question
+func (ps *PricingStrategy) CategorizeMarket(question string) MarketCategory {
+	return ps.rules().Categorize(question, nil)
 }
 
 // SuggestPricingForDustMarket provides intelligent pricing for illiquid/dust markets
-// Returns (bidPrice, askPrice, reasoning)
+// Returns (bidPrice, askPrice, reasoning); use PriceMarket and Decide for a structured record
 func (ps *PricingStrategy) SuggestPricingForDustMarket(question string, category MarketCategory) (float64, float64, string) {
-	switch category {
-	case CategorySports:
-		return ps.priceSportsLongshot(question)
+	quote := ps.PriceMarket(PricingInput{
+		Market:   Market{Question: question},
+		Category: category,
+	})
+	return quote.Bid, quote.Ask, quote.Explanation
+}
 
-	case CategoryPolitics:
-		return ps.pricePoliticalEvent(question)
+// PriceMarket runs the registered pricing models and returns the first quote that applies,
+// falling back to a conservative wide spread if no model does
+func (ps *PricingStrategy) PriceMarket(input PricingInput) PricingQuote {
+	return ps.adjustQuote(ps.priceModels(input), input)
+}
 
-	case CategoryEconomic:
-		return ps.priceEconomicEvent(question)
+// priceModels runs the pricing models without learning from fills or trades
+func (ps *PricingStrategy) priceModels(input PricingInput) PricingQuote {
+	if input.Rules == nil {
+		input.Rules = ps.rules()
+	}
 
-	default:
-		// Conservative default: wide spread for safety
-		return 0.10, 0.30, "Unknown category - using conservative wide spread (10-30%)"
+	quote, ok := ps.registry().Price(input)
+	if !ok {
+		quote, _ = fallbackModel{}.Price(input)
 	}
+	if len(quote.Steps) == 0 {
+		quote = quote.withStep("model", quote.Model, quote.Explanation, nil)
+	}
+	return withdrawLateBid(quote, input)
 }
 
-// priceSportsLongshot prices sports outcomes (usually longshots)
-func (ps *PricingStrategy) priceSportsLongshot(question string) (float64, float64, string) {
-	lowerQ := strings.ToLower(question)
-
-	// Super Bowl winner pricing
-	if strings.Contains(lowerQ, "super bowl") {
-		// Most NFL teams: ~3% (1/32 teams)
-		// Bad teams: ~0.5-1%
-		// Good teams: ~5-15%
-
-		// Check for bad teams
-		badTeams := []string{"browns", "titans", "jets", "raiders", "panthers", "giants"}
-		for _, team := range badTeams {
-			if strings.Contains(lowerQ, team) {
-				return 0.005, 0.015, "Bad NFL team - priced at 0.5-1.5% (conservative longshot)"
-			}
-		}
-
-		// Check for good teams
-		goodTeams := []string{"chiefs", "49ers", "ravens", "bills", "eagles"}
-		for _, team := range goodTeams {
-			if strings.Contains(lowerQ, team) {
-				return 0.08, 0.12, "Good NFL team - priced at 8-12% (competitive odds)"
-			}
-		}
-
-		// Average team: ~3%
-		return 0.02, 0.05, "Average NFL team - priced at 2-5% (base rate 1/32 teams)"
+// withdrawLateBid pulls the bid once the market's deadline has passed: the outcome may
+// already be known to whoever is selling
+func withdrawLateBid(quote PricingQuote, input PricingInput) PricingQuote {
+	now := input.now()
+	deadline, ok := MarketDeadline(input.Market, now)
+	if !ok || !deadline.Passed(now) || quote.Bid <= 0 {
+		return quote
 	}
-
-	// Default sports longshot
-	return 0.01, 0.05, "Generic sports longshot - priced at 1-5%"
+	note := fmt.Sprintf("deadline %s (%s) has passed - no bid", deadline.Time.Format("Jan 2, 2006"), deadline.Source)
+	quote.Bid = 0
+	quote.Explanation += " | " + note
+	return quote.withStep("deadline", deadline.Source, note, nil)
 }
 
-// pricePoliticalEvent prices political outcomes
-func (ps *PricingStrategy) pricePoliticalEvent(question string) (float64, float64, string) {
-	lowerQ := strings.ToLower(question)
-
-	// Presidential elections - multi-candidate races
-	if strings.Contains(lowerQ, "president") {
-		// If it's a specific candidate in a race with many candidates
-		if strings.Contains(lowerQ, "will") && strings.Contains(lowerQ, "win") {
-			// Unknown candidate in large field: ~5-15%
-			return 0.05, 0.15, "Presidential candidate in large field - priced at 5-15%"
-		}
-	}
+// adjustQuote replaces a model quote with the posterior learned from observed trades,
+// then widens it by the multiplier learned from toxic fills
+func (ps *PricingStrategy) adjustQuote(quote PricingQuote, input PricingInput) PricingQuote {
+	return ps.applyToxicity(ps.applyPosterior(quote, input), input)
+}
 
-	// Mayoral races - local elections
-	if strings.Contains(lowerQ, "mayor") {
-		return 0.10, 0.25, "Mayoral candidate - priced at 10-25% (assume 4-5 competitive candidates)"
+// applyPosterior replaces a model quote with the posterior learned from observed trades
+func (ps *PricingStrategy) applyPosterior(quote PricingQuote, input PricingInput) PricingQuote {
+	if tokenID := input.Market.yesTokenID(); ps.Posteriors != nil && tokenID != "" {
+		return ps.Posteriors.Apply(tokenID, quote, input.now())
 	}
+	return quote
+}
 
-	// Rare political events (resignations, etc.)
-	rareEvents := []string{"out in", "resign", "impeach", "remove"}
-	for _, event := range rareEvents {
-		if strings.Contains(lowerQ, event) {
-			return 0.01, 0.05, "Rare political event - priced at 1-5%"
-		}
+// applyToxicity widens a quote by the multiplier learned from toxic fills
+func (ps *PricingStrategy) applyToxicity(quote PricingQuote, input PricingInput) PricingQuote {
+	if ps.Toxicity == nil {
+		return quote
 	}
-
-	// Default political event
-	return 0.15, 0.35, "Generic political event - priced at 15-35%"
+	return widenQuote(quote, ps.Toxicity.Multiplier(input.Market.yesTokenID(), input.Category))
 }
 
-// priceEconomicEvent prices economic/Fed events
-func (ps *PricingStrategy) priceEconomicEvent(question string) (float64, float64, string) {
-	lowerQ := strings.ToLower(question)
-
-	// Fed rate increases
-	if strings.Contains(lowerQ, "fed") && strings.Contains(lowerQ, "increase") {
-		// Check timeframe
-		if strings.Contains(lowerQ, "2025") || strings.Contains(lowerQ, "2026") {
-			return 0.20, 0.40, "Fed rate increase (future) - priced at 20-40%"
-		}
-		return 0.30, 0.50, "Fed rate increase - priced at 30-50%"
+// registry returns the models this strategy consults
+func (ps *PricingStrategy) registry() *ModelRegistry {
+	if ps.Models != nil {
+		return ps.Models
 	}
+	return DefaultModels
+}
 
-	// Recession predictions
-	if strings.Contains(lowerQ, "recession") {
-		return 0.15, 0.35, "Recession prediction - priced at 15-35%"
+// rules returns the active rule set for this strategy
+func (ps *PricingStrategy) rules() *RuleSet {
+	if ps.Rules != nil {
+		return ps.Rules.Load()
 	}
-
-	// Default economic event
-	return 0.25, 0.45, "Generic economic event - priced at 25-45%"
+	return DefaultRules.Load()
 }
 
+// Maker sizing assumptions: the chance each resting side fills before we requote, and how
+// far a fill moves our probability against us
+const (
+	defaultMakerFillProbability = 0.5
+	defaultMakerAdverse         = 0.2
+)
+
 // CalculateKellyBetSize calculates optimal position size using Kelly Criterion
-// edgePercent: your edge over the market (e.g., 0.05 for 5% edge)
 // probability: your estimated true probability (e.g., 0.5 for 50%)
-// Returns fraction of bankroll to risk
+// marketPrice: price you would trade at
+// Returns signed fraction of bankroll to risk: positive buys YES, negative sells YES
+// (buys NO at 1-marketPrice)
 func (ps *PricingStrategy) CalculateKellyBetSize(probability float64, marketPrice float64) float64 {
-	if probability <= marketPrice || probability <= 0 || probability >= 1 {
-		return 0 // No edge or invalid probability
-	}
-
-	// Kelly formula: f = (bp - q) / b
-	// where:
-	//   b = odds (payout ratio)
-	//   p = probability of winning
-	//   q = probability of losing (1 - p)
+	risk := ps.risk()
+	kelly := KellyFraction(probability, marketPrice)
 
-	b := (1.0 - marketPrice) / marketPrice // Odds
-	q := 1.0 - probability
+	// Use fractional Kelly for safety
+	fractionalKelly := kelly * risk.KellyFraction
 
-	kelly := (b*probability - q) / b
-
-	// Use fractional Kelly (1/4 Kelly) for safety
-	fractionalKelly := kelly * 0.25
-
-	// Cap at 10% of bankroll for risk management
-	return math.Min(fractionalKelly, 0.10)
+	// Cap the share of bankroll for risk management
+	return math.Copysign(math.Min(math.Abs(fractionalKelly), risk.MaxKelly), fractionalKelly)
 }
 
 // SuggestPositionSize suggests position size for a market
 // bankroll: total capital available
 // probability: your estimated probability
-// marketBid/marketAsk: current market prices
-// Returns (buySize in dollars, sellSize in dollars, reasoning)
-func (ps *PricingStrategy) SuggestPositionSize(bankroll float64, probability float64, marketBid float64, marketAsk float64) (float64, float64, string) {
-	// For dust markets, start VERY small
-	minSize := 5.0   // $5 minimum
-	maxSize := 50.0  // $50 maximum for dust markets
-
-	// Calculate Kelly sizing
-	kellyFraction := ps.CalculateKellyBetSize(probability, (marketBid+marketAsk)/2)
-	suggestedSize := bankroll * kellyFraction
-
-	// Clamp to min/max for dust markets
-	if suggestedSize < minSize {
-		return minSize, minSize, "Using minimum size ($5) - Kelly suggests lower but testing requires minimum capital"
-	}
+// bid/ask: prices your resting orders will sit at
+// Returns (buySize in dollars, sellSize in dollars of collateral, reasoning)
+func (ps *PricingStrategy) SuggestPositionSize(bankroll float64, probability float64, bid float64, ask float64) (float64, float64, string) {
+	return ps.SuggestPositionSizeFor(Position{Cash: bankroll}, probability, bid, ask)
+}
 
-	if suggestedSize > maxSize {
-		return maxSize, maxSize, "Capped at $50 - dust markets are too risky for larger positions"
-	}
+// SuggestPositionSizeFor sizes each side of a resting quote given what we already hold,
+// so a partially filled side shrinks and the opposite side can grow to unwind it
+func (ps *PricingStrategy) SuggestPositionSizeFor(pos Position, probability float64, bid float64, ask float64) (float64, float64, string) {
+	risk := ps.risk()
+
+	size := MakerKelly(probability, pos, MakerQuote{
+		Bid:         bid,
+		Ask:         ask,
+		BidFillProb: defaultMakerFillProbability,
+		AskFillProb: defaultMakerFillProbability,
+		Adverse:     defaultMakerAdverse,
+	}, risk.KellyFraction)
+
+	buy, buyReason := risk.clampTicket("buy", size.BuyNotional, pos.Cash)
+	sell, sellReason := risk.clampTicket("sell", size.SellNotional, pos.Cash)
+	return buy, sell, fmt.Sprintf("%s Kelly at %.1f%%: %s, %s",
+		formatKellyFraction(risk.KellyFraction), probability*100, buyReason, sellReason)
+}
 
-	return suggestedSize, suggestedSize, "Kelly criterion sizing with 1/4 Kelly for safety"
+// formatKellyFraction renders common Kelly fractions as "1/4 Kelly"-style labels
+func formatKellyFraction(f float64) string {
+	if f > 0 && f <= 1 {
+		if n := 1 / f; math.Abs(n-math.Round(n)) < 1e-9 {
+			if n == 1 {
+				return "Full"
+			}
+			return fmt.Sprintf("1/%.0f", n)
+		}
+	}
+	return fmt.Sprintf("%.2fx", f)
 }
@@ -0,0 +1,173 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// QuoteInput is what a Quoter needs to place a bid and ask on one token
+type QuoteInput struct {
+	Mid              float64 // Reference price: book mid or fair value
+	Inventory        float64 // Shares held; negative when short
+	Volatility       float64 // Standard deviation of price changes per square-root day
+	TimeToResolution float64 // Days until the market resolves
+	TickSize         float64 // Price increment; 0 leaves prices unsnapped
+	SpreadMultiplier float64 // Extra widening, e.g. from toxicity tracking; 0 means 1
+}
+
+// QuoteResult is a two-sided quote
+type QuoteResult struct {
+	Reservation float64 // Price at which we are indifferent to trading, after inventory skew
+	Spread      float64 // Distance between bid and ask before snapping
+	Bid         float64
+	Ask         float64
+	Reasoning   string
+}
+
+// Quoter turns a reference price and our state into a bid and ask
+type Quoter interface {
+	Quote(input QuoteInput) QuoteResult
+}
+
+// SymmetricQuoter quotes a fixed spread centered on mid, ignoring inventory
+type SymmetricQuoter struct {
+	Spread float64 // Absolute distance between bid and ask
+}
+
+// Quote implements Quoter
+func (q SymmetricQuoter) Quote(input QuoteInput) QuoteResult {
+	spread := q.Spread * spreadMultiplier(input)
+	result := QuoteResult{
+		Reservation: input.Mid,
+		Spread:      spread,
+		Bid:         input.Mid - spread/2,
+		Ask:         input.Mid + spread/2,
+		Reasoning:   fmt.Sprintf("Symmetric %.4f spread around mid", spread),
+	}
+	return boundQuote(result, input.TickSize)
+}
+
+// InventoryQuoter implements the Avellaneda-Stoikov market making model: the reservation
+// price is skewed against our inventory so that fills tend to flatten the position, and
+// the spread balances order arrival against inventory risk.
+//
+//	reservation = mid - q * gamma * sigma^2 * T
+//	spread      = gamma * sigma^2 * T + (2/gamma) * ln(1 + gamma/k)
+type InventoryQuoter struct {
+	RiskAversion float64 // gamma: higher skews harder against inventory (default 0.1)
+	OrderArrival float64 // k: decay of fill intensity with distance from mid, per unit price (default 100)
+	Horizon      float64 // Days of inventory risk to price; caps time to resolution (default 1)
+	MinSpread    float64 // Floor on the spread (default one tick)
+}
+
+// Quote implements Quoter
+func (q InventoryQuoter) Quote(input QuoteInput) QuoteResult {
+	gamma := q.RiskAversion
+	if gamma <= 0 {
+		gamma = 0.1
+	}
+	k := q.OrderArrival
+	if k <= 0 {
+		k = 100
+	}
+	horizon := q.Horizon
+	if horizon <= 0 {
+		horizon = 1
+	}
+
+	t := horizon
+	if input.TimeToResolution > 0 {
+		t = math.Min(input.TimeToResolution, horizon)
+	}
+
+	variance := input.Volatility * input.Volatility * t
+	reservation := input.Mid - input.Inventory*gamma*variance
+	spread := (gamma*variance + (2/gamma)*math.Log(1+gamma/k)) * spreadMultiplier(input)
+
+	minSpread := q.MinSpread
+	if minSpread <= 0 {
+		minSpread = input.TickSize
+	}
+	spread = math.Max(spread, minSpread)
+
+	result := QuoteResult{
+		Reservation: reservation,
+		Spread:      spread,
+		Bid:         reservation - spread/2,
+		Ask:         reservation + spread/2,
+		Reasoning: fmt.Sprintf("Inventory %.0f skews reservation %.4f -> %.4f; spread %.4f (gamma %.2f, sigma %.4f, %.1fd)",
+			input.Inventory, input.Mid, reservation, spread, gamma, input.Volatility, t),
+	}
+	return boundQuote(result, input.TickSize)
+}
+
+func spreadMultiplier(input QuoteInput) float64 {
+	if input.SpreadMultiplier <= 0 {
+		return 1
+	}
+	return input.SpreadMultiplier
+}
+
+// boundQuote snaps a quote outward to the tick grid and keeps both sides inside (0,1)
+func boundQuote(result QuoteResult, tick float64) QuoteResult {
+	floor := math.Max(tick, probabilityEpsilon)
+
+	result.Bid = floorToTick(result.Bid, tick)
+	result.Ask = ceilToTick(result.Ask, tick)
+
+	result.Bid = math.Min(math.Max(result.Bid, floor), 1-floor-tick)
+	result.Ask = math.Max(math.Min(result.Ask, 1-floor), result.Bid+math.Max(tick, probabilityEpsilon))
+
+	return result
+}
+
+// RealizedVolatility estimates the standard deviation of price changes per square-root
+// day from prices sampled at a fixed interval
+func RealizedVolatility(prices []float64, interval time.Duration) float64 {
+	if len(prices) < 3 || interval <= 0 {
+		return 0
+	}
+
+	var sum, sumSq float64
+	n := float64(len(prices) - 1)
+	for i := 1; i < len(prices); i++ {
+		d := prices[i] - prices[i-1]
+		sum += d
+		sumSq += d * d
+	}
+	variance := (sumSq - sum*sum/n) / (n - 1)
+	if variance <= 0 {
+		return 0
+	}
+
+	perDay := float64(24*time.Hour) / float64(interval)
+	return math.Sqrt(variance * perDay)
+}
+
+// TimeToResolution returns the days until the market's end date, or 0 if unknown
+func (m Market) TimeToResolution(now time.Time) float64 {
+	end, ok := m.EndTime()
+	if !ok || !end.After(now) {
+		return 0
+	}
+	return end.Sub(now).Hours() / 24
+}
+
+// EndTime parses the market's end date
+func (m Market) EndTime() (time.Time, bool) {
+	return parseMarketDate(m.EndDate)
+}
+
+// parseMarketDate parses a Gamma date field
+func parseMarketDate(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,204 @@
+package marketmaker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// BankrollSource reports the capital available to trade with
+type BankrollSource interface {
+	Bankroll() (float64, error)
+}
+
+// FixedBankroll is a bankroll set in configuration
+type FixedBankroll float64
+
+// Bankroll implements BankrollSource
+func (b FixedBankroll) Bankroll() (float64, error) {
+	if b <= 0 {
+		return 0, fmt.Errorf("bankroll must be positive, got %.2f", float64(b))
+	}
+	return float64(b), nil
+}
+
+// BankrollFunc adapts a function, such as an exchange balance lookup, to BankrollSource
+type BankrollFunc func() (float64, error)
+
+// Bankroll implements BankrollSource
+func (f BankrollFunc) Bankroll() (float64, error) {
+	return f()
+}
+
+// RiskProfile holds the sizing limits for a kind of market
+type RiskProfile struct {
+	Name          string
+	KellyFraction float64                    // Fraction of full Kelly to bet
+	MaxKelly      float64                    // Cap on the Kelly fraction of bankroll for one market
+	MinTicket     float64                    // Smallest order worth placing, in dollars
+	MaxTicket     float64                    // Largest order per market side, in dollars
+	MaxExposure   float64                    // Cap on total stake as a fraction of bankroll
+	CategoryCaps  map[MarketCategory]float64 // Cap on stake per category as a fraction of bankroll
+	Bankroll      BankrollSource             // Where the bankroll comes from; nil must be set before sizing
+}
+
+// DustRiskProfile sizes illiquid markets: tiny tickets and a small total footprint
+var DustRiskProfile = RiskProfile{
+	Name:          "dust",
+	KellyFraction: 0.25,
+	MaxKelly:      0.10,
+	MinTicket:     5,
+	MaxTicket:     20,
+	MaxExposure:   0.10,
+	// Every category needs a cap: priced event markets are mostly classed by their observed
+	// probability, as longshot or competitive, rather than by their text
+	CategoryCaps: map[MarketCategory]float64{
+		CategorySports:      0.05,
+		CategoryPolitics:    0.05,
+		CategoryEconomic:    0.05,
+		CategoryLongshot:    0.05,
+		CategoryCompetitive: 0.05,
+		CategoryUnknown:     0.03,
+	},
+}
+
+// ActiveRiskProfile sizes liquid markets with real two-sided flow
+var ActiveRiskProfile = RiskProfile{
+	Name:          "active",
+	KellyFraction: 0.25,
+	MaxKelly:      0.10,
+	MinTicket:     100,
+	MaxTicket:     500,
+	MaxExposure:   0.20,
+}
+
+// RiskProfiles are the named profiles selectable from configuration
+var RiskProfiles = map[string]RiskProfile{
+	DustRiskProfile.Name:   DustRiskProfile,
+	ActiveRiskProfile.Name: ActiveRiskProfile,
+}
+
+// LookupRiskProfile returns a named profile
+func LookupRiskProfile(name string) (RiskProfile, error) {
+	profile, ok := RiskProfiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		names := make([]string, 0, len(RiskProfiles))
+		for n := range RiskProfiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return RiskProfile{}, fmt.Errorf("unknown risk profile %q (want one of %s)", name, strings.Join(names, ", "))
+	}
+	return profile.Clone(), nil
+}
+
+// Clone returns a copy whose category caps can be changed without touching the original
+func (r RiskProfile) Clone() RiskProfile {
+	if r.CategoryCaps != nil {
+		caps := make(map[MarketCategory]float64, len(r.CategoryCaps))
+		for category, limit := range r.CategoryCaps {
+			caps[category] = limit
+		}
+		r.CategoryCaps = caps
+	}
+	return r
+}
+
+// Validate checks that the limits are consistent
+func (r RiskProfile) Validate() error {
+	var errs []error
+	if r.KellyFraction <= 0 || r.KellyFraction > 1 {
+		errs = append(errs, fmt.Errorf("kelly fraction %.3f outside (0,1]", r.KellyFraction))
+	}
+	if r.MaxKelly <= 0 || r.MaxKelly > 1 {
+		errs = append(errs, fmt.Errorf("max kelly %.3f outside (0,1]", r.MaxKelly))
+	}
+	if r.MinTicket < 0 || r.MaxTicket <= 0 || r.MinTicket > r.MaxTicket {
+		errs = append(errs, fmt.Errorf("ticket range $%.2f-$%.2f is invalid", r.MinTicket, r.MaxTicket))
+	}
+	if r.MaxExposure <= 0 || r.MaxExposure > 1 {
+		errs = append(errs, fmt.Errorf("max exposure %.3f outside (0,1]", r.MaxExposure))
+	}
+	for category, limit := range r.CategoryCaps {
+		if limit < 0 || limit > 1 {
+			errs = append(errs, fmt.Errorf("%s cap %.3f outside [0,1]", category, limit))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CapExposure scales stakes down so that each category, and the total, stays within the
+// profile's limits. Stakes are in dollars; categories give each stake's category.
+func (r RiskProfile) CapExposure(stakes []float64, categories []MarketCategory, bankroll float64) []float64 {
+	capped := append([]float64(nil), stakes...)
+
+	totals := make(map[MarketCategory]float64)
+	for i, s := range capped {
+		totals[categories[i]] += s
+	}
+	for category, limit := range r.CategoryCaps {
+		if total := totals[category]; total > limit*bankroll {
+			scale := limit * bankroll / total
+			for i := range capped {
+				if categories[i] == category {
+					capped[i] *= scale
+				}
+			}
+		}
+	}
+
+	total := 0.0
+	for _, s := range capped {
+		total += s
+	}
+	if limit := r.MaxExposure * bankroll; r.MaxExposure > 0 && total > limit {
+		for i := range capped {
+			capped[i] *= limit / total
+		}
+	}
+	return capped
+}
+
+// Strategy returns a pricing strategy that sizes with the config's risk profile
+func (c *Config) Strategy() *PricingStrategy {
+	return &PricingStrategy{Risk: c.Risk, Toxicity: c.Toxicity}
+}
+
+// risk returns the strategy's risk profile, defaulting to the dust profile
+func (ps *PricingStrategy) risk() RiskProfile {
+	if ps.Risk != nil {
+		return *ps.Risk
+	}
+	return DustRiskProfile
+}
+
+// Bankroll returns the capital the strategy sizes against
+func (ps *PricingStrategy) Bankroll() (float64, error) {
+	risk := ps.risk()
+	if risk.Bankroll == nil {
+		return 0, fmt.Errorf("risk profile %q has no bankroll source", risk.Name)
+	}
+	bankroll, err := risk.Bankroll.Bankroll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get bankroll: %w", err)
+	}
+	return bankroll, nil
+}
+
+// clampTicket applies the profile's ticket limits to one side's Kelly size in dollars
+func (r RiskProfile) clampTicket(side string, dollars, bankroll float64) (float64, string) {
+	maxTicket := math.Min(r.MaxTicket, r.MaxKelly*bankroll)
+	switch {
+	case dollars <= 0:
+		return 0, "no " + side + " - Kelly sees no edge"
+	case maxTicket < r.MinTicket:
+		return 0, fmt.Sprintf("no %s - bankroll too small for the $%.0f minimum ticket", side, r.MinTicket)
+	case dollars < r.MinTicket:
+		return r.MinTicket, fmt.Sprintf("%s $%.2f raised to $%.0f minimum ticket", side, dollars, r.MinTicket)
+	case dollars > maxTicket:
+		return maxTicket, fmt.Sprintf("%s $%.0f capped at $%.0f (%s profile)", side, dollars, maxTicket, r.Name)
+	}
+	return dollars, fmt.Sprintf("%s $%.2f", side, dollars)
+}
@@ -0,0 +1,23 @@
+package marketmaker
+
+import "testing"
+
+func TestDustRiskProfileCapsEveryCategory(t *testing.T) {
+	for category, name := range categoryNames {
+		if _, ok := DustRiskProfile.CategoryCaps[category]; !ok {
+			t.Errorf("dust profile has no cap for %s markets", name)
+		}
+	}
+
+	profile := DustRiskProfile.Clone()
+	profile.Bankroll = FixedBankroll(1000)
+	limits, err := RiskLimitsFor(profile)
+	if err != nil {
+		t.Fatalf("RiskLimitsFor: %v", err)
+	}
+	for _, category := range []MarketCategory{CategoryLongshot, CategoryCompetitive} {
+		if limits.MaxCategoryExposure[category] <= 0 {
+			t.Errorf("no exposure limit for %s markets", category)
+		}
+	}
+}
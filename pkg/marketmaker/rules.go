@@ -0,0 +1,493 @@
+package marketmaker
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RuleSetVersion is the rules file schema version this package understands.
+// Version 2 matches keywords on word boundaries and weights category rules.
+const RuleSetVersion = 2
+
+//go:embed rules/default.json
+var defaultRulesJSON []byte
+
+//go:embed rules/corpus.json
+var defaultCorpusJSON []byte
+
+// RuleSet holds categorization rules and price bands loaded from a rules file
+type RuleSet struct {
+	Version    int              `json:"version"`
+	Classifier ClassifierConfig `json:"classifier"`
+	Categories []CategoryRule   `json:"categories"` // Weighted features; every matching rule scores
+	Rules      []PriceRule      `json:"rules"`      // Evaluated in order within a category; first match wins
+}
+
+// CategoryRule is a classifier feature: questions matching its conditions add Weight
+// to the score of its category
+type CategoryRule struct {
+	Name     string         `json:"name,omitempty"`
+	Category string         `json:"category"`
+	Match    MatchCondition `json:"match"`
+	Weight   *float64       `json:"weight,omitempty"` // Score added when the rule matches (default 1)
+
+	category MarketCategory
+}
+
+// weight returns the score the rule adds to its category
+func (cr *CategoryRule) weight() float64 {
+	if cr.Weight != nil {
+		return *cr.Weight
+	}
+	return 1
+}
+
+// PriceRule quotes a price band for questions in a category matching its conditions.
+// A rule with an empty match condition applies to every question in its category.
+type PriceRule struct {
+	Name       string         `json:"name"`
+	Category   string         `json:"category"`
+	Match      MatchCondition `json:"match"`
+	Bid        float64        `json:"bid"`
+	Ask        float64        `json:"ask"`
+	Confidence *float64       `json:"confidence,omitempty"` // Confidence in the band (default 0.4)
+	Reasoning  string         `json:"reasoning"`
+	Scheduled  *bool          `json:"scheduled,omitempty"` // Happens on a fixed date, so the band does not decay toward a deadline (default true for economic rules)
+
+	category MarketCategory
+}
+
+// confidence returns the rule's confidence in its band
+func (r *PriceRule) confidence() float64 {
+	if r.Confidence != nil {
+		return *r.Confidence
+	}
+	return defaultRuleConfidence
+}
+
+// scheduled reports whether the rule's events happen on a fixed date. Economic events are
+// meetings and data releases, so their rules are scheduled unless they say otherwise.
+func (r *PriceRule) scheduled() bool {
+	if r.Scheduled != nil {
+		return *r.Scheduled
+	}
+	return r.category == CategoryEconomic
+}
+
+// MatchCondition is satisfied when every non-empty field matches. Keywords are words or
+// phrases matched case-insensitively on word boundaries; a trailing "*" matches any word
+// starting with the keyword ("resign*" matches "resignation"). Regexes run against the
+// lowercased question and tags against Gamma tag labels and slugs.
+type MatchCondition struct {
+	Keywords        []string `json:"keywords,omitempty"`         // Any keyword must appear
+	AllKeywords     []string `json:"all_keywords,omitempty"`     // Every keyword must appear
+	ExcludeKeywords []string `json:"exclude_keywords,omitempty"` // No keyword may appear
+	Regex           []string `json:"regex,omitempty"`            // Any pattern must match
+	AllRegex        []string `json:"all_regex,omitempty"`        // Every pattern must match
+	Tags            []string `json:"tags,omitempty"`             // Any tag must be present
+
+	regex       []*regexp.Regexp
+	allRegex    []*regexp.Regexp
+	keywords    []keywordPhrase
+	allKeywords []keywordPhrase
+	excludes    []keywordPhrase
+}
+
+// defaultRuleConfidence is used for price rules that do not set a confidence
+const defaultRuleConfidence = 0.4
+
+// ParseRuleSet parses and validates a rules file
+func ParseRuleSet(data []byte) (*RuleSet, error) {
+	var rs RuleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rules: %w", err)
+	}
+
+	if err := rs.compile(); err != nil {
+		return nil, err
+	}
+
+	return &rs, nil
+}
+
+// LoadRuleSet reads and validates a rules file from disk
+func LoadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	rs, err := ParseRuleSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return rs, nil
+}
+
+// DefaultRuleSet returns the rules compiled into the binary
+func DefaultRuleSet() *RuleSet {
+	rs, err := ParseRuleSet(defaultRulesJSON)
+	if err != nil {
+		panic("marketmaker: invalid embedded rules: " + err.Error())
+	}
+	return rs
+}
+
+// compile validates the rule set and prepares regexes and categories
+func (rs *RuleSet) compile() error {
+	var errs []error
+
+	if rs.Version != RuleSetVersion {
+		errs = append(errs, fmt.Errorf("unsupported rules version %d (want %d)", rs.Version, RuleSetVersion))
+	}
+
+	for i := range rs.Categories {
+		cr := &rs.Categories[i]
+		where := fmt.Sprintf("categories[%d]", i)
+
+		category, err := ParseMarketCategory(cr.Category)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+		cr.category = category
+
+		if cr.Match.isEmpty() {
+			errs = append(errs, fmt.Errorf("%s: match condition is empty", where))
+		}
+		if cr.weight() < 0 {
+			errs = append(errs, fmt.Errorf("%s: weight must not be negative", where))
+		}
+		if err := cr.Match.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+	}
+
+	names := make(map[string]bool)
+	catchAll := make(map[MarketCategory]string)
+
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		where := fmt.Sprintf("rules[%d] %q", i, r.Name)
+
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", where))
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate rule name", where))
+		}
+		names[r.Name] = true
+
+		category, err := ParseMarketCategory(r.Category)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+		r.category = category
+
+		if r.Bid <= 0 || r.Ask >= 1 || r.Bid >= r.Ask {
+			errs = append(errs, fmt.Errorf("%s: band must satisfy 0 < bid < ask < 1 (got %.4f/%.4f)", where, r.Bid, r.Ask))
+		}
+		if c := r.confidence(); c < 0 || c > 1 {
+			errs = append(errs, fmt.Errorf("%s: confidence must be within [0,1]", where))
+		}
+		if r.Reasoning == "" {
+			errs = append(errs, fmt.Errorf("%s: reasoning is required", where))
+		}
+
+		if err := r.Match.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+
+		if shadow, ok := catchAll[category]; ok {
+			errs = append(errs, fmt.Errorf("%s: unreachable, shadowed by catch-all rule %q", where, shadow))
+		}
+		if r.Match.isEmpty() {
+			catchAll[category] = r.Name
+		}
+	}
+
+	if err := rs.Classifier.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("classifier: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Categorize returns the classifier's top category for the question,
+// CategoryUnknown if the evidence is weak or ambiguous
+func (rs *RuleSet) Categorize(question string, tags []Tag) MarketCategory {
+	return rs.Classify(question, tags).Category
+}
+
+// MatchPriceRule returns the first price rule in the category matching the question
+func (rs *RuleSet) MatchPriceRule(category MarketCategory, question string, tags []Tag) (*PriceRule, bool) {
+	text := newQuestionText(question)
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.category == category && r.Match.matches(text, tags) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (mc *MatchCondition) isEmpty() bool {
+	return len(mc.Keywords) == 0 && len(mc.AllKeywords) == 0 && len(mc.ExcludeKeywords) == 0 &&
+		len(mc.Regex) == 0 && len(mc.AllRegex) == 0 && len(mc.Tags) == 0
+}
+
+func (mc *MatchCondition) compile() error {
+	var err error
+	if mc.keywords, err = compileKeywords(mc.Keywords); err != nil {
+		return err
+	}
+	if mc.allKeywords, err = compileKeywords(mc.AllKeywords); err != nil {
+		return err
+	}
+	if mc.excludes, err = compileKeywords(mc.ExcludeKeywords); err != nil {
+		return err
+	}
+
+	if mc.regex, err = compileRegexes(mc.Regex); err != nil {
+		return err
+	}
+	if mc.allRegex, err = compileRegexes(mc.AllRegex); err != nil {
+		return err
+	}
+	return nil
+}
+
+func compileRegexes(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matches evaluates the condition against a question
+func (mc *MatchCondition) matches(text questionText, tags []Tag) bool {
+	if len(mc.keywords) > 0 && !text.containsAny(mc.keywords) {
+		return false
+	}
+
+	for _, keyword := range mc.allKeywords {
+		if !text.contains(keyword) {
+			return false
+		}
+	}
+
+	if len(mc.excludes) > 0 && text.containsAny(mc.excludes) {
+		return false
+	}
+
+	if len(mc.regex) > 0 {
+		matched := false
+		for _, re := range mc.regex {
+			if re.MatchString(text.lower) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, re := range mc.allRegex {
+		if !re.MatchString(text.lower) {
+			return false
+		}
+	}
+
+	if len(mc.Tags) > 0 && !hasAnyTag(tags, mc.Tags) {
+		return false
+	}
+
+	return true
+}
+
+func hasAnyTag(tags []Tag, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if strings.EqualFold(tag.Label, w) || strings.EqualFold(tag.Slug, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RuleStore holds the active rule set and swaps it atomically on reload
+type RuleStore struct {
+	current atomic.Pointer[RuleSet]
+}
+
+// NewRuleStore creates a store holding the given rule set
+func NewRuleStore(rs *RuleSet) *RuleStore {
+	store := &RuleStore{}
+	store.current.Store(rs)
+	return store
+}
+
+// DefaultRules is the store used by a PricingStrategy without its own Rules
+var DefaultRules = NewRuleStore(DefaultRuleSet())
+
+// Load returns the active rule set
+func (s *RuleStore) Load() *RuleSet {
+	return s.current.Load()
+}
+
+// Store replaces the active rule set
+func (s *RuleStore) Store(rs *RuleSet) {
+	s.current.Store(rs)
+}
+
+// LoadFile loads, validates and activates a rules file. The active set is left
+// untouched if the file is invalid.
+func (s *RuleStore) LoadFile(path string) error {
+	rs, err := LoadRuleSet(path)
+	if err != nil {
+		return err
+	}
+	s.Store(rs)
+	return nil
+}
+
+// Watch polls a rules file and reloads it whenever its modification time or size changes,
+// until ctx is cancelled. Invalid files are reported to onError and the previous set is kept.
+func (s *RuleStore) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	var lastMod time.Time
+	var lastSize int64 = -1
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			if onError != nil {
+				onError(fmt.Errorf("failed to stat rules file: %w", err))
+			}
+		} else if !info.ModTime().Equal(lastMod) || info.Size() != lastSize {
+			if err := s.LoadFile(path); err != nil {
+				if onError != nil {
+					onError(err)
+				}
+			}
+			// Remember the attempt either way so a broken file is not re-reported every tick
+			lastMod, lastSize = info.ModTime(), info.Size()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RuleCase is a labeled question used to check a rule set
+type RuleCase struct {
+	Question string `json:"question"`
+	Tags     []Tag  `json:"tags,omitempty"`
+	Category string `json:"category"`       // Expected category
+	Rule     string `json:"rule,omitempty"` // Expected price rule, if checked
+}
+
+// RuleCaseResult is the outcome of checking one RuleCase
+type RuleCaseResult struct {
+	Case     RuleCase
+	Category MarketCategory
+	Rule     string
+	Passed   bool
+}
+
+// LoadRuleCorpus reads a JSON array of labeled questions
+func LoadRuleCorpus(path string) ([]RuleCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	return parseRuleCorpus(data)
+}
+
+// DefaultRuleCorpus returns the labeled questions shipped with the default rules
+func DefaultRuleCorpus() []RuleCase {
+	cases, err := parseRuleCorpus(defaultCorpusJSON)
+	if err != nil {
+		panic("marketmaker: invalid embedded corpus: " + err.Error())
+	}
+	return cases
+}
+
+func parseRuleCorpus(data []byte) ([]RuleCase, error) {
+	var cases []RuleCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal corpus: %w", err)
+	}
+	return cases, nil
+}
+
+// CheckCorpus runs every case through the rule set and reports what matched
+func (rs *RuleSet) CheckCorpus(cases []RuleCase) []RuleCaseResult {
+	results := make([]RuleCaseResult, 0, len(cases))
+	for _, c := range cases {
+		result := RuleCaseResult{Case: c}
+		result.Category = rs.Categorize(c.Question, c.Tags)
+		if rule, ok := rs.MatchPriceRule(result.Category, c.Question, c.Tags); ok {
+			result.Rule = rule.Name
+		}
+
+		result.Passed = result.Category.String() == strings.ToLower(c.Category) &&
+			(c.Rule == "" || c.Rule == result.Rule)
+		results = append(results, result)
+	}
+	return results
+}
+
+// RuleModel prices markets with the price rules of the active rule set
+type RuleModel struct {
+	Store *RuleStore // nil uses the rules carried by the input, then DefaultRules
+}
+
+// Name implements PricingModel
+func (m RuleModel) Name() string {
+	return "rules"
+}
+
+// Price implements PricingModel
+func (m RuleModel) Price(input PricingInput) (PricingQuote, bool) {
+	rs := input.Rules
+	if m.Store != nil {
+		rs = m.Store.Load()
+	}
+	if rs == nil {
+		rs = DefaultRules.Load()
+	}
+
+	rule, ok := rs.MatchPriceRule(input.Category, input.Market.Question, input.Market.Tags)
+	if !ok {
+		return PricingQuote{}, false
+	}
+
+	return PricingQuote{
+		Model:       "rules/" + rule.Name,
+		FairValue:   (rule.Bid + rule.Ask) / 2,
+		Bid:         rule.Bid,
+		Ask:         rule.Ask,
+		Confidence:  rule.confidence(),
+		Explanation: rule.Reasoning,
+	}, true
+}
@@ -0,0 +1,158 @@
+package marketmaker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRules is a minimal valid rules file; tests break one part of it at a time
+const testRules = `{
+  "version": 2,
+  "categories": [
+    {"name": "sports", "category": "sports", "match": {"keywords": ["nfl"]}}
+  ],
+  "rules": [
+    {"name": "nfl-bad", "category": "sports", "match": {"keywords": ["browns"]},
+     "bid": 0.01, "ask": 0.05, "reasoning": "bad team"},
+    {"name": "sports-default", "category": "sports", "match": {},
+     "bid": 0.02, "ask": 0.10, "reasoning": "catch-all"}
+  ]
+}`
+
+func TestParseRuleSetRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		wantErr string
+	}{
+		{"wrong version", `"version": 2`, `"version": 1`, "unsupported rules version 1"},
+		{"inverted band", `"bid": 0.01, "ask": 0.05`, `"bid": 0.05, "ask": 0.01`, "band must satisfy 0 < bid < ask < 1"},
+		{"bad regex", `"keywords": ["browns"]`, `"regex": ["browns("]`, "nfl-bad"},
+		{"confidence above one", `"reasoning": "bad team"`, `"reasoning": "bad team", "confidence": 1.5`, "confidence must be within [0,1]"},
+		{"negative weight", `"keywords": ["nfl"]}`, `"keywords": ["nfl"]}, "weight": -1`, "weight must not be negative"},
+		{"shadowed by catch-all",
+			`"rules": [`,
+			`"rules": [{"name": "early-default", "category": "sports", "match": {}, "bid": 0.02, "ask": 0.10, "reasoning": "too early"},`,
+			`unreachable, shadowed by catch-all rule "early-default"`},
+	}
+
+	if _, err := ParseRuleSet([]byte(testRules)); err != nil {
+		t.Fatalf("base rules are invalid: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(testRules, tt.old, tt.new, 1)
+			if data == testRules {
+				t.Fatalf("%q not found in the base rules", tt.old)
+			}
+			_, err := ParseRuleSet([]byte(data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleStoreWatchKeepsPreviousSetOnInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	initial := DefaultRuleSet()
+	store := NewRuleStore(initial)
+	errs := make(chan error, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, path, 5*time.Millisecond, func(err error) { errs <- err })
+
+	waitFor := func(what string, done func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor("the rules file to load", func() bool { return store.Load() != initial })
+	loaded := store.Load()
+	if len(loaded.Rules) != 2 {
+		t.Fatalf("loaded %d rules, want 2", len(loaded.Rules))
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(testRules, `"version": 2`, `"version": 99`, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "unsupported rules version") {
+			t.Errorf("reported %v, want the version error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalid rules file was not reported")
+	}
+	if store.Load() != loaded {
+		t.Error("invalid rules file replaced the active set")
+	}
+}
+
+func TestDefaultRulesPassCorpus(t *testing.T) {
+	rules := DefaultRuleSet()
+	corpus := DefaultRuleCorpus()
+	if len(corpus) == 0 {
+		t.Fatal("corpus is empty")
+	}
+	for _, result := range rules.CheckCorpus(corpus) {
+		t.Run(result.Case.Question, func(t *testing.T) {
+			if !result.Passed {
+				t.Errorf("got %s/%s, want %s/%s (labels: %s)", result.Category, result.Rule,
+					result.Case.Category, result.Case.Rule, formatLabels(rules.Classify(result.Case.Question, result.Case.Tags).Labels))
+			}
+		})
+	}
+}
+
+func TestRuleExplicitZeroIsNotDefaulted(t *testing.T) {
+	rs, err := ParseRuleSet([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rs.Categories[0].weight(); got != 1 {
+		t.Errorf("omitted weight %v, want 1", got)
+	}
+	if got := rs.Rules[0].confidence(); got != defaultRuleConfidence {
+		t.Errorf("omitted confidence %v, want %v", got, defaultRuleConfidence)
+	}
+
+	data := strings.Replace(testRules, `"keywords": ["nfl"]}`, `"keywords": ["nfl"]}, "weight": 0`, 1)
+	data = strings.Replace(data, `"reasoning": "bad team"`, `"reasoning": "bad team", "confidence": 0`, 1)
+	if rs, err = ParseRuleSet([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if got := rs.Categories[0].weight(); got != 0 {
+		t.Errorf("explicit zero weight became %v", got)
+	}
+	if got := rs.Rules[0].confidence(); got != 0 {
+		t.Errorf("explicit zero confidence became %v", got)
+	}
+	if class := rs.Classify("Will the NFL expand?", nil); class.Category != CategoryUnknown {
+		t.Errorf("zero-weight feature classified %s, want unknown", class.Category)
+	}
+}
+
+func TestClassifierConfigZeroUsesDefaults(t *testing.T) {
+	var cfg ClassifierConfig
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if cfg.AmbiguityRatio != 0.75 || cfg.MinScore != 1 || cfg.LabelThreshold != 0.2 || cfg.PriorWeight != 1 {
+		t.Errorf("zero config became %+v, want the defaults", cfg)
+	}
+}
@@ -2,6 +2,7 @@ package marketmaker
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -16,6 +17,10 @@ func (mm *MarketMaker) FindIlliquidMarkets() ([]Opportunity, error) {
 	}
 
 	var opportunities []Opportunity
+	events := GroupEvents(markets)
+	if err := mm.CompleteEvents(events); err != nil {
+		fmt.Printf("Some events stay partial and are priced market by market: %v\n", err)
+	}
 
 	fmt.Printf("Scanning %d markets for illiquid orderbooks...\n", len(markets))
 
@@ -69,9 +74,7 @@ func (mm *MarketMaker) FindIlliquidMarkets() ([]Opportunity, error) {
 
 		// CORE LOGIC: Detect placeholder orderbooks
 		// Placeholder: bid <= 0.01, ask >= 0.99 (99,800% spread)
-		isPlaceholder := bestBid <= 0.01 && bestAsk >= 0.99
-
-		if !isPlaceholder {
+		if !isPlaceholder(bestBid, bestAsk) {
 			// Not an illiquid market, skip
 			time.Sleep(50 * time.Millisecond)
 			continue
@@ -81,11 +84,7 @@ func (mm *MarketMaker) FindIlliquidMarkets() ([]Opportunity, error) {
 		volume := parseVolume(market.Volume24hr)
 
 		// Calculate spread
-		spread := bestAsk - bestBid
-		spreadPct := 0.0
-		if bestBid > 0 {
-			spreadPct = spread / bestBid
-		}
+		spread := NewSpreadMetrics(bestBid, bestAsk)
 
 		// For illiquid markets, suggest initial pricing
 		// Use conservative wide spreads for safety (per RISKS_AND_MITIGATION.md)
@@ -102,10 +101,13 @@ func (mm *MarketMaker) FindIlliquidMarkets() ([]Opportunity, error) {
 			Volume:             volume,
 			BestBid:            bestBid,
 			BestAsk:            bestAsk,
-			SpreadPct:          spreadPct,
+			Spread:             spread,
 			SuggestedBuyPrice:  suggestedBuyPrice,
 			SuggestedSellPrice: suggestedSellPrice,
 			IsIlliquid:         true,
+			Market:             market,
+			Book:               book,
+			Event:              events.For(market),
 		})
 
 		time.Sleep(50 * time.Millisecond) // Rate limiting
@@ -115,7 +117,8 @@ func (mm *MarketMaker) FindIlliquidMarkets() ([]Opportunity, error) {
 }
 
 // FindActiveMarkets finds markets with real liquidity (NOT placeholders)
-// These are markets where other traders are already active
+// These are markets where other traders are already active. Suggested prices assume no
+// inventory; the quoting engine requotes them with live positions.
 func (mm *MarketMaker) FindActiveMarkets() ([]Opportunity, error) {
 	markets, err := mm.FetchMarkets()
 	if err != nil {
@@ -123,6 +126,10 @@ func (mm *MarketMaker) FindActiveMarkets() ([]Opportunity, error) {
 	}
 
 	var opportunities []Opportunity
+	events := GroupEvents(markets)
+	if err := mm.CompleteEvents(events); err != nil {
+		fmt.Printf("Some events stay partial and are priced market by market: %v\n", err)
+	}
 
 	fmt.Printf("Scanning %d markets for active liquidity...\n", len(markets))
 
@@ -168,7 +175,7 @@ func (mm *MarketMaker) FindActiveMarkets() ([]Opportunity, error) {
 		}
 
 		// Filter OUT placeholder orderbooks
-		if bestBid <= 0.01 && bestAsk >= 0.99 {
+		if isPlaceholder(bestBid, bestAsk) {
 			time.Sleep(50 * time.Millisecond)
 			continue // Skip placeholders
 		}
@@ -181,37 +188,127 @@ func (mm *MarketMaker) FindActiveMarkets() ([]Opportunity, error) {
 
 		volume := parseVolume(market.Volume24hr)
 
-		spread := bestAsk - bestBid
-		spreadPct := 0.0
-		if bestBid > 0 {
-			spreadPct = spread / bestBid
-		}
+		spread := NewSpreadMetrics(bestBid, bestAsk)
 
 		// Check if spread is wide enough for market making
-		if spreadPct < mm.config.MinSpreadPct {
+		if !mm.config.wideEnough(spread) {
 			time.Sleep(50 * time.Millisecond)
 			continue
 		}
 
-		// Calculate suggested prices (place orders inside current spread)
-		mid := (bestBid + bestAsk) / 2
-		suggestedBuyPrice := mid - (mm.config.TargetSpreadPct / 2)
-		suggestedSellPrice := mid + (mm.config.TargetSpreadPct / 2)
+		opp := Opportunity{
+			Question:   market.Question,
+			TokenID:    tokenID,
+			Volume:     volume,
+			BestBid:    bestBid,
+			BestAsk:    bestAsk,
+			Spread:     spread,
+			IsIlliquid: false,
+			Market:     market,
+			Book:       book,
+			Event:      events.For(market),
+		}
 
-		opportunities = append(opportunities, Opportunity{
-			Question:           market.Question,
-			TokenID:            tokenID,
-			Volume:             volume,
-			BestBid:            bestBid,
-			BestAsk:            bestAsk,
-			SpreadPct:          spreadPct,
-			SuggestedBuyPrice:  suggestedBuyPrice,
-			SuggestedSellPrice: suggestedSellPrice,
-			IsIlliquid:         false,
+		// Calculate suggested prices (place orders inside current spread). The scanner
+		// holds no positions, so Inventory is 0 and the quote is unskewed.
+		quote := mm.quoter().Quote(QuoteInput{
+			Mid:              spread.Mid,
+			Volatility:       mm.volatility(tokenID),
+			TimeToResolution: market.TimeToResolution(time.Now()),
+			TickSize:         opp.OrderOptions().TickSize,
+			SpreadMultiplier: mm.spreadMultiplier(tokenID, market, opp.Event),
 		})
+		opp.SuggestedBuyPrice = quote.Bid
+		opp.SuggestedSellPrice = quote.Ask
+		opportunities = append(opportunities, opp)
 
 		time.Sleep(50 * time.Millisecond)
 	}
 
 	return opportunities, nil
 }
+
+// EventIndex maps Gamma event IDs to the markets of that event seen in a scan
+type EventIndex map[string]*EventContext
+
+// GroupEvents groups markets by the first event they belong to. The scan may not have
+// seen every market of an event, so each is partial until CompleteEvents fetches it.
+func GroupEvents(markets []Market) EventIndex {
+	index := make(EventIndex)
+	for _, market := range markets {
+		if len(market.Events) == 0 {
+			continue
+		}
+		event := market.Events[0]
+		ctx, ok := index[event.ID]
+		if !ok {
+			ctx = &EventContext{Title: event.Title, Partial: true}
+			index[event.ID] = ctx
+		}
+		ctx.Markets = append(ctx.Markets, market)
+	}
+	return index
+}
+
+// CompleteEvents fills in every open market of each negative-risk event in the index, so
+// the event can be normalized. An event that fails to load stays partial.
+func (mm *MarketMaker) CompleteEvents(index EventIndex) error {
+	var errs []error
+	for id, ctx := range index {
+		negRisk := false
+		for _, m := range ctx.Markets {
+			negRisk = negRisk || m.NegRisk
+		}
+		if !negRisk {
+			continue
+		}
+
+		full, err := mm.FetchEvent(id)
+		time.Sleep(50 * time.Millisecond) // Rate limiting
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %q: %w", ctx.Title, err))
+			continue
+		}
+		ctx.Markets, ctx.Partial = full.Markets, false
+	}
+	return errors.Join(errs...)
+}
+
+// For returns the event context of a market, or nil if it has none
+func (idx EventIndex) For(market Market) *EventContext {
+	if len(market.Events) == 0 {
+		return nil
+	}
+	return idx[market.Events[0].ID]
+}
+
+// quoter returns the configured quoting model, defaulting to TargetSpreadPct around mid
+func (mm *MarketMaker) quoter() Quoter {
+	if mm.config.Quoter != nil {
+		return mm.config.Quoter
+	}
+	return SymmetricQuoter{Spread: mm.config.TargetSpreadPct}
+}
+
+// volatility estimates a token's realized volatility per square-root day from its past
+// week of hourly prices. Only a configured Quoter uses volatility, so the default skips
+// the fetch; 0 means unknown.
+func (mm *MarketMaker) volatility(tokenID string) float64 {
+	if mm.config.Quoter == nil {
+		return 0
+	}
+	prices, err := mm.GetPriceHistory(tokenID)
+	if err != nil {
+		return 0
+	}
+	return RealizedVolatility(prices, priceHistoryFidelity)
+}
+
+// spreadMultiplier returns the widening learned from fills on this market
+func (mm *MarketMaker) spreadMultiplier(tokenID string, market Market, event *EventContext) float64 {
+	if mm.config.Toxicity == nil {
+		return 1
+	}
+	category := (&PricingStrategy{}).CategorizeMarketData(market, event)
+	return mm.config.Toxicity.Multiplier(tokenID, category)
+}
@@ -0,0 +1,148 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+)
+
+// SpreadMetric selects which spread definition a threshold or report uses
+type SpreadMetric int
+
+const (
+	SpreadRelativeToMid SpreadMetric = iota // (ask-bid)/mid as a fraction (0.002 = 0.2%)
+	SpreadAbsoluteCents                     // ask-bid in cents (1.0 = one cent)
+	SpreadBasisPoints                       // (ask-bid)/mid in basis points (20 = 0.2%)
+	SpreadLogOdds                           // logit(ask) - logit(bid)
+)
+
+// String returns a short label for the metric
+func (m SpreadMetric) String() string {
+	switch m {
+	case SpreadRelativeToMid:
+		return "relative-to-mid"
+	case SpreadAbsoluteCents:
+		return "cents"
+	case SpreadBasisPoints:
+		return "bps"
+	case SpreadLogOdds:
+		return "log-odds"
+	default:
+		return fmt.Sprintf("SpreadMetric(%d)", int(m))
+	}
+}
+
+// SpreadMetrics describes a bid/ask spread under every supported definition.
+// Relative measures use the mid rather than the bid, so cheap tokens no longer
+// report spreads in the tens of thousands of percent.
+type SpreadMetrics struct {
+	Bid           float64
+	Ask           float64
+	Mid           float64
+	Cents         float64 // ask - bid, in cents
+	RelativeToMid float64 // (ask - bid) / mid
+	BasisPoints   float64 // (ask - bid) / mid * 10,000
+	LogOdds       float64 // logit(ask) - logit(bid)
+}
+
+// NewSpreadMetrics computes all spread definitions for a bid/ask pair
+func NewSpreadMetrics(bid, ask float64) SpreadMetrics {
+	s := SpreadMetrics{
+		Bid:   bid,
+		Ask:   ask,
+		Mid:   (bid + ask) / 2,
+		Cents: (ask - bid) * 100,
+	}
+
+	if s.Mid > 0 {
+		s.RelativeToMid = (ask - bid) / s.Mid
+		s.BasisPoints = s.RelativeToMid * 10000
+	}
+
+	s.LogOdds = logit(ask) - logit(bid)
+
+	return s
+}
+
+// Value returns the spread under the given definition
+func (s SpreadMetrics) Value(metric SpreadMetric) float64 {
+	switch metric {
+	case SpreadAbsoluteCents:
+		return s.Cents
+	case SpreadBasisPoints:
+		return s.BasisPoints
+	case SpreadLogOdds:
+		return s.LogOdds
+	default:
+		return s.RelativeToMid
+	}
+}
+
+// Format renders the spread under the given definition with its unit
+func (s SpreadMetrics) Format(metric SpreadMetric) string {
+	switch metric {
+	case SpreadAbsoluteCents:
+		return fmt.Sprintf("%.2f¢", s.Cents)
+	case SpreadBasisPoints:
+		return fmt.Sprintf("%.1f bps", s.BasisPoints)
+	case SpreadLogOdds:
+		return fmt.Sprintf("%.3f logit", s.LogOdds)
+	default:
+		return fmt.Sprintf("%.3f%%", s.RelativeToMid*100)
+	}
+}
+
+// defaultMinSpread is the relative-to-mid spread a market needs when Config.MinSpread is unset
+const defaultMinSpread = 0.002
+
+// wideEnough reports whether a spread clears the configured minimum
+func (c *Config) wideEnough(s SpreadMetrics) bool {
+	if c.MinSpread <= 0 {
+		return s.RelativeToMid >= defaultMinSpread
+	}
+	return s.Value(c.SpreadMetric) >= c.MinSpread
+}
+
+// probabilityEpsilon keeps probabilities away from 0 and 1 in log-odds space
+const probabilityEpsilon = 1e-6
+
+// logit converts a probability to log-odds, clamping at the boundaries
+func logit(p float64) float64 {
+	p = math.Max(probabilityEpsilon, math.Min(1-probabilityEpsilon, p))
+	return math.Log(p / (1 - p))
+}
+
+// logistic converts log-odds back to a probability
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// tickSizeFor returns Polymarket's tick size at a price: a tenth of a cent near
+// the extremes, a cent elsewhere
+func tickSizeFor(price float64) float64 {
+	if price < 0.04 || price > 0.96 {
+		return 0.001
+	}
+	return 0.01
+}
+
+// floorToTick rounds a price down to the tick grid
+func floorToTick(price, tick float64) float64 {
+	if tick <= 0 {
+		return price
+	}
+	// The epsilon absorbs float noise such as 0.29/0.01 = 28.999999999999996
+	return cleanPrice(math.Floor(price/tick+1e-9) * tick)
+}
+
+// ceilToTick rounds a price up to the tick grid
+func ceilToTick(price, tick float64) float64 {
+	if tick <= 0 {
+		return price
+	}
+	return cleanPrice(math.Ceil(price/tick-1e-9) * tick)
+}
+
+// cleanPrice strips float noise left over from tick arithmetic
+func cleanPrice(price float64) float64 {
+	return math.Round(price*1e9) / 1e9
+}
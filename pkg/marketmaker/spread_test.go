@@ -0,0 +1,52 @@
+package marketmaker
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewSpreadMetrics(t *testing.T) {
+	s := NewSpreadMetrics(0.40, 0.44)
+	want := map[SpreadMetric]float64{
+		SpreadRelativeToMid: 0.04 / 0.42,
+		SpreadAbsoluteCents: 4,
+		SpreadBasisPoints:   0.04 / 0.42 * 10000,
+		SpreadLogOdds:       logit(0.44) - logit(0.40),
+	}
+	for metric, w := range want {
+		if got := s.Value(metric); math.Abs(got-w) > 1e-9 {
+			t.Errorf("%s: %.6f, want %.6f", metric, got, w)
+		}
+	}
+	if math.Abs(s.Mid-0.42) > 1e-9 {
+		t.Errorf("mid %v, want 0.42", s.Mid)
+	}
+
+	// A placeholder book is wide relative to its mid, not to its bid
+	if got := NewSpreadMetrics(0.001, 0.999).RelativeToMid; math.Abs(got-1.996) > 1e-9 {
+		t.Errorf("placeholder spread %.4f of mid, want 1.996", got)
+	}
+	if got := NewSpreadMetrics(0, 0).RelativeToMid; got != 0 {
+		t.Errorf("empty book spread %v, want 0", got)
+	}
+}
+
+func TestMinSpreadUsesMetricUnits(t *testing.T) {
+	s := NewSpreadMetrics(0.500, 0.502) // 0.4% of mid, 40 bps, 0.2 cents
+	tests := []struct {
+		config Config
+		want   bool
+	}{
+		{Config{}, true}, // 0.2% of mid by default
+		{Config{MinSpread: 0.005}, false},
+		{Config{MinSpread: 30, SpreadMetric: SpreadBasisPoints}, true},
+		{Config{MinSpread: 50, SpreadMetric: SpreadBasisPoints}, false},
+		{Config{MinSpread: 1, SpreadMetric: SpreadAbsoluteCents}, false},
+		{Config{SpreadMetric: SpreadLogOdds}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.config.wideEnough(s); got != tt.want {
+			t.Errorf("min %v %s: wide enough %v, want %v", tt.config.MinSpread, tt.config.SpreadMetric, got, tt.want)
+		}
+	}
+}
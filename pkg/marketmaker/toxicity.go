@@ -0,0 +1,300 @@
+package marketmaker
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fill is one of our orders trading
+type Fill struct {
+	TokenID  string
+	Category MarketCategory
+	Side     Side
+	Price    float64
+	Size     float64
+	Time     time.Time
+}
+
+// Markout is how the price moved after a fill, in log-odds from our side: positive means
+// the trade was good for us, negative means we were picked off
+type Markout struct {
+	Fill    Fill
+	Horizon time.Duration
+	Mid     float64 // First mid observed at or after the horizon
+	Value   float64
+	Toxic   bool
+}
+
+// MarkoutStats summarizes resolved markouts at one horizon
+type MarkoutStats struct {
+	Count int
+	Toxic int
+	Mean  float64 // Average markout in log-odds
+}
+
+// DefaultMarkoutHorizons are the delays after a fill at which the price is checked
+var DefaultMarkoutHorizons = []time.Duration{time.Minute, time.Hour, 24 * time.Hour}
+
+// ToxicityTracker measures adverse selection from post-fill price drift and turns it into a
+// spread multiplier per token and per category. Toxic fills widen the spread at once;
+// benign ones tighten it back toward normal slowly.
+type ToxicityTracker struct {
+	Horizons       []time.Duration // Markout horizons (default 1m, 1h, 1d)
+	ToxicThreshold float64         // Adverse log-odds drift counted as toxic (default 0.2)
+	WidenRate      float64         // Multiplier growth per threshold of adverse drift (default 0.25)
+	TightenRate    float64         // Fraction of the excess multiplier removed per benign markout (default 0.05)
+	MaxMultiplier  float64         // Cap on widening (default 4)
+	Grace          time.Duration   // How long past its horizon a markout waits for a mid before it is dropped (default 1h)
+
+	mu         sync.Mutex
+	pending    []Markout
+	tokens     map[string]*toxicityState
+	categories map[MarketCategory]*toxicityState
+}
+
+// toxicityState is the learned widening for one token or category
+type toxicityState struct {
+	multiplier float64
+	stats      map[time.Duration]*MarkoutStats
+}
+
+// NewToxicityTracker creates a tracker with default settings
+func NewToxicityTracker() *ToxicityTracker {
+	return &ToxicityTracker{}
+}
+
+// RecordFill schedules markouts for a fill, dropping any that went unresolved for the grace
+// period past their horizon
+func (t *ToxicityTracker) RecordFill(fill Fill) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining := t.pending[:0]
+	for _, m := range t.pending {
+		if !t.expired(m, fill.Time) {
+			remaining = append(remaining, m)
+		}
+	}
+	t.pending = remaining
+	for _, h := range t.horizons() {
+		t.pending = append(t.pending, Markout{Fill: fill, Horizon: h})
+	}
+}
+
+// Observe records the token's current mid, resolving every markout whose horizon has
+// passed, and returns the markouts it resolved. Markouts on any token that went unresolved
+// for the grace period past their horizon are dropped first: a mid seen that late does not
+// measure the horizon.
+func (t *ToxicityTracker) Observe(tokenID string, mid float64, now time.Time) []Markout {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var resolved []Markout
+	remaining := t.pending[:0]
+	for _, m := range t.pending {
+		if t.expired(m, now) {
+			continue
+		}
+		if m.Fill.TokenID != tokenID || now.Before(m.Fill.Time.Add(m.Horizon)) {
+			remaining = append(remaining, m)
+			continue
+		}
+		m.Mid = mid
+		m.Value = m.Fill.Side.sign() * (logit(mid) - logit(m.Fill.Price))
+		m.Toxic = m.Value < -t.toxicThreshold()
+		resolved = append(resolved, m)
+
+		t.update(t.tokenState(m.Fill.TokenID), m)
+		t.update(t.categoryState(m.Fill.Category), m)
+	}
+	t.pending = remaining
+
+	return resolved
+}
+
+// Multiplier returns the spread widening for a token, taking the worse of what was learned
+// for the token itself and for its category. A nil tracker never widens.
+func (t *ToxicityTracker) Multiplier(tokenID string, category MarketCategory) float64 {
+	if t == nil {
+		return 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := 1.0
+	if s, ok := t.tokens[tokenID]; ok {
+		m = math.Max(m, s.multiplier)
+	}
+	if s, ok := t.categories[category]; ok {
+		m = math.Max(m, s.multiplier)
+	}
+	return m
+}
+
+// Stats returns markout statistics by horizon for a token
+func (t *ToxicityTracker) Stats(tokenID string) map[time.Duration]MarkoutStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return copyStats(t.tokens[tokenID])
+}
+
+// CategoryStats returns markout statistics by horizon for a category
+func (t *ToxicityTracker) CategoryStats(category MarketCategory) map[time.Duration]MarkoutStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return copyStats(t.categories[category])
+}
+
+// Pending returns the number of markouts waiting for their horizon
+func (t *ToxicityTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+// update folds a resolved markout into a state's statistics and multiplier
+func (t *ToxicityTracker) update(s *toxicityState, m Markout) {
+	stats, ok := s.stats[m.Horizon]
+	if !ok {
+		stats = &MarkoutStats{}
+		s.stats[m.Horizon] = stats
+	}
+	stats.Count++
+	stats.Mean += (m.Value - stats.Mean) / float64(stats.Count)
+
+	if m.Toxic {
+		stats.Toxic++
+		severity := math.Min(-m.Value/t.toxicThreshold(), 3)
+		s.multiplier = math.Min(t.maxMultiplier(), s.multiplier*(1+t.widenRate()*severity))
+		return
+	}
+	s.multiplier = 1 + (s.multiplier-1)*(1-t.tightenRate())
+}
+
+func (t *ToxicityTracker) tokenState(tokenID string) *toxicityState {
+	if t.tokens == nil {
+		t.tokens = make(map[string]*toxicityState)
+	}
+	s, ok := t.tokens[tokenID]
+	if !ok {
+		s = newToxicityState()
+		t.tokens[tokenID] = s
+	}
+	return s
+}
+
+func (t *ToxicityTracker) categoryState(category MarketCategory) *toxicityState {
+	if t.categories == nil {
+		t.categories = make(map[MarketCategory]*toxicityState)
+	}
+	s, ok := t.categories[category]
+	if !ok {
+		s = newToxicityState()
+		t.categories[category] = s
+	}
+	return s
+}
+
+func newToxicityState() *toxicityState {
+	return &toxicityState{multiplier: 1, stats: make(map[time.Duration]*MarkoutStats)}
+}
+
+func copyStats(s *toxicityState) map[time.Duration]MarkoutStats {
+	out := make(map[time.Duration]MarkoutStats)
+	if s == nil {
+		return out
+	}
+	for h, stats := range s.stats {
+		out[h] = *stats
+	}
+	return out
+}
+
+// expired reports whether a markout's token went unobserved for the grace period past its
+// horizon, so a late mid would no longer measure the fill
+func (t *ToxicityTracker) expired(m Markout, now time.Time) bool {
+	return now.After(m.Fill.Time.Add(m.Horizon + t.grace()))
+}
+
+func (t *ToxicityTracker) grace() time.Duration {
+	if t.Grace > 0 {
+		return t.Grace
+	}
+	return time.Hour
+}
+
+func (t *ToxicityTracker) horizons() []time.Duration {
+	if len(t.Horizons) > 0 {
+		return t.Horizons
+	}
+	return DefaultMarkoutHorizons
+}
+
+func (t *ToxicityTracker) toxicThreshold() float64 {
+	if t.ToxicThreshold > 0 {
+		return t.ToxicThreshold
+	}
+	return 0.2
+}
+
+func (t *ToxicityTracker) widenRate() float64 {
+	if t.WidenRate > 0 {
+		return t.WidenRate
+	}
+	return 0.25
+}
+
+func (t *ToxicityTracker) tightenRate() float64 {
+	if t.TightenRate > 0 {
+		return t.TightenRate
+	}
+	return 0.05
+}
+
+func (t *ToxicityTracker) maxMultiplier() float64 {
+	if t.MaxMultiplier > 1 {
+		return t.MaxMultiplier
+	}
+	return 4
+}
+
+// FormatStats renders markout statistics in horizon order
+func FormatStats(stats map[time.Duration]MarkoutStats) string {
+	horizons := make([]time.Duration, 0, len(stats))
+	for h := range stats {
+		horizons = append(horizons, h)
+	}
+	sort.Slice(horizons, func(i, j int) bool { return horizons[i] < horizons[j] })
+
+	out := ""
+	for i, h := range horizons {
+		if i > 0 {
+			out += " | "
+		}
+		s := stats[h]
+		out += fmt.Sprintf("%v: %+.3f (%d/%d toxic)", h, s.Mean, s.Toxic, s.Count)
+	}
+	return out
+}
+
+// widenQuote scales the distance from fair value to each side of a quote in log-odds
+func widenQuote(quote PricingQuote, multiplier float64) PricingQuote {
+	if multiplier <= 1 || quote.FairValue <= 0 || quote.FairValue >= 1 {
+		return quote
+	}
+
+	fair := logit(quote.FairValue)
+	bid := logistic(fair - (fair-logit(quote.Bid))*multiplier)
+	ask := logistic(fair + (logit(quote.Ask)-fair)*multiplier)
+
+	if quote.Bid > 0 {
+		quote.Bid = math.Max(tickSizeFor(bid), floorToTick(bid, tickSizeFor(bid)))
+	}
+	quote.Ask = math.Min(1-tickSizeFor(ask), ceilToTick(ask, tickSizeFor(ask)))
+	note := fmt.Sprintf("spread widened %.2fx after toxic fills", multiplier)
+	quote.Explanation += " | " + note
+	return quote.withStep("toxicity", "", note, map[string]float64{"multiplier": multiplier})
+}
@@ -0,0 +1,52 @@
+package marketmaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToxicityTrackerDropsStaleMarkouts(t *testing.T) {
+	tox := NewToxicityTracker()
+	tox.Horizons = []time.Duration{time.Minute}
+	start := time.Unix(1_700_000_000, 0)
+
+	tox.RecordFill(Fill{TokenID: "quiet", Side: SideBuy, Price: 0.1, Size: 10, Time: start})
+	tox.RecordFill(Fill{TokenID: "busy", Side: SideBuy, Price: 0.1, Size: 10, Time: start})
+
+	// Within the grace period the quiet token's markout still waits for a mid
+	tox.Observe("busy", 0.1, start.Add(30*time.Minute))
+	if got := tox.Pending(); got != 1 {
+		t.Fatalf("%d markouts pending, want the quiet token's 1", got)
+	}
+	tox.Observe("busy", 0.1, start.Add(2*time.Hour))
+	if got := tox.Pending(); got != 0 {
+		t.Errorf("%d markouts pending past the grace period, want 0", got)
+	}
+
+	// Recording fills prunes too, for a tracker that is never observed
+	for i := range 100 {
+		tox.RecordFill(Fill{TokenID: "quiet", Side: SideSell, Price: 0.1, Size: 10, Time: start.Add(time.Duration(i) * time.Hour)})
+	}
+	if got := tox.Pending(); got > 2 {
+		t.Errorf("%d markouts pending after a hundred hourly fills, want at most 2", got)
+	}
+}
+
+func TestToxicityTrackerDoesNotResolveLateMarkouts(t *testing.T) {
+	tox := NewToxicityTracker()
+	tox.Horizons = []time.Duration{time.Minute, 24 * time.Hour}
+	start := time.Unix(1_700_000_000, 0)
+	tox.RecordFill(Fill{TokenID: testTokenID, Side: SideBuy, Price: 0.1, Size: 10, Time: start})
+
+	// Hours late, the one-minute markout is dropped; the one-day markout still waits
+	resolved := tox.Observe(testTokenID, 0.5, start.Add(3*time.Hour))
+	if len(resolved) != 0 {
+		t.Errorf("resolved %+v from a mid seen hours past the horizon", resolved)
+	}
+	if got := tox.Pending(); got != 1 {
+		t.Errorf("%d markouts pending, want the one-day markout", got)
+	}
+	if stats := tox.Stats(testTokenID); len(stats) != 0 {
+		t.Errorf("stats %+v recorded from a late mid", stats)
+	}
+}
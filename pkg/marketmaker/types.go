@@ -2,18 +2,45 @@ package marketmaker
 
 // Config holds market maker configuration
 type Config struct {
-	MinSpreadPct    float64 // Minimum spread to participate (default 0.2%)
-	TargetSpreadPct float64 // Your target spread inside theirs (default 0.1%)
-	MaxMarkets      int     // Maximum number of markets to scan
+	MinSpread       float64          // Minimum spread to participate, in SpreadMetric units; 0 requires 0.2% of mid under any metric
+	SpreadMetric    SpreadMetric     // Spread definition used for MinSpread and reporting (default relative to mid)
+	TargetSpreadPct float64          // Your target spread inside theirs (default 0.1%)
+	MaxMarkets      int              // Maximum number of markets to scan
+	Quoter          Quoter           // Quoting model for suggested prices (nil quotes TargetSpreadPct around mid)
+	Toxicity        *ToxicityTracker // Widens suggested spreads after toxic fills (nil never widens)
+	Risk            *RiskProfile     // Sizing limits and bankroll for strategies built from this config
 }
 
 // Market represents a Polymarket market
 type Market struct {
-	Question      string      `json:"question"`
-	ClobTokenIDs  string      `json:"clobTokenIds"`
-	Volume24hr    interface{} `json:"volume24hr"` // Can be string or number
-	Closed        bool        `json:"closed"`
-	Active        bool        `json:"active"`
+	Question       string      `json:"question"`
+	ClobTokenIDs   string      `json:"clobTokenIds"`
+	Volume24hr     interface{} `json:"volume24hr"` // Can be string or number
+	Closed         bool        `json:"closed"`
+	Active         bool        `json:"active"`
+	Tags           []Tag       `json:"tags"`
+	OutcomePrices  string      `json:"outcomePrices"`  // JSON-encoded array of strings, YES first
+	LastTradePrice float64     `json:"lastTradePrice"` // 0 if the market never traded
+	Events         []Event     `json:"events"`
+	NegRisk        bool        `json:"negRisk"`   // Part of a mutually exclusive (negative-risk) event
+	StartDate      string      `json:"startDate"` // RFC 3339 date the market opened
+	EndDate        string      `json:"endDate"`   // RFC 3339 resolution date
+	TickSize       float64     `json:"orderPriceMinTickSize"`
+	MinOrderSize   float64     `json:"orderMinSize"`
+}
+
+// Event is the Gamma API event a market belongs to
+type Event struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// Tag is a Gamma API tag attached to a market or event
+type Tag struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Slug  string `json:"slug"`
 }
 
 // OrderBookResponse represents the CLOB orderbook response
@@ -22,6 +49,9 @@ type OrderBookResponse struct {
 	Asset  string  `json:"asset_id"`
 	Bids   []Order `json:"bids"`
 	Asks   []Order `json:"asks"`
+
+	TickSize     string `json:"tick_size"`
+	MinOrderSize string `json:"min_order_size"`
 }
 
 // Order represents a single order in the orderbook
@@ -37,8 +67,11 @@ type Opportunity struct {
 	Volume             float64
 	BestBid            float64
 	BestAsk            float64
-	SpreadPct          float64
+	Spread             SpreadMetrics // Spread under every supported definition
 	SuggestedBuyPrice  float64
 	SuggestedSellPrice float64
-	IsIlliquid         bool // True if placeholder orderbook (0.001/0.999)
+	IsIlliquid         bool               // True if placeholder orderbook (0.001/0.999)
+	Market             Market             // Gamma market the opportunity was found in
+	Event              *EventContext      // Sibling markets from the same scan, nil if the market has no event
+	Book               *OrderBookResponse // Orderbook the opportunity was found in
 }
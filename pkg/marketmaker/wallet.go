@@ -0,0 +1,175 @@
+package marketmaker
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// Chain IDs the CLOB runs on
+const (
+	PolygonChainID int64 = 137
+	AmoyChainID    int64 = 80002
+)
+
+// Wallet is an Ethereum key that signs CLOB authentication and orders
+type Wallet struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+// NewWallet loads a wallet from a hex private key, with or without 0x
+func NewWallet(hexKey string) (*Wallet, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("private key must be 32 bytes, got %d", len(raw))
+	}
+
+	key := secp256k1.PrivKeyFromBytes(raw)
+	if key.Key.IsZero() {
+		return nil, errors.New("private key is zero")
+	}
+	return &Wallet{key: key, address: pubKeyAddress(key.PubKey())}, nil
+}
+
+// Address returns the wallet's checksummed address
+func (w *Wallet) Address() string {
+	return w.address
+}
+
+// SignHash signs a 32-byte digest, returning r || s || v with v = 27 or 28
+func (w *Wallet) SignHash(hash [32]byte) []byte {
+	compact := ecdsa.SignCompact(w.key, hash[:], false)
+	return append(compact[1:], compact[0])
+}
+
+// RecoverAddress returns the checksummed address that produced a signature over hash
+func RecoverAddress(hash [32]byte, sig []byte) (string, error) {
+	if len(sig) != 65 {
+		return "", fmt.Errorf("signature must be 65 bytes, got %d", len(sig))
+	}
+	v := sig[64]
+	if v < 27 {
+		v += 27
+	}
+	if v != 27 && v != 28 {
+		return "", fmt.Errorf("invalid signature recovery byte %d", sig[64])
+	}
+
+	compact := append([]byte{v}, sig[:64]...)
+	pub, _, err := ecdsa.RecoverCompact(compact, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return pubKeyAddress(pub), nil
+}
+
+// pubKeyAddress is the last 20 bytes of the Keccak hash of the uncompressed key
+func pubKeyAddress(pub *secp256k1.PublicKey) string {
+	hash := keccak256(pub.SerializeUncompressed()[1:])
+	return checksumAddress(hash[12:])
+}
+
+// keccak256 is Ethereum's hash: Keccak-256 as submitted, not the final SHA3-256
+func keccak256(data ...[]byte) [32]byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
+
+// checksumAddress formats a 20-byte address with EIP-55 mixed-case checksum
+func checksumAddress(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := keccak256([]byte(lower))
+
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// parseAddress decodes a 0x-prefixed hex address, ignoring case
+func parseAddress(s string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+	if err != nil || len(raw) != 20 {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	return raw, nil
+}
+
+// SameAddress reports whether two addresses are equal, ignoring checksum case
+func SameAddress(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x"))
+}
+
+// EIP712Domain separates signatures made for one contract and chain from any other
+type EIP712Domain struct {
+	Name              string
+	Version           string
+	ChainID           int64
+	VerifyingContract string // Omitted from the domain when empty
+}
+
+// Separator returns the domain's hashStruct
+func (d EIP712Domain) Separator() ([32]byte, error) {
+	name, version := keccak256([]byte(d.Name)), keccak256([]byte(d.Version))
+	chainID := abiUint(big.NewInt(d.ChainID))
+	if d.VerifyingContract == "" {
+		typeHash := keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId)"))
+		return keccak256(typeHash[:], name[:], version[:], chainID[:]), nil
+	}
+
+	contract, err := abiAddress(d.VerifyingContract)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to encode verifying contract: %w", err)
+	}
+	typeHash := keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	return keccak256(typeHash[:], name[:], version[:], chainID[:], contract[:]), nil
+}
+
+// TypedDataHash is the digest signed for a struct under a domain
+func TypedDataHash(domain EIP712Domain, structHash [32]byte) ([32]byte, error) {
+	separator, err := domain.Separator()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return keccak256([]byte{0x19, 0x01}, separator[:], structHash[:]), nil
+}
+
+// abiUint encodes a non-negative integer as a 32-byte big-endian word
+func abiUint(n *big.Int) [32]byte {
+	var word [32]byte
+	n.FillBytes(word[:])
+	return word
+}
+
+// abiAddress left-pads an address to a 32-byte word
+func abiAddress(s string) ([32]byte, error) {
+	raw, err := parseAddress(s)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	var word [32]byte
+	copy(word[12:], raw)
+	return word, nil
+}